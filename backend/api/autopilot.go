@@ -0,0 +1,88 @@
+// Purpose: Owner management of fallback ("autopilot") playlists.
+
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"radiokpowka/backend/player"
+)
+
+type autopilotAddReq struct {
+	URL  string `json:"url"`
+	Name string `json:"name,omitempty"`
+	Mode string `json:"mode,omitempty"` // random|sequential
+}
+
+type autopilotUpdateReq struct {
+	Mode    *string `json:"mode,omitempty"`
+	Enabled *bool   `json:"enabled,omitempty"`
+}
+
+func AutopilotListHandler(deps RouterDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		items, err := deps.Player.ListFallbackPlaylists()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "playlist list failed"})
+			return
+		}
+		c.JSON(http.StatusOK, items)
+	}
+}
+
+func AutopilotAddHandler(deps RouterDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req autopilotAddReq
+		if err := c.ShouldBindJSON(&req); err != nil || req.URL == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "поле url обязательно"})
+			return
+		}
+
+		p, err := deps.Player.AddFallbackPlaylist(req.URL, req.Name, req.Mode)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "не удалось добавить плейлист: " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, p)
+	}
+}
+
+func AutopilotUpdateHandler(deps RouterDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req autopilotUpdateReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		err := deps.Player.UpdateFallbackPlaylist(c.Param("id"), req.Mode, req.Enabled)
+		switch {
+		case errors.Is(err, player.ErrPlaylistNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, player.ErrInvalidAutopilotMode):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		default:
+			c.Status(http.StatusNoContent)
+		}
+	}
+}
+
+func AutopilotDeleteHandler(deps RouterDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := deps.Player.DeleteFallbackPlaylist(c.Param("id"))
+		if errors.Is(err, player.ErrPlaylistNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,15 @@
+// Purpose: Local audio cache stats (hits/misses, size) for the owner.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func CacheStatsHandler(deps RouterDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, deps.Cache.Stats())
+	}
+}
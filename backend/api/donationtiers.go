@@ -0,0 +1,33 @@
+// Purpose: Owner-editable donation tiers (queue priority by amount, interrupt threshold).
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"radiokpowka/backend/player"
+)
+
+func GetDonationTiersHandler(deps RouterDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, deps.Player.DonationTiers())
+	}
+}
+
+func PutDonationTiersHandler(deps RouterDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req player.DonationTiers
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "некорректный JSON: " + err.Error()})
+			return
+		}
+		tiers, err := deps.Player.SetDonationTiers(req)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, tiers)
+	}
+}
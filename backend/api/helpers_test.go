@@ -0,0 +1,113 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"radiokpowka/backend/auth"
+	"radiokpowka/backend/config"
+	"radiokpowka/backend/db"
+	"radiokpowka/backend/player"
+	"radiokpowka/backend/source"
+	"radiokpowka/backend/websocket"
+)
+
+const testSecret = "test-secret"
+
+// fakeSource resolves http://tracks.test/<name> and answers searches without yt-dlp.
+type fakeSource struct {
+	searches atomic.Int32
+}
+
+func (s *fakeSource) Name() string { return "fake" }
+
+func (s *fakeSource) Match(u *url.URL) bool { return u.Host == "tracks.test" }
+
+func (s *fakeSource) Resolve(_ context.Context, raw string) ([]source.Meta, error) {
+	u, _ := url.Parse(raw)
+	return []source.Meta{{Title: strings.TrimPrefix(u.Path, "/"), DurationSec: 200, WebpageURL: raw}}, nil
+}
+
+func (s *fakeSource) Input(_ context.Context, raw string) (string, error) { return raw, nil }
+
+func (s *fakeSource) Search(_ context.Context, query string, _ int) ([]source.Meta, error) {
+	s.searches.Add(1)
+	return []source.Meta{{Title: query, DurationSec: 200, WebpageURL: "http://tracks.test/" + url.PathEscape(query)}}, nil
+}
+
+func newTestDeps(t *testing.T, tweak func(*player.ControllerDeps)) (RouterDeps, *fakeSource) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	database, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(database); err != nil {
+		t.Fatal(err)
+	}
+	hub := websocket.NewHub()
+	go hub.Run()
+	fake := &fakeSource{}
+	registry := source.NewRegistry(fake)
+	d := player.ControllerDeps{
+		DB:          database,
+		Hub:         hub,
+		Sources:     registry,
+		QueueWindow: player.QueueWindowConfig{Prev: 10, Next: 50},
+	}
+	if tweak != nil {
+		tweak(&d)
+	}
+	return RouterDeps{
+		Cfg:     config.Config{JWTSecret: testSecret},
+		DB:      database,
+		Player:  player.NewController(d),
+		Hub:     hub,
+		Sources: registry,
+	}, fake
+}
+
+func ownerToken(t *testing.T) string {
+	t.Helper()
+	tok, err := auth.SignJWT(testSecret, auth.Claims{UserID: uuid.New(), Role: "owner", Exp: time.Now().Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tok
+}
+
+// do sends a JSON request (body may be nil) with an optional bearer token.
+func do(t *testing.T, h http.Handler, method, path, token string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
@@ -0,0 +1,70 @@
+// Purpose: Public play history (cursor pagination, date range, title search).
+
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"radiokpowka/backend/player"
+)
+
+// GET /api/history?cursor=&limit=&from=&to=&q=
+// from/to accept RFC3339 or YYYY-MM-DD (a date-only "to" includes that whole day).
+func HistoryHandler(deps RouterDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q := player.HistoryQuery{
+			Cursor: c.Query("cursor"),
+			Search: c.Query("q"),
+		}
+		if v := c.Query("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "limit должен быть положительным числом"})
+				return
+			}
+			q.Limit = n
+		}
+		var err error
+		if q.From, err = parseHistoryTime(c.Query("from"), false); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "некорректный from: " + err.Error()})
+			return
+		}
+		if q.To, err = parseHistoryTime(c.Query("to"), true); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "некорректный to: " + err.Error()})
+			return
+		}
+
+		page, err := deps.Player.History(q)
+		if errors.Is(err, player.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, page)
+	}
+}
+
+func parseHistoryTime(v string, endOfDay bool) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
@@ -0,0 +1,78 @@
+// Purpose: HLS endpoint (/hls/live.m3u8 + segments) served from the station pipeline output.
+// Cache headers are set so a caching reverse proxy/CDN can absorb listener traffic.
+
+package api
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"radiokpowka/backend/player"
+)
+
+func HLSHandler(deps RouterDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := deps.Player.HLSConfig()
+		if !cfg.Enabled {
+			c.String(http.StatusNotFound, "hls disabled")
+			return
+		}
+
+		name := c.Param("file")
+		path, ok := cfg.HLSFilePath(name)
+		if !ok {
+			c.String(http.StatusNotFound, "not found")
+			return
+		}
+
+		// Любой HLS запрос держит конвейер станции запущенным.
+		deps.Player.HoldHLS()
+
+		if name == player.HLSPlaylistName {
+			// После запуска конвейера плейлист появляется с первым сегментом.
+			if !waitForFile(c, path, time.Duration(cfg.SegmentSec*3)*time.Second) {
+				c.Header("Retry-After", strconv.Itoa(cfg.SegmentSec))
+				c.String(http.StatusServiceUnavailable, "stream is starting")
+				return
+			}
+			maxAge := cfg.SegmentSec / 2
+			if maxAge < 1 {
+				maxAge = 1
+			}
+			c.Header("Content-Type", "application/vnd.apple.mpegurl")
+			c.Header("Cache-Control", "public, max-age="+strconv.Itoa(maxAge))
+			c.File(path)
+			return
+		}
+
+		if _, err := os.Stat(path); err != nil {
+			c.String(http.StatusNotFound, "segment expired")
+			return
+		}
+		// Сегменты не меняются после записи.
+		c.Header("Content-Type", "video/mp2t")
+		c.Header("Cache-Control", "public, max-age="+strconv.Itoa(cfg.SegmentSec*cfg.WindowSize*2))
+		c.File(path)
+	}
+}
+
+func waitForFile(c *gin.Context, path string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Purpose: Local music library: public listing/search, owner-triggered rescans.
+// Library tracks are queued through POST /api/playlist/add with track_id.
+
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"radiokpowka/backend/library"
+)
+
+// GET /api/library?q=&offset=&limit=
+func LibraryListHandler(deps RouterDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q := library.Query{Search: c.Query("q")}
+		for _, p := range []struct {
+			name string
+			dst  *int
+		}{{"offset", &q.Offset}, {"limit", &q.Limit}} {
+			v := c.Query(p.name)
+			if v == "" {
+				continue
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": p.name + " должен быть неотрицательным числом"})
+				return
+			}
+			*p.dst = n
+		}
+
+		page, err := deps.Library.List(q)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "library list failed"})
+			return
+		}
+		c.JSON(http.StatusOK, page)
+	}
+}
+
+func LibraryScanStatusHandler(deps RouterDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, deps.Library.Status())
+	}
+}
+
+// POST /api/library/scan starts a rescan; progress is visible in GET /api/library/scan.
+func LibraryScanHandler(deps RouterDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := deps.Library.ScanAsync()
+		switch {
+		case errors.Is(err, library.ErrDisabled):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "библиотека отключена: LOCAL_MEDIA_DIRS не задан"})
+		case errors.Is(err, library.ErrScanRunning):
+			c.JSON(http.StatusConflict, gin.H{"error": "сканирование уже идёт"})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		default:
+			c.Status(http.StatusAccepted)
+		}
+	}
+}
@@ -3,9 +3,13 @@
 package api
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+
+	"radiokpowka/backend/player"
 )
 
 func GetPlayerStateHandler(deps RouterDeps) gin.HandlerFunc {
@@ -34,9 +38,14 @@ func PlayerPauseHandler(deps RouterDeps) gin.HandlerFunc {
 	}
 }
 
+// PlayerNextHandler switches to the next track; ?fade=false skips the crossfade.
 func PlayerNextHandler(deps RouterDeps) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if err := deps.Player.Next(); err != nil {
+		next := deps.Player.Next
+		if fade, err := strconv.ParseBool(c.DefaultQuery("fade", "true")); err == nil && !fade {
+			next = deps.Player.NextWithoutFade
+		}
+		if err := next(); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
@@ -54,6 +63,90 @@ func PlayerPrevHandler(deps RouterDeps) gin.HandlerFunc {
 	}
 }
 
+// seekReq sets either an absolute position or an offset from the current one ("skip intro").
+type seekReq struct {
+	PositionSec *int `json:"positionSec"`
+	OffsetSec   *int `json:"offsetSec"`
+}
+
+func PlayerSeekHandler(deps RouterDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req seekReq
+		if err := c.ShouldBindJSON(&req); err != nil || (req.PositionSec == nil) == (req.OffsetSec == nil) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "positionSec or offsetSec required"})
+			return
+		}
+
+		var err error
+		if req.PositionSec != nil {
+			err = deps.Player.Seek(*req.PositionSec, false)
+		} else {
+			err = deps.Player.Seek(*req.OffsetSec, true)
+		}
+		if errors.Is(err, player.ErrNothingPlaying) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+type shuffleReq struct {
+	Enabled bool   `json:"enabled"`
+	Seed    *int64 `json:"seed,omitempty"` // omitted = random seed
+}
+
+func PlayerShuffleHandler(deps RouterDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req shuffleReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid shuffle request"})
+			return
+		}
+		deps.Player.SetShuffle(req.Enabled, req.Seed)
+		c.Status(http.StatusNoContent)
+	}
+}
+
+type fairQueueReq struct {
+	Enabled bool `json:"enabled"`
+}
+
+func PlayerFairQueueHandler(deps RouterDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req fairQueueReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid fair queue request"})
+			return
+		}
+		deps.Player.SetFairQueue(req.Enabled)
+		c.Status(http.StatusNoContent)
+	}
+}
+
+type repeatReq struct {
+	Mode string `json:"mode"` // off|one|all
+}
+
+func PlayerRepeatHandler(deps RouterDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req repeatReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid repeat request"})
+			return
+		}
+		if err := deps.Player.SetRepeat(req.Mode); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
 type volumeReq struct {
 	Volume float64 `json:"volume"`
 }
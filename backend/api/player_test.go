@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"radiokpowka/backend/auth"
+	"radiokpowka/backend/player"
+)
+
+func ownerRouter(deps RouterDeps) *gin.Engine {
+	r := gin.New()
+	owner := r.Group("/api", auth.JWTMiddleware(testSecret), auth.RequireRole("owner"))
+	owner.POST("/player/seek", PlayerSeekHandler(deps))
+	owner.DELETE("/playlist/:id", PlaylistDeleteHandler(deps))
+	owner.POST("/playlist/:id/move", PlaylistMoveHandler(deps))
+	owner.POST("/playlist/clear", PlaylistClearHandler(deps))
+	return r
+}
+
+func TestPlayerSeekHandler(t *testing.T) {
+	deps, _ := newTestDeps(t, nil)
+	r := ownerRouter(deps)
+	tok := ownerToken(t)
+
+	if w := do(t, r, http.MethodPost, "/api/player/seek", "", map[string]int{"positionSec": 10}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("anonymous seek: %d", w.Code)
+	}
+	if w := do(t, r, http.MethodPost, "/api/player/seek", tok, map[string]int{"positionSec": 10}); w.Code != http.StatusConflict {
+		t.Fatalf("seek with nothing playing: %d %s", w.Code, w.Body)
+	}
+
+	if _, err := deps.Player.AddTrack(player.AddRequest{URL: "http://tracks.test/a", Nick: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	_ = deps.Player.Pause()
+
+	for _, body := range []map[string]int{{}, {"positionSec": 10, "offsetSec": 5}} {
+		if w := do(t, r, http.MethodPost, "/api/player/seek", tok, body); w.Code != http.StatusBadRequest {
+			t.Fatalf("seek %v: %d, want 400", body, w.Code)
+		}
+	}
+
+	if w := do(t, r, http.MethodPost, "/api/player/seek", tok, map[string]int{"positionSec": 40}); w.Code != http.StatusNoContent {
+		t.Fatalf("absolute seek: %d %s", w.Code, w.Body)
+	}
+	if w := do(t, r, http.MethodPost, "/api/player/seek", tok, map[string]int{"offsetSec": 15}); w.Code != http.StatusNoContent {
+		t.Fatalf("relative seek: %d %s", w.Code, w.Body)
+	}
+	if got := deps.Player.State().PositionSec; got != 55 {
+		t.Fatalf("position = %d, want 55", got)
+	}
+}
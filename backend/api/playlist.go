@@ -1,30 +1,60 @@
-// Purpose: Playlist/queue list + add track.
+// Purpose: Playlist/queue list, add track, owner edits (delete, move, clear).
 
 package api
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 
 	"radiokpowka/backend/auth"
+	"radiokpowka/backend/player"
 )
 
 type playlistAddReq struct {
-	URL         string `json:"url"`
+	URL         string `json:"url"`                // link or a text query ("artist - song")
+	TrackID     string `json:"track_id,omitempty"` // local library track instead of url
 	AddedByNick string `json:"added_by_nick,omitempty"`
-	InsertNext  bool   `json:"insert_next,omitempty"`
-	IsDonation  bool   `json:"is_donation,omitempty"`
+	InsertNext  bool   `json:"insert_next,omitempty"` // honored for the owner only
+	// no is_donation: donations come only from the webhook, a client flag would skip the quotas
 }
 
+// GET /api/playlist returns the queue window; ?before=<prevCursor> pages older played
+// entries and ?after=<nextCursor> further upcoming ones (with optional &limit=).
 func PlaylistListHandler(deps RouterDeps) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		items, err := deps.Player.ListQueue()
+		limit := 0
+		if v := c.Query("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "limit должен быть положительным числом"})
+				return
+			}
+			limit = n
+		}
+
+		var page player.QueueWindow
+		var err error
+		switch {
+		case c.Query("before") != "":
+			page, err = deps.Player.QueuePageBefore(c.Query("before"), limit)
+		case c.Query("after") != "":
+			page, err = deps.Player.QueuePageAfter(c.Query("after"), limit)
+		default:
+			page, err = deps.Player.QueueWindow()
+		}
+		if errors.Is(err, player.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "курсор устарел, запросите очередь заново"})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "queue list failed"})
 			return
 		}
-		c.JSON(http.StatusOK, items)
+		c.JSON(http.StatusOK, page)
 	}
 }
 
@@ -35,10 +65,19 @@ func PlaylistAddHandler(deps RouterDeps) gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "некорректный JSON: " + err.Error()})
 			return
 		}
-		if req.URL == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "поле url обязательно"})
+		if req.URL == "" && req.TrackID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "поле url или track_id обязательно"})
 			return
 		}
+		var libraryID *uuid.UUID
+		if req.TrackID != "" {
+			id, err := uuid.Parse(req.TrackID)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "некорректный track_id"})
+				return
+			}
+			libraryID = &id
+		}
 
 		pc := auth.MustGetOptionalClaims(c)
 
@@ -55,7 +94,25 @@ func PlaylistAddHandler(deps RouterDeps) gin.HandlerFunc {
 			addedByNick = "guest"
 		}
 
-		_, err := deps.Player.AddTrack(req.URL, addedByUser, addedByNick, req.InsertNext, req.IsDonation)
+		owner := role == "owner"
+		_, err := deps.Player.AddTrack(player.AddRequest{
+			URL:    req.URL,
+			UserID: addedByUser,
+			Nick:   addedByNick,
+			// jumping the queue is reserved for the owner and donations
+			InsertNext:     req.InsertNext && owner,
+			Privileged:     owner,
+			LibraryTrackID: libraryID,
+		})
+		var rerr *player.RequestError
+		if errors.As(err, &rerr) {
+			writeRequestError(c, rerr)
+			return
+		}
+		if errors.Is(err, player.ErrTrackNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "не удалось добавить трек: " + err.Error()})
 			return
@@ -64,3 +121,83 @@ func PlaylistAddHandler(deps RouterDeps) gin.HandlerFunc {
 		c.Status(http.StatusNoContent)
 	}
 }
+
+// writeRequestError answers a rejected song request with its code
+// (429 for quota and cooldown, with Retry-After for cooldown).
+func writeRequestError(c *gin.Context, e *player.RequestError) {
+	status := http.StatusBadRequest
+	switch e.Code {
+	case player.CodeQuotaExceeded:
+		status = http.StatusTooManyRequests
+	case player.CodeCooldown:
+		status = http.StatusTooManyRequests
+		c.Header("Retry-After", strconv.Itoa(int(e.RetryAfter.Seconds())+1))
+	case player.CodeDuplicate, player.CodeRecentlyPlayed:
+		status = http.StatusConflict
+	case player.CodeNoResults:
+		status = http.StatusNotFound
+	}
+	c.JSON(status, gin.H{"error": e.Message, "code": e.Code})
+}
+
+func PlaylistDeleteHandler(deps RouterDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := deps.Player.RemoveEntry(c.Param("id"))
+		if errors.Is(err, player.ErrEntryNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+type playlistMoveReq struct {
+	Position int `json:"position"` // 1-based among upcoming entries
+}
+
+func PlaylistMoveHandler(deps RouterDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req playlistMoveReq
+		if err := c.ShouldBindJSON(&req); err != nil || req.Position < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid position"})
+			return
+		}
+
+		err := deps.Player.MoveEntry(c.Param("id"), req.Position)
+		switch {
+		case errors.Is(err, player.ErrEntryNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, player.ErrEntryNotUpcoming):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		default:
+			c.Status(http.StatusNoContent)
+		}
+	}
+}
+
+type playlistClearReq struct {
+	Scope string `json:"scope"` // upcoming|played
+}
+
+func PlaylistClearHandler(deps RouterDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req playlistClearReq
+		if err := c.ShouldBindJSON(&req); err != nil || (req.Scope != player.ClearUpcoming && req.Scope != player.ClearPlayed) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "scope must be upcoming or played"})
+			return
+		}
+
+		n, err := deps.Player.ClearQueue(req.Scope)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"removed": n})
+	}
+}
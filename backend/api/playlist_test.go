@@ -0,0 +1,137 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"radiokpowka/backend/auth"
+	"radiokpowka/backend/player"
+)
+
+func playlistRouter(deps RouterDeps) *gin.Engine {
+	r := gin.New()
+	r.POST("/api/playlist/add", auth.OptionalJWT(testSecret), PlaylistAddHandler(deps))
+	return r
+}
+
+func TestPlaylistAddIgnoresClientDonationFlag(t *testing.T) {
+	deps, _ := newTestDeps(t, func(d *player.ControllerDeps) {
+		d.Quota = player.QuotaConfig{MaxPending: 1}
+	})
+	r := playlistRouter(deps)
+
+	body := map[string]any{"url": "http://tracks.test/a", "added_by_nick": "anon", "is_donation": true}
+	if w := do(t, r, http.MethodPost, "/api/playlist/add", "", body); w.Code != http.StatusNoContent {
+		t.Fatalf("first request: %d %s", w.Code, w.Body)
+	}
+	body["url"] = "http://tracks.test/b"
+	w := do(t, r, http.MethodPost, "/api/playlist/add", "", body)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request: %d %s, want 429 (the quota applies)", w.Code, w.Body)
+	}
+
+	win, err := deps.Player.QueueWindow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range win.Items {
+		if e.IsDonation {
+			t.Fatalf("entry %q marked as donation", e.Title)
+		}
+	}
+}
+
+func TestPlaylistAddInsertNextOwnerOnly(t *testing.T) {
+	deps, _ := newTestDeps(t, nil)
+	r := playlistRouter(deps)
+
+	for _, name := range []string{"a", "b"} {
+		body := map[string]any{"url": "http://tracks.test/" + name, "added_by_nick": "anon"}
+		if w := do(t, r, http.MethodPost, "/api/playlist/add", "", body); w.Code != http.StatusNoContent {
+			t.Fatalf("add %s: %d %s", name, w.Code, w.Body)
+		}
+	}
+	body := map[string]any{"url": "http://tracks.test/jump", "added_by_nick": "anon", "insert_next": true}
+	do(t, r, http.MethodPost, "/api/playlist/add", "", body)
+	body = map[string]any{"url": "http://tracks.test/owner", "insert_next": true}
+	do(t, r, http.MethodPost, "/api/playlist/add", ownerToken(t), body)
+
+	win, err := deps.Player.QueueWindow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var upcoming []string
+	for _, e := range win.Items {
+		if e.Status == "next" {
+			upcoming = append(upcoming, e.Title)
+		}
+	}
+	want := []string{"owner", "b", "jump"}
+	if len(upcoming) != len(want) {
+		t.Fatalf("upcoming = %v, want %v", upcoming, want)
+	}
+	for i := range want {
+		if upcoming[i] != want[i] {
+			t.Fatalf("upcoming = %v, want %v", upcoming, want)
+		}
+	}
+}
+
+func TestPlaylistEditHandlers(t *testing.T) {
+	deps, _ := newTestDeps(t, nil)
+	r := ownerRouter(deps)
+	tok := ownerToken(t)
+
+	var ids []string
+	for _, name := range []string{"a", "b", "c"} {
+		id, err := deps.Player.AddTrack(player.AddRequest{URL: "http://tracks.test/" + name, Nick: "alice"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	cases := []struct {
+		method, path string
+		body         any
+		want         int
+	}{
+		{http.MethodPost, "/api/playlist/" + ids[2] + "/move", map[string]int{"position": 1}, http.StatusNoContent},
+		{http.MethodPost, "/api/playlist/" + ids[0] + "/move", map[string]int{"position": 1}, http.StatusConflict},
+		{http.MethodPost, "/api/playlist/" + ids[1] + "/move", map[string]int{"position": 0}, http.StatusBadRequest},
+		{http.MethodPost, "/api/playlist/nope/move", map[string]int{"position": 1}, http.StatusNotFound},
+		{http.MethodDelete, "/api/playlist/" + ids[1], nil, http.StatusNoContent},
+		{http.MethodDelete, "/api/playlist/" + ids[1], nil, http.StatusNotFound},
+		{http.MethodPost, "/api/playlist/clear", map[string]string{"scope": "everything"}, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		if w := do(t, r, tc.method, tc.path, "", tc.body); w.Code != http.StatusUnauthorized {
+			t.Fatalf("anonymous %s %s: %d", tc.method, tc.path, w.Code)
+		}
+		if w := do(t, r, tc.method, tc.path, tok, tc.body); w.Code != tc.want {
+			t.Fatalf("%s %s: %d %s, want %d", tc.method, tc.path, w.Code, w.Body, tc.want)
+		}
+	}
+
+	w := do(t, r, http.MethodPost, "/api/playlist/clear", tok, map[string]string{"scope": "upcoming"})
+	if w.Code != http.StatusOK || w.Body.String() != `{"removed":1}` {
+		t.Fatalf("clear: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestPlaylistAddReportsRuleRejection(t *testing.T) {
+	deps, _ := newTestDeps(t, nil)
+	if _, err := deps.Player.SetRules(player.Rules{AllowedDomains: []string{"youtube.com"}}); err != nil {
+		t.Fatal(err)
+	}
+	r := playlistRouter(deps)
+
+	w := do(t, r, http.MethodPost, "/api/playlist/add", "", map[string]string{"url": "http://tracks.test/a", "added_by_nick": "anon"})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"code":"rule_rejected"`) ||
+		!strings.Contains(w.Body.String(), "домен tracks.test не разрешён") {
+		t.Fatalf("got %d %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,60 @@
+// Purpose: Per-client request limits for expensive public endpoints (track search runs yt-dlp).
+// - clientLimiter allows max requests per window for each client key (IP), like the bot's
+//   RateLimiter but keyed.
+// - A semaphore caps how many such requests run at once across all clients.
+
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+type clientLimiter struct {
+	mu      sync.Mutex
+	max     int
+	window  time.Duration
+	clients map[string]*clientWindow
+}
+
+type clientWindow struct {
+	tokens int
+	reset  time.Time
+}
+
+// newClientLimiter returns nil for max <= 0 (no limit); a nil limiter allows everything.
+func newClientLimiter(max int, window time.Duration) *clientLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return &clientLimiter{max: max, window: window, clients: map[string]*clientWindow{}}
+}
+
+// Allow takes a request token for key. When none is left it reports how long until the window resets.
+func (l *clientLimiter) Allow(key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	w := l.clients[key]
+	if w == nil || now.After(w.reset) {
+		if len(l.clients) > 10000 {
+			// forget clients whose window is over
+			for k, cw := range l.clients {
+				if now.After(cw.reset) {
+					delete(l.clients, k)
+				}
+			}
+		}
+		w = &clientWindow{tokens: l.max, reset: now.Add(l.window)}
+		l.clients[key] = w
+	}
+	if w.tokens <= 0 {
+		return false, w.reset.Sub(now)
+	}
+	w.tokens--
+	return true, 0
+}
@@ -3,36 +3,50 @@
 package api
 
 import (
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 
 	"radiokpowka/backend/auth"
+	"radiokpowka/backend/cache"
 	"radiokpowka/backend/config"
+	"radiokpowka/backend/library"
 	"radiokpowka/backend/player"
+	"radiokpowka/backend/source"
 	"radiokpowka/backend/websocket"
 	"radiokpowka/backend/youtube"
 )
 
 type RouterDeps struct {
-	Cfg    config.Config
-	DB     *gorm.DB
-	Player *player.Controller
-	Hub    *websocket.Hub
-	YT     *youtube.Client
+	Cfg     config.Config
+	DB      *gorm.DB
+	Player  *player.Controller
+	Hub     *websocket.Hub
+	YT      *youtube.Client
+	Sources *source.Registry
+	Cache   *cache.Cache
+	Library *library.Library
 }
 
-func NewRouter(cfg config.Config, database *gorm.DB) http.Handler {
+// NewRouter also returns the player controller, so the Twitch bot can share it.
+func NewRouter(cfg config.Config, database *gorm.DB) (http.Handler, *player.Controller) {
 	gin.SetMode(gin.ReleaseMode)
 
 	r := gin.New()
 	r.Use(gin.Recovery())
+	// client IPs feed per-IP limits: only a known proxy may set X-Forwarded-For
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Printf("TRUSTED_PROXIES: %v, доверенных прокси нет", err)
+		_ = r.SetTrustedProxies(nil)
+	}
 
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     cfg.CORSOrigins,
-		AllowMethods:     []string{"GET", "POST", "OPTIONS"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Authorization", "Content-Type", "X-RK-Webhook-Secret"},
 		AllowCredentials: false,
 		MaxAge:           3600,
@@ -47,18 +61,90 @@ func NewRouter(cfg config.Config, database *gorm.DB) http.Handler {
 		CookiesFromBrowser: cfg.YTDLPCookiesFromBrowser,
 	})
 
+	// first match wins: known sites, direct audio links, local files, then any site yt-dlp knows
+	sources := []source.Source{
+		source.YouTube(yt),
+		source.SoundCloud(yt),
+		source.Bandcamp(yt),
+		source.HTTP(cfg.FFProbePath),
+	}
+	if len(cfg.LocalMediaDirs) > 0 {
+		sources = append(sources, source.Local(cfg.FFProbePath, cfg.LocalMediaDirs))
+	}
+	registry := source.NewRegistry(append(sources, source.YTDLP(yt))...)
+
+	audioCache, err := cache.New(cache.Config{
+		Enabled:  cfg.CacheEnabled,
+		Dir:      cfg.CacheDir,
+		MaxBytes: int64(cfg.CacheMaxMB) << 20,
+	}, registry)
+	if err != nil {
+		log.Printf("кэш аудио отключён: %v", err)
+		audioCache = nil
+	}
+
+	mounts := make([]player.Mount, 0, len(cfg.StreamMounts))
+	for _, m := range cfg.StreamMounts {
+		mounts = append(mounts, player.Mount{Codec: m.Codec, Bitrate: m.Bitrate})
+	}
+
 	ctrl := player.NewController(player.ControllerDeps{
-		DB:  database,
-		Hub: hub,
-		YT:  yt,
+		DB:         database,
+		Hub:        hub,
+		Sources:    registry,
+		FFMPEGPath: cfg.FFMPEGPath,
+		Cache:      audioCache,
+		HLS: player.HLSConfig{
+			Enabled:    cfg.HLSEnabled,
+			Dir:        cfg.HLSDir,
+			SegmentSec: cfg.HLSSegmentSec,
+			WindowSize: cfg.HLSWindowSize,
+		},
+		Mounts: mounts,
+		Loudness: player.LoudnessConfig{
+			Enabled:   cfg.LoudnessEnabled,
+			TargetI:   cfg.LoudnessTargetI,
+			TargetTP:  cfg.LoudnessTargetTP,
+			TargetLRA: cfg.LoudnessTargetLRA,
+		},
+		Crossfade: time.Duration(cfg.CrossfadeSec * float64(time.Second)),
+		Quota: player.QuotaConfig{
+			MaxPending:        cfg.RequestMaxPending,
+			MaxPlaylistTracks: cfg.RequestMaxPlaylistTracks,
+			Cooldown:          time.Duration(cfg.RequestCooldownSec) * time.Second,
+		},
+		Duplicates: player.DuplicateConfig{
+			Policy:       cfg.DuplicatePolicy,
+			RecentWindow: time.Duration(cfg.RecentWindowMin) * time.Minute,
+		},
+		QueueWindow: player.QueueWindowConfig{
+			Prev: cfg.QueueWindowPrev,
+			Next: cfg.QueueWindowNext,
+		},
+		VoteSkip: player.VoteSkipConfig{
+			Enabled: cfg.VoteSkipEnabled,
+			Votes:   cfg.VoteSkipVotes,
+			Percent: cfg.VoteSkipPercent,
+			Min:     cfg.VoteSkipMin,
+		},
+	})
+
+	lib := library.New(database, library.Config{
+		Dirs:        cfg.LocalMediaDirs,
+		FFProbePath: cfg.FFProbePath,
+		Interval:    time.Duration(cfg.LibraryScanIntervalSec) * time.Second,
 	})
+	lib.Start()
 
 	deps := RouterDeps{
-		Cfg:    cfg,
-		DB:     database,
-		Player: ctrl,
-		Hub:    hub,
-		YT:     yt,
+		Cfg:     cfg,
+		DB:      database,
+		Player:  ctrl,
+		Hub:     hub,
+		YT:      yt,
+		Sources: registry,
+		Cache:   audioCache,
+		Library: lib,
 	}
 
 	// Public
@@ -68,8 +154,15 @@ func NewRouter(cfg config.Config, database *gorm.DB) http.Handler {
 	r.GET("/api/player/state", GetPlayerStateHandler(deps))
 	r.POST("/api/playlist/add", auth.OptionalJWT(cfg.JWTSecret), PlaylistAddHandler(deps))
 	r.GET("/api/playlist", PlaylistListHandler(deps))
-
-	r.GET("/stream", StreamHandler(deps))
+	r.GET("/api/history", HistoryHandler(deps))
+	r.GET("/api/library", LibraryListHandler(deps))
+	r.GET("/api/search", SearchHandler(deps))
+
+	r.GET("/stream", StreamHandler(deps, ""))
+	r.GET("/stream.mp3", StreamHandler(deps, player.CodecMP3))
+	r.GET("/stream.ogg", StreamHandler(deps, player.CodecOpus))
+	r.GET("/stream.aac", StreamHandler(deps, player.CodecAAC))
+	r.GET("/hls/:file", HLSHandler(deps))
 	r.GET("/ws", WSHandler(deps))
 
 	// Webhook (protected by shared secret header if WEBHOOK_SECRET set)
@@ -79,6 +172,8 @@ func NewRouter(cfg config.Config, database *gorm.DB) http.Handler {
 	protected := r.Group("/api")
 	protected.Use(auth.JWTMiddleware(cfg.JWTSecret))
 	protected.GET("/me", MeHandler(deps))
+	protected.GET("/player/voteskip", VoteSkipProgressHandler(deps))
+	protected.POST("/player/voteskip", VoteSkipHandler(deps))
 
 	owner := protected.Group("/")
 	owner.Use(auth.RequireRole("owner"))
@@ -86,10 +181,34 @@ func NewRouter(cfg config.Config, database *gorm.DB) http.Handler {
 	owner.POST("/player/pause", PlayerPauseHandler(deps))
 	owner.POST("/player/next", PlayerNextHandler(deps))
 	owner.POST("/player/prev", PlayerPrevHandler(deps))
+	owner.POST("/player/seek", PlayerSeekHandler(deps))
 	owner.POST("/player/volume", PlayerVolumeHandler(deps))
+	owner.POST("/player/shuffle", PlayerShuffleHandler(deps))
+	owner.POST("/player/fair", PlayerFairQueueHandler(deps))
+	owner.POST("/player/repeat", PlayerRepeatHandler(deps))
+
+	owner.DELETE("/playlist/:id", PlaylistDeleteHandler(deps))
+	owner.POST("/playlist/:id/move", PlaylistMoveHandler(deps))
+	owner.POST("/playlist/clear", PlaylistClearHandler(deps))
+
+	owner.GET("/rules", GetRulesHandler(deps))
+	owner.PUT("/rules", PutRulesHandler(deps))
+
+	owner.GET("/donations/tiers", GetDonationTiersHandler(deps))
+	owner.PUT("/donations/tiers", PutDonationTiersHandler(deps))
+
+	owner.GET("/autopilot/playlists", AutopilotListHandler(deps))
+	owner.POST("/autopilot/playlists", AutopilotAddHandler(deps))
+	owner.PATCH("/autopilot/playlists/:id", AutopilotUpdateHandler(deps))
+	owner.DELETE("/autopilot/playlists/:id", AutopilotDeleteHandler(deps))
+
+	owner.GET("/cache/stats", CacheStatsHandler(deps))
+
+	owner.GET("/library/scan", LibraryScanStatusHandler(deps))
+	owner.POST("/library/scan", LibraryScanHandler(deps))
 
 	owner.POST("/integrations/donationalerts/connect", DonAlertsConnectHandler(deps))
 	owner.POST("/integrations/donx/connect", DonXConnectHandler(deps))
 
-	return r
+	return r, ctrl
 }
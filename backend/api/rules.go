@@ -0,0 +1,33 @@
+// Purpose: Owner-editable request rules (duration, livestreams, blocklists, domains).
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"radiokpowka/backend/player"
+)
+
+func GetRulesHandler(deps RouterDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, deps.Player.Rules())
+	}
+}
+
+func PutRulesHandler(deps RouterDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req player.Rules
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "некорректный JSON: " + err.Error()})
+			return
+		}
+		rules, err := deps.Player.SetRules(req)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, rules)
+	}
+}
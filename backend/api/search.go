@@ -0,0 +1,89 @@
+// Purpose: Track search for song requests (GET /api/search?q=), YouTube via yt-dlp.
+// Public, so it is throttled per client IP and the number of running searches is capped.
+
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"radiokpowka/backend/source"
+)
+
+const (
+	searchDefaultLimit = 5
+	searchMaxLimit     = 10
+)
+
+type searchResultDTO struct {
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	Channel     string `json:"channel,omitempty"`
+	DurationSec int    `json:"durationSec"`
+	IsLive      bool   `json:"isLive,omitempty"`
+	Provider    string `json:"provider"`
+}
+
+// GET /api/search?q=&limit=
+func SearchHandler(deps RouterDeps) gin.HandlerFunc {
+	lim := newClientLimiter(deps.Cfg.SearchRateLimitPerMin, time.Minute)
+	running := make(chan struct{}, max(deps.Cfg.SearchMaxConcurrent, 1))
+	return func(c *gin.Context) {
+		if ok, wait := lim.Allow(c.ClientIP()); !ok {
+			c.Header("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "слишком много поисковых запросов, попробуйте позже"})
+			return
+		}
+		select {
+		case running <- struct{}{}:
+			defer func() { <-running }()
+		default:
+			c.Header("Retry-After", "5")
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "поиск занят, попробуйте через несколько секунд"})
+			return
+		}
+
+		q := strings.TrimSpace(c.Query("q"))
+		if q == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "параметр q обязателен"})
+			return
+		}
+		limit := searchDefaultLimit
+		if v := c.Query("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "limit должен быть положительным числом"})
+				return
+			}
+			limit = min(n, searchMaxLimit)
+		}
+
+		metas, err := deps.Sources.Search(c.Request.Context(), q, limit)
+		if errors.Is(err, source.ErrSearchUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "поиск недоступен"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "поиск не удался: " + err.Error()})
+			return
+		}
+
+		out := make([]searchResultDTO, 0, len(metas))
+		for _, m := range metas {
+			out = append(out, searchResultDTO{
+				Title:       m.Title,
+				URL:         m.WebpageURL,
+				Channel:     m.Channel,
+				DurationSec: m.DurationSec,
+				IsLive:      m.IsLive,
+				Provider:    m.Provider,
+			})
+		}
+		c.JSON(http.StatusOK, gin.H{"items": out})
+	}
+}
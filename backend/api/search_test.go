@@ -0,0 +1,68 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSearchRateLimitedPerClient(t *testing.T) {
+	deps, fake := newTestDeps(t, nil)
+	deps.Cfg.SearchRateLimitPerMin = 2
+	deps.Cfg.SearchMaxConcurrent = 1
+	r := gin.New()
+	r.GET("/api/search", SearchHandler(deps))
+
+	get := func(ip, forwarded string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/search?q=song", nil)
+		req.RemoteAddr = ip + ":40000"
+		if forwarded != "" {
+			req.Header.Set("X-Forwarded-For", forwarded)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for i := range 2 {
+		if code := get("203.0.113.1", ""); code != http.StatusOK {
+			t.Fatalf("search %d: %d", i+1, code)
+		}
+	}
+	if code := get("203.0.113.1", ""); code != http.StatusTooManyRequests {
+		t.Fatalf("third search: %d, want 429", code)
+	}
+	if code := get("203.0.113.2", ""); code != http.StatusOK {
+		t.Fatalf("another client: %d, want 200", code)
+	}
+	if got := fake.searches.Load(); got != 3 {
+		t.Fatalf("searches run = %d, want 3", got)
+	}
+}
+
+func TestSearchIgnoresForwardedForFromUntrustedPeers(t *testing.T) {
+	deps, _ := newTestDeps(t, nil)
+	deps.Cfg.SearchRateLimitPerMin = 1
+	r := gin.New()
+	if err := r.SetTrustedProxies(deps.Cfg.TrustedProxies); err != nil {
+		t.Fatal(err)
+	}
+	r.GET("/api/search", SearchHandler(deps))
+
+	for i, forwarded := range []string{"198.51.100.1", "198.51.100.2"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/search?q=song", nil)
+		req.RemoteAddr = "203.0.113.1:40000"
+		req.Header.Set("X-Forwarded-For", forwarded)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		want := http.StatusOK
+		if i > 0 {
+			want = http.StatusTooManyRequests
+		}
+		if w.Code != want {
+			t.Fatalf("search %d via %s: %d, want %d", i+1, forwarded, w.Code, want)
+		}
+	}
+}
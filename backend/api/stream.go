@@ -1,46 +1,69 @@
-// Purpose: Audio stream endpoint. Pipes MP3 audio to clients.
+// Purpose: Audio stream endpoints (/stream, /stream.mp3, /stream.ogg, /stream.aac). Pipes a shared station mount to clients.
 
 package api
 
 import (
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"radiokpowka/backend/player"
 )
 
-func StreamHandler(deps RouterDeps) gin.HandlerFunc {
+// StreamHandler serves a mount by codec ("" = default mount); ?br=<kbps> picks the bitrate.
+func StreamHandler(deps RouterDeps, codec string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		log.Printf("stream: start client=%s ua=%s", c.ClientIP(), c.Request.UserAgent())
 
-		flusher, ok := c.Writer.(http.Flusher)
+		br := 0
+		if raw := c.Query("br"); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n <= 0 {
+				c.String(http.StatusBadRequest, "invalid br")
+				return
+			}
+			br = n
+		}
+		mount, ok := deps.Player.FindMount(codec, br)
 		if !ok {
-			c.String(http.StatusInternalServerError, "stream: flusher not supported")
+			c.String(http.StatusNotFound, "no such mount")
 			return
 		}
-		flusher.Flush()
 
-		// ВАЖНО: сначала решаем “можем ли стримить”, чтобы не отдавать 200 и потом молча падать.
-		// Если в твоём Player есть State() — используй её.
-		st := deps.Player.State()
-		if st.IsPaused {
-			c.String(http.StatusConflict, "server paused")
+		flusher, ok := c.Writer.(http.Flusher)
+		if !ok {
+			c.String(http.StatusInternalServerError, "stream: flusher not supported")
 			return
 		}
-		if st.Current == nil || st.Current.URL == "" {
-			c.String(http.StatusNotFound, "no current track")
-			return
+
+		// Поток непрерывный: пауза и пустая очередь отдаются тишиной, смена трека без переподключения.
+		// Снимаем WriteTimeout сервера только для этого соединения.
+		if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+			log.Printf("stream: cannot clear write deadline: %v", err)
 		}
 
 		// Заголовки + статус + первый flush
-		c.Header("Content-Type", "audio/mpeg")
+		c.Header("Content-Type", mount.ContentType())
 		c.Header("Cache-Control", "no-store")
 		c.Header("X-Content-Type-Options", "nosniff")
+
+		// ICY: VLC/foobar2000/OBS показывают текущий трек
+		opts := player.StreamOptions{Mount: mount}
+		if c.GetHeader("Icy-MetaData") == "1" && mount.SupportsICY() {
+			opts.IcyMetaInt = player.IcyMetaInt
+			c.Header("icy-metaint", strconv.Itoa(player.IcyMetaInt))
+			c.Header("icy-name", deps.Cfg.StationName)
+			c.Header("icy-br", strconv.Itoa(mount.Bitrate))
+		}
+
 		c.Status(http.StatusOK)
 		flusher.Flush()
 
 		ctx := c.Request.Context()
-		if err := deps.Player.StreamTo(ctx, c.Writer, flusher); err != nil {
+		if err := deps.Player.StreamTo(ctx, c.Writer, flusher, opts); err != nil {
 			// Теперь ты УВИДИШЬ причину, а не “тишину”
 			log.Printf("stream: error: %v", err)
 			return
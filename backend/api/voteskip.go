@@ -0,0 +1,40 @@
+// Purpose: Listener skip voting on the current track (any authenticated user).
+
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"radiokpowka/backend/auth"
+	"radiokpowka/backend/player"
+)
+
+func VoteSkipProgressHandler(deps RouterDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, deps.Player.VoteSkipProgress())
+	}
+}
+
+func VoteSkipHandler(deps RouterDeps) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pc := auth.MustGetClaims(c)
+		progress, err := deps.Player.VoteSkip(player.ListenerVoter(pc.UserID))
+		switch {
+		case errors.Is(err, player.ErrVoteSkipDisabled):
+			c.JSON(http.StatusForbidden, gin.H{"error": "голосование за пропуск отключено"})
+		case errors.Is(err, player.ErrNothingPlaying):
+			c.JSON(http.StatusConflict, gin.H{"error": "сейчас ничего не играет"})
+		case errors.Is(err, player.ErrAlreadyVoted):
+			c.JSON(http.StatusConflict, gin.H{"error": "вы уже голосовали за этот трек", "progress": progress})
+		case errors.Is(err, player.ErrAlreadySkipped):
+			c.JSON(http.StatusConflict, gin.H{"error": "трек уже пропускается", "progress": progress})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusOK, progress)
+		}
+	}
+}
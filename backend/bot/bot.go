@@ -21,8 +21,15 @@ type Config struct {
 
 	GlobalRateLimitPerMin int
 
+	// !sr requests resolved at once; more are turned down until one finishes
+	MaxConcurrentRequests int
+
 	// Returns a text to post for !track command
 	GetCurrentTrackText func() string
+	// Records a !skip vote from a chatter and returns the reply (nil = command ignored)
+	VoteSkip func(nick string) string
+	// Queues a !sr request (link or text query) from a chatter and returns the reply
+	RequestTrack func(nick, query string) string
 }
 
 func Run(cfg Config) error {
@@ -40,6 +47,9 @@ func Run(cfg Config) error {
 	if cfg.GlobalRateLimitPerMin <= 0 {
 		cfg.GlobalRateLimitPerMin = 18
 	}
+	if cfg.MaxConcurrentRequests <= 0 {
+		cfg.MaxConcurrentRequests = 2
+	}
 	if cfg.GetCurrentTrackText == nil {
 		cfg.GetCurrentTrackText = func() string { return "RadioKpowka: трек неизвестен." }
 	}
@@ -57,6 +67,7 @@ func Run(cfg Config) error {
 	log.Printf("twitch bot connected: #%s as %s", cfg.Channel, cfg.Nick)
 
 	lim := NewRateLimiter(cfg.GlobalRateLimitPerMin, time.Minute)
+	resolving := make(chan struct{}, cfg.MaxConcurrentRequests)
 
 	var wg sync.WaitGroup
 	defer wg.Wait()
@@ -79,6 +90,39 @@ func Run(cfg Config) error {
 			}
 			_ = conn.Say(cfg.Channel, cfg.GetCurrentTrackText())
 
+		case CmdSkip:
+			if cfg.VoteSkip == nil || msg.Nick == "" {
+				continue
+			}
+			reply := cfg.VoteSkip(msg.Nick)
+			if reply != "" && lim.Allow() {
+				_ = conn.Say(cfg.Channel, reply)
+			}
+
+		case CmdSongRequest:
+			if cfg.RequestTrack == nil || msg.Nick == "" {
+				continue
+			}
+			// resolving takes a few seconds: do not block reading chat, but keep the
+			// number of yt-dlp runs bounded
+			select {
+			case resolving <- struct{}{}:
+			default:
+				if lim.Allow() {
+					_ = conn.Say(cfg.Channel, "@"+msg.Nick+", заказы сейчас обрабатываются, попробуйте через минуту")
+				}
+				continue
+			}
+			wg.Add(1)
+			go func(nick, query string) {
+				defer wg.Done()
+				defer func() { <-resolving }()
+				reply := cfg.RequestTrack(nick, query)
+				if reply != "" && lim.Allow() {
+					_ = conn.Say(cfg.Channel, reply)
+				}
+			}(msg.Nick, cmd.Query)
+
 		case CmdTrackSpam:
 			if !cfg.SpamEnabled {
 				if lim.Allow() {
@@ -15,11 +15,14 @@ const (
 	CmdNone CommandKind = iota
 	CmdTrack
 	CmdTrackSpam
+	CmdSkip
+	CmdSongRequest
 )
 
 type Command struct {
-	Kind CommandKind
-	N    int
+	Kind  CommandKind
+	N     int
+	Query string // !sr: link or "artist - song"
 }
 
 func ParseCommand(text string) Command {
@@ -33,6 +36,16 @@ func ParseCommand(text string) Command {
 		return Command{Kind: CmdNone}
 	}
 
+	if parts[0] == "!skip" {
+		return Command{Kind: CmdSkip}
+	}
+	if parts[0] == "!sr" {
+		q := strings.TrimSpace(strings.TrimPrefix(t, "!sr"))
+		if q == "" {
+			return Command{Kind: CmdNone}
+		}
+		return Command{Kind: CmdSongRequest, Query: q}
+	}
 	if parts[0] != "!track" {
 		return Command{Kind: CmdNone}
 	}
@@ -0,0 +1,42 @@
+package bot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCommand(t *testing.T) {
+	cases := []struct {
+		text string
+		want Command
+	}{
+		{"!sr Artist - Song", Command{Kind: CmdSongRequest, Query: "Artist - Song"}},
+		{"  !sr   https://youtu.be/dQw4w9WgXcQ  ", Command{Kind: CmdSongRequest, Query: "https://youtu.be/dQw4w9WgXcQ"}},
+		{"!sr", Command{Kind: CmdNone}},
+		{"!sr    ", Command{Kind: CmdNone}},
+		{"!srsly", Command{Kind: CmdNone}},
+		{"please !sr song", Command{Kind: CmdNone}},
+		{"!skip", Command{Kind: CmdSkip}},
+		{"!track", Command{Kind: CmdTrack}},
+		{"!track spam 3", Command{Kind: CmdTrackSpam, N: 3}},
+		{"!track spam x", Command{Kind: CmdTrackSpam, N: -1}},
+		{"hello", Command{Kind: CmdNone}},
+		{"", Command{Kind: CmdNone}},
+	}
+	for _, tc := range cases {
+		if got := ParseCommand(tc.text); got != tc.want {
+			t.Errorf("ParseCommand(%q) = %+v, want %+v", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	lim := NewRateLimiter(2, 50*time.Millisecond)
+	if !lim.Allow() || !lim.Allow() || lim.Allow() {
+		t.Fatal("expected exactly two messages per window")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if !lim.Allow() {
+		t.Fatal("tokens not refilled after the window")
+	}
+}
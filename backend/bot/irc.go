@@ -71,6 +71,7 @@ func (i *IRCConn) Say(channel, text string) error {
 
 type IRCMessage struct {
 	Raw  string
+	Nick string // sender login, "" for server messages
 	Text string
 }
 
@@ -92,7 +93,25 @@ func (i *IRCConn) ReadMessage() (IRCMessage, error) {
 		text = line[idx+2:]
 	}
 
-	return IRCMessage{Raw: line, Text: text}, nil
+	return IRCMessage{Raw: line, Nick: senderNick(line), Text: text}, nil
+}
+
+// senderNick extracts "nick" from "[@tags ]:nick!user@host PRIVMSG ...".
+func senderNick(line string) string {
+	if strings.HasPrefix(line, "@") {
+		if idx := strings.IndexByte(line, ' '); idx >= 0 {
+			line = line[idx+1:]
+		}
+	}
+	if !strings.HasPrefix(line, ":") {
+		return ""
+	}
+	prefix, _, _ := strings.Cut(line[1:], " ")
+	nick, _, ok := strings.Cut(prefix, "!")
+	if !ok {
+		return ""
+	}
+	return nick
 }
 
 func (i *IRCConn) writeLine(s string) error {
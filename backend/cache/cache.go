@@ -0,0 +1,290 @@
+// Purpose: Local audio cache for queued tracks.
+// - Tracks are downloaded in the background through their audio source (one at a time);
+//   sources that cannot download (direct HTTP, local files) are never cached.
+// - Files live in a directory with an LRU size limit; the index is rebuilt from disk on start.
+// - Playback asks Lookup first and falls back to the direct URL on a miss.
+
+package cache
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	fileExt      = ".audio"
+	jobQueueSize = 256
+)
+
+type Config struct {
+	Enabled  bool
+	Dir      string
+	MaxBytes int64
+}
+
+type Stats struct {
+	Enabled  bool  `json:"enabled"`
+	Hits     int64 `json:"hits"`
+	Misses   int64 `json:"misses"`
+	Files    int   `json:"files"`
+	Bytes    int64 `json:"bytes"`
+	MaxBytes int64 `json:"maxBytes"`
+	Pending  int   `json:"pending"`
+}
+
+// Downloader saves the audio of a URL to a file (implemented by source.Registry).
+type Downloader interface {
+	Cacheable(url string) bool
+	DownloadAudio(ctx context.Context, url, dest string) error
+}
+
+type Cache struct {
+	cfg Config
+	dl  Downloader
+
+	mu      sync.Mutex
+	lru     *list.List               // front = most recently used
+	entries map[string]*list.Element // key -> element holding *entry
+	size    int64
+	pending map[string]bool
+	jobs    chan string
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+type entry struct {
+	key  string
+	path string
+	size int64
+}
+
+// New opens the cache directory and starts the download worker.
+// A disabled cache is returned as nil; all methods are nil-safe.
+func New(cfg Config, dl Downloader) (*Cache, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	c := &Cache{
+		cfg:     cfg,
+		dl:      dl,
+		lru:     list.New(),
+		entries: map[string]*list.Element{},
+		pending: map[string]bool{},
+		jobs:    make(chan string, jobQueueSize),
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	go c.worker()
+	return c, nil
+}
+
+// load indexes files left by a previous run, oldest first.
+func (c *Cache) load() error {
+	dirEntries, err := os.ReadDir(c.cfg.Dir)
+	if err != nil {
+		return err
+	}
+
+	type found struct {
+		e   *entry
+		mod time.Time
+	}
+	var files []found
+	for _, de := range dirEntries {
+		name := de.Name()
+		if de.IsDir() {
+			continue
+		}
+		if !strings.HasSuffix(name, fileExt) {
+			// leftovers of interrupted downloads
+			if strings.HasSuffix(name, ".tmp") {
+				_ = os.Remove(filepath.Join(c.cfg.Dir, name))
+			}
+			continue
+		}
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, found{
+			e:   &entry{key: strings.TrimSuffix(name, fileExt), path: filepath.Join(c.cfg.Dir, name), size: info.Size()},
+			mod: info.ModTime(),
+		})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].mod.Before(files[j].mod) })
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, f := range files {
+		c.entries[f.e.key] = c.lru.PushFront(f.e)
+		c.size += f.e.size
+	}
+	c.evictLocked()
+	return nil
+}
+
+func keyFor(url string) string {
+	sum := sha1.Sum([]byte(strings.TrimSpace(url)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Lookup returns the local file for url and counts a hit or a miss.
+func (c *Cache) Lookup(url string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	key := keyFor(url)
+
+	c.mu.Lock()
+	el, ok := c.entries[key]
+	if ok {
+		c.lru.MoveToFront(el)
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		c.misses.Add(1)
+		return "", false
+	}
+	e := el.Value.(*entry)
+	if _, err := os.Stat(e.path); err != nil {
+		// removed behind our back
+		c.mu.Lock()
+		c.removeLocked(el)
+		c.mu.Unlock()
+		c.misses.Add(1)
+		return "", false
+	}
+	c.hits.Add(1)
+	return e.path, true
+}
+
+// Has reports whether url is cached without touching the hit/miss counters.
+func (c *Cache) Has(url string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.entries[keyFor(url)]
+	return ok
+}
+
+// Prefetch schedules a background download of url unless it is cached or already queued.
+func (c *Cache) Prefetch(url string) {
+	if c == nil || url == "" || !c.dl.Cacheable(url) {
+		return
+	}
+	key := keyFor(url)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; ok || c.pending[key] {
+		return
+	}
+	select {
+	case c.jobs <- url:
+		c.pending[key] = true
+	default:
+		log.Printf("кэш: очередь загрузок переполнена, пропускаем %s", url)
+	}
+}
+
+func (c *Cache) Stats() Stats {
+	if c == nil {
+		return Stats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Stats{
+		Enabled:  true,
+		Hits:     c.hits.Load(),
+		Misses:   c.misses.Load(),
+		Files:    c.lru.Len(),
+		Bytes:    c.size,
+		MaxBytes: c.cfg.MaxBytes,
+		Pending:  len(c.pending),
+	}
+}
+
+func (c *Cache) worker() {
+	for url := range c.jobs {
+		c.download(url)
+	}
+}
+
+func (c *Cache) download(url string) {
+	key := keyFor(url)
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, key)
+		c.mu.Unlock()
+	}()
+
+	final := filepath.Join(c.cfg.Dir, key+fileExt)
+	tmp := final + ".tmp"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
+	defer cancel()
+	if err := c.dl.DownloadAudio(ctx, url, tmp); err != nil {
+		_ = os.Remove(tmp)
+		log.Printf("кэш: ошибка загрузки url=%s: %v", url, err)
+		return
+	}
+	info, err := os.Stat(tmp)
+	if err != nil {
+		log.Printf("кэш: файл загрузки не найден url=%s: %v", url, err)
+		return
+	}
+	if err := os.Rename(tmp, final); err != nil {
+		_ = os.Remove(tmp)
+		log.Printf("кэш: не удалось сохранить url=%s: %v", url, err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.size -= el.Value.(*entry).size
+		c.lru.Remove(el)
+	}
+	c.entries[key] = c.lru.PushFront(&entry{key: key, path: final, size: info.Size()})
+	c.size += info.Size()
+	c.evictLocked()
+	log.Printf("кэш: сохранён url=%s bytes=%d", url, info.Size())
+}
+
+// evictLocked removes least recently used files until the cache fits MaxBytes.
+func (c *Cache) evictLocked() {
+	for c.cfg.MaxBytes > 0 && c.size > c.cfg.MaxBytes && c.lru.Len() > 0 {
+		el := c.lru.Back()
+		_ = os.Remove(el.Value.(*entry).path)
+		c.removeLocked(el)
+	}
+}
+
+func (c *Cache) removeLocked(el *list.Element) {
+	e := el.Value.(*entry)
+	if _, ok := c.entries[e.key]; !ok {
+		return
+	}
+	delete(c.entries, e.key)
+	c.lru.Remove(el)
+	c.size -= e.size
+}
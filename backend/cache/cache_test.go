@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeDownloader writes size bytes for every URL; URLs under "local:" are not cacheable
+// and URLs containing "broken" fail.
+type fakeDownloader struct {
+	size int
+}
+
+func (d fakeDownloader) Cacheable(url string) bool { return !strings.HasPrefix(url, "local:") }
+
+func (d fakeDownloader) DownloadAudio(_ context.Context, url, dest string) error {
+	if strings.Contains(url, "broken") {
+		_ = os.WriteFile(dest, []byte("partial"), 0o644)
+		return errors.New("download failed")
+	}
+	return os.WriteFile(dest, make([]byte, d.size), 0o644)
+}
+
+func newTestCache(t *testing.T, maxBytes int64) *Cache {
+	t.Helper()
+	c, err := New(Config{Enabled: true, Dir: t.TempDir(), MaxBytes: maxBytes}, fakeDownloader{size: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// fetch prefetches url and waits until the worker is done with it.
+func fetch(t *testing.T, c *Cache, url string) {
+	t.Helper()
+	c.Prefetch(url)
+	deadline := time.Now().Add(5 * time.Second)
+	for c.Stats().Pending > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("download of %s did not finish", url)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestLookupHitsAndMisses(t *testing.T) {
+	c := newTestCache(t, 0)
+	if _, ok := c.Lookup("https://a"); ok {
+		t.Fatal("empty cache must miss")
+	}
+	fetch(t, c, "https://a")
+	path, ok := c.Lookup(" https://a ")
+	if !ok || filepath.Dir(path) != c.cfg.Dir {
+		t.Fatalf("lookup = %q, %v", path, ok)
+	}
+
+	// a file removed behind the cache's back turns into a miss
+	_ = os.Remove(path)
+	if _, ok := c.Lookup("https://a"); ok {
+		t.Fatal("missing file must miss")
+	}
+	st := c.Stats()
+	if st.Hits != 1 || st.Misses != 2 || st.Files != 0 || st.Bytes != 0 {
+		t.Fatalf("stats = %+v", st)
+	}
+}
+
+func TestEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newTestCache(t, 250)
+	fetch(t, c, "https://a")
+	fetch(t, c, "https://b")
+	c.Lookup("https://a") // a is now more recent than b
+	fetch(t, c, "https://c")
+
+	if !c.Has("https://a") || c.Has("https://b") || !c.Has("https://c") {
+		t.Fatalf("a=%v b=%v c=%v, want b evicted", c.Has("https://a"), c.Has("https://b"), c.Has("https://c"))
+	}
+	if st := c.Stats(); st.Bytes != 200 || st.Files != 2 {
+		t.Fatalf("stats = %+v", st)
+	}
+	if files, _ := os.ReadDir(c.cfg.Dir); len(files) != 2 {
+		t.Fatalf("%d files on disk, want 2", len(files))
+	}
+}
+
+func TestSkipsUncacheableAndFailed(t *testing.T) {
+	c := newTestCache(t, 0)
+	c.Prefetch("local:/music/a.mp3")
+	if st := c.Stats(); st.Pending != 0 {
+		t.Fatal("uncacheable URL must not be queued")
+	}
+
+	fetch(t, c, "https://broken")
+	if c.Has("https://broken") {
+		t.Fatal("failed download must not be indexed")
+	}
+	if files, _ := os.ReadDir(c.cfg.Dir); len(files) != 0 {
+		t.Fatalf("failed download left %d files", len(files))
+	}
+}
+
+func TestLoadRebuildsIndex(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, keyFor("https://old")+fileExt)
+	recent := filepath.Join(dir, keyFor("https://recent")+fileExt)
+	_ = os.WriteFile(old, make([]byte, 100), 0o644)
+	_ = os.WriteFile(recent, make([]byte, 100), 0o644)
+	_ = os.WriteFile(filepath.Join(dir, "x"+fileExt+".tmp"), []byte("partial"), 0o644)
+	past := time.Now().Add(-time.Hour)
+	_ = os.Chtimes(old, past, past)
+
+	// over the limit on start: the oldest file goes first
+	c, err := New(Config{Enabled: true, Dir: dir, MaxBytes: 150}, fakeDownloader{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Has("https://old") || !c.Has("https://recent") {
+		t.Fatal("expected only the recent file to survive")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "x"+fileExt+".tmp")); !os.IsNotExist(err) {
+		t.Fatal("leftover download must be removed")
+	}
+}
+
+func TestDisabledCacheIsNil(t *testing.T) {
+	c, err := New(Config{}, fakeDownloader{})
+	if err != nil || c != nil {
+		t.Fatalf("New = %v, %v", c, err)
+	}
+	c.Prefetch("https://a")
+	if _, ok := c.Lookup("https://a"); ok || c.Has("https://a") || c.Stats().Enabled {
+		t.Fatal("nil cache must behave as empty")
+	}
+}
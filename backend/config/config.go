@@ -5,6 +5,7 @@ package config
 import (
 	"log"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -12,6 +13,9 @@ import (
 type Config struct {
 	Port string
 
+	// Station name shown to ICY clients (icy-name)
+	StationName string
+
 	// DB
 	DBDialect string // postgres|mysql|sqlite
 	DBDSN     string
@@ -22,6 +26,9 @@ type Config struct {
 	// CORS
 	CORSOrigins []string
 
+	// Reverse proxies whose X-Forwarded-For is trusted for the client IP (empty = none)
+	TrustedProxies []string
+
 	// Admin seed
 	AdminUsername string
 	AdminPassword string
@@ -32,8 +39,62 @@ type Config struct {
 	// Tools
 	YTDLPPath               string
 	FFMPEGPath              string
+	FFProbePath             string
 	YTDLPCookiesFromBrowser string
 
+	// Directories local files may be played from (file:// URLs); they are also scanned into the
+	// library every LibraryScanIntervalSec (0 = only at start). Empty = local source disabled.
+	LocalMediaDirs         []string
+	LibraryScanIntervalSec int
+
+	// Public track search (each call runs yt-dlp): requests per minute per client IP and
+	// searches running at once across all clients
+	SearchRateLimitPerMin int
+	SearchMaxConcurrent   int
+
+	// Local audio cache
+	CacheEnabled bool
+	CacheDir     string
+	CacheMaxMB   int
+
+	// Stream mounts (/stream, /stream.mp3?br=128, /stream.ogg, /stream.aac).
+	// The first mount is served at /stream.
+	StreamMounts []StreamMount
+
+	// Loudness normalization (EBU R128)
+	LoudnessEnabled   bool
+	LoudnessTargetI   float64
+	LoudnessTargetTP  float64
+	LoudnessTargetLRA float64
+
+	// Song request quotas (0 = unlimited); donations and the owner are exempt
+	RequestMaxPending        int
+	RequestMaxPlaylistTracks int
+	RequestCooldownSec       int
+
+	// Duplicate requests: merge|reject|off; recently played tracks are rejected for the window
+	DuplicatePolicy string
+	RecentWindowMin int
+
+	// Crossfade between tracks, seconds (0 = hard cut, max 10)
+	CrossfadeSec float64
+
+	// Queue window (GET /api/playlist, queue_update): played and upcoming entries
+	QueueWindowPrev int
+	QueueWindowNext int
+
+	// Listener skip voting: a fixed vote count, or a percentage of WebSocket listeners
+	VoteSkipEnabled bool
+	VoteSkipVotes   int
+	VoteSkipPercent int
+	VoteSkipMin     int // floor of a percentage threshold
+
+	// HLS output (/hls/live.m3u8)
+	HLSEnabled    bool
+	HLSDir        string
+	HLSSegmentSec int
+	HLSWindowSize int
+
 	// Twitch bot (optional)
 	RunTwitchBot          bool
 	TwitchNick            string
@@ -43,10 +104,12 @@ type Config struct {
 	TwitchSpamMax         int
 	TwitchSpamDelayMs     int
 	TwitchRateLimitPerMin int
+	TwitchMaxConcurrentSR int // !sr requests resolved at once
 }
 
 func MustLoad() Config {
 	port := getEnv("PORT", "8080")
+	stationName := getEnv("STATION_NAME", "RadioKpowka")
 
 	dbDialect := getEnv("DB_DIALECT", "sqlite")
 	dbDSN := getEnv("DB_DSN", "file:radiokpowka.db?_foreign_keys=on")
@@ -58,6 +121,7 @@ func MustLoad() Config {
 
 	originsRaw := getEnv("CORS_ORIGINS", "http://localhost:5173")
 	origins := splitCSV(originsRaw)
+	trustedProxies := splitCSV(getEnv("TRUSTED_PROXIES", ""))
 
 	adminUser := getEnv("ADMIN_USERNAME", "admin")
 	adminPass := getEnv("ADMIN_PASSWORD", "admin123")
@@ -66,7 +130,64 @@ func MustLoad() Config {
 
 	ytDlp := getEnv("YTDLP_PATH", "yt-dlp")
 	ffmpeg := getEnv("FFMPEG_PATH", "ffmpeg")
+	ffprobe := getEnv("FFPROBE_PATH", "ffprobe")
 	ytCookies := getEnv("YTDLP_COOKIES_FROM_BROWSER", "")
+	mediaDirs := splitCSV(getEnv("LOCAL_MEDIA_DIRS", ""))
+	libraryScanSec := getEnvInt("LIBRARY_SCAN_INTERVAL_SEC", 300)
+	searchRate := getEnvInt("SEARCH_RATE_LIMIT_PER_MIN", 10)
+	searchConcurrent := getEnvInt("SEARCH_MAX_CONCURRENT", 2)
+
+	cacheEnabled := getEnvBool("CACHE_ENABLED", true)
+	cacheDir := getEnv("CACHE_DIR", "audio-cache")
+	cacheMaxMB := getEnvInt("CACHE_MAX_MB", 2048)
+
+	mounts := parseMounts(getEnv("STREAM_MOUNTS", "mp3:192,mp3:128,opus:96,aac:128"))
+
+	loudEnabled := getEnvBool("LOUDNESS_NORMALIZE", true)
+	loudI := getEnvFloat("LOUDNESS_TARGET_I", -16)
+	loudTP := getEnvFloat("LOUDNESS_TARGET_TP", -1.5)
+	loudLRA := getEnvFloat("LOUDNESS_TARGET_LRA", 11)
+
+	reqMaxPending := getEnvInt("REQUEST_MAX_PENDING", 5)
+	reqMaxPlaylist := getEnvInt("REQUEST_MAX_PLAYLIST_TRACKS", 10)
+	reqCooldown := getEnvInt("REQUEST_COOLDOWN_SEC", 30)
+
+	dupPolicy := strings.ToLower(getEnv("DUPLICATE_POLICY", "merge"))
+	switch dupPolicy {
+	case "merge", "reject", "off":
+	default:
+		dupPolicy = "merge"
+	}
+	recentWindow := getEnvInt("RECENT_WINDOW_MIN", 60)
+	if recentWindow < 0 {
+		recentWindow = 0
+	}
+
+	crossfade := getEnvFloat("CROSSFADE_SEC", 3)
+	if crossfade < 0 {
+		crossfade = 0
+	}
+	if crossfade > 10 {
+		crossfade = 10
+	}
+
+	windowPrev := max(getEnvInt("QUEUE_WINDOW_PREV", 10), 0)
+	windowNext := max(getEnvInt("QUEUE_WINDOW_NEXT", 50), 1)
+
+	voteEnabled := getEnvBool("VOTE_SKIP_ENABLED", true)
+	voteVotes, votePercent := parseVoteThreshold(getEnv("VOTE_SKIP_THRESHOLD", "3"))
+	voteMin := max(getEnvInt("VOTE_SKIP_MIN_VOTES", 3), 1)
+
+	hlsEnabled := getEnvBool("HLS_ENABLED", true)
+	hlsDir := getEnv("HLS_DIR", filepath.Join(os.TempDir(), "radiokpowka-hls"))
+	hlsSegment := getEnvInt("HLS_SEGMENT_SEC", 4)
+	if hlsSegment < 1 {
+		hlsSegment = 1
+	}
+	hlsWindow := getEnvInt("HLS_WINDOW_SIZE", 6)
+	if hlsWindow < 2 {
+		hlsWindow = 2
+	}
 
 	runBot := getEnvBool("RUN_TWITCH_BOT", false)
 	tNick := getEnv("TWITCH_NICK", "")
@@ -76,9 +197,11 @@ func MustLoad() Config {
 	spamMax := getEnvInt("TWITCH_SPAM_MAX", 5)
 	spamDelay := getEnvInt("TWITCH_SPAM_DELAY_MS", 900)
 	rateLimit := getEnvInt("TWITCH_GLOBAL_RATE_LIMIT_PER_MIN", 18)
+	srConcurrent := getEnvInt("TWITCH_SR_MAX_CONCURRENT", 2)
 
 	return Config{
-		Port: port,
+		Port:        port,
+		StationName: stationName,
 
 		DBDialect: dbDialect,
 		DBDSN:     dbDSN,
@@ -87,6 +210,8 @@ func MustLoad() Config {
 
 		CORSOrigins: origins,
 
+		TrustedProxies: trustedProxies,
+
 		AdminUsername: adminUser,
 		AdminPassword: adminPass,
 
@@ -94,8 +219,48 @@ func MustLoad() Config {
 
 		YTDLPPath:               ytDlp,
 		FFMPEGPath:              ffmpeg,
+		FFProbePath:             ffprobe,
 		YTDLPCookiesFromBrowser: ytCookies,
 
+		LocalMediaDirs:         mediaDirs,
+		LibraryScanIntervalSec: libraryScanSec,
+
+		SearchRateLimitPerMin: searchRate,
+		SearchMaxConcurrent:   searchConcurrent,
+
+		CacheEnabled: cacheEnabled,
+		CacheDir:     cacheDir,
+		CacheMaxMB:   cacheMaxMB,
+
+		StreamMounts: mounts,
+
+		LoudnessEnabled:   loudEnabled,
+		LoudnessTargetI:   loudI,
+		LoudnessTargetTP:  loudTP,
+		LoudnessTargetLRA: loudLRA,
+
+		RequestMaxPending:        reqMaxPending,
+		RequestMaxPlaylistTracks: reqMaxPlaylist,
+		RequestCooldownSec:       reqCooldown,
+
+		DuplicatePolicy: dupPolicy,
+		RecentWindowMin: recentWindow,
+
+		CrossfadeSec: crossfade,
+
+		QueueWindowPrev: windowPrev,
+		QueueWindowNext: windowNext,
+
+		VoteSkipEnabled: voteEnabled,
+		VoteSkipVotes:   voteVotes,
+		VoteSkipPercent: votePercent,
+		VoteSkipMin:     voteMin,
+
+		HLSEnabled:    hlsEnabled,
+		HLSDir:        hlsDir,
+		HLSSegmentSec: hlsSegment,
+		HLSWindowSize: hlsWindow,
+
 		RunTwitchBot:          runBot,
 		TwitchNick:            tNick,
 		TwitchOAuthToken:      tTok,
@@ -104,9 +269,54 @@ func MustLoad() Config {
 		TwitchSpamMax:         spamMax,
 		TwitchSpamDelayMs:     spamDelay,
 		TwitchRateLimitPerMin: rateLimit,
+		TwitchMaxConcurrentSR: srConcurrent,
 	}
 }
 
+type StreamMount struct {
+	Codec   string // mp3|opus|aac
+	Bitrate int    // kbps
+}
+
+// parseMounts reads "codec:kbps" pairs, e.g. "mp3:192,opus:96". Invalid entries are skipped.
+func parseMounts(raw string) []StreamMount {
+	var out []StreamMount
+	for _, item := range splitCSV(raw) {
+		codec, br, _ := strings.Cut(item, ":")
+		codec = strings.ToLower(strings.TrimSpace(codec))
+		n, err := strconv.Atoi(strings.TrimSpace(br))
+		if err != nil || n <= 0 {
+			log.Printf("STREAM_MOUNTS: пропускаем %q (нужно codec:kbps)", item)
+			continue
+		}
+		switch codec {
+		case "mp3", "opus", "aac":
+			out = append(out, StreamMount{Codec: codec, Bitrate: n})
+		default:
+			log.Printf("STREAM_MOUNTS: неизвестный кодек %q", codec)
+		}
+	}
+	if len(out) == 0 {
+		out = []StreamMount{{Codec: "mp3", Bitrate: 192}}
+	}
+	return out
+}
+
+// parseVoteThreshold reads "N" (votes) or "N%" (percent of listeners).
+func parseVoteThreshold(raw string) (votes, percent int) {
+	raw = strings.TrimSpace(raw)
+	if p, ok := strings.CutSuffix(raw, "%"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err == nil && n > 0 && n <= 100 {
+			return 0, n
+		}
+	} else if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+		return n, 0
+	}
+	log.Printf("VOTE_SKIP_THRESHOLD: некорректное значение %q, используется 3", raw)
+	return 3, 0
+}
+
 func getEnv(k, def string) string {
 	if v := os.Getenv(k); strings.TrimSpace(v) != "" {
 		return v
@@ -146,3 +356,15 @@ func getEnvInt(k string, def int) int {
 	}
 	return n
 }
+
+func getEnvFloat(k string, def float64) float64 {
+	v := strings.TrimSpace(os.Getenv(k))
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return def
+	}
+	return f
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMounts(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want []StreamMount
+	}{
+		{"mp3:192, OPUS:96,aac:128", []StreamMount{{"mp3", 192}, {"opus", 96}, {"aac", 128}}},
+		{"mp3:192,flac:900,mp3:abc,opus:-1", []StreamMount{{"mp3", 192}}},
+		{"", []StreamMount{{"mp3", 192}}},
+		{"wav:1411", []StreamMount{{"mp3", 192}}},
+	}
+	for _, tc := range cases {
+		if got := parseMounts(tc.raw); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("parseMounts(%q) = %v, want %v", tc.raw, got, tc.want)
+		}
+	}
+}
+
+func TestParseVoteThreshold(t *testing.T) {
+	cases := []struct {
+		raw            string
+		votes, percent int
+	}{
+		{"5", 5, 0},
+		{" 30% ", 0, 30},
+		{"100%", 0, 100},
+		{"150%", 3, 0},
+		{"0", 3, 0},
+		{"lots", 3, 0},
+	}
+	for _, tc := range cases {
+		if v, p := parseVoteThreshold(tc.raw); v != tc.votes || p != tc.percent {
+			t.Errorf("parseVoteThreshold(%q) = %d, %d%%; want %d, %d%%", tc.raw, v, p, tc.votes, tc.percent)
+		}
+	}
+}
@@ -12,5 +12,10 @@ func AutoMigrate(database *gorm.DB) error {
 		&QueueEntry{},
 		&Donation{},
 		&Integration{},
+		&Playlist{},
+		&PlaylistItem{},
+		&PlayerRuntime{},
+		&Setting{},
+		&PlayHistory{},
 	)
 }
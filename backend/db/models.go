@@ -23,6 +23,14 @@ type Track struct {
 	ID            uuid.UUID      `gorm:"type:char(36);primaryKey" json:"id"`
 	Title         string         `gorm:"size:512;not null" json:"title"`
 	SourceURL     string         `gorm:"size:2048;not null" json:"source_url"`
+	CanonicalID   string         `gorm:"size:256;index" json:"canonical_id"` // e.g. youtube:<video id>
+	Provider      string         `gorm:"size:32;not null;default:youtube" json:"provider"` // audio source: youtube|soundcloud|bandcamp|http|local|ytdlp
+	Artist        string         `gorm:"size:512" json:"artist,omitempty"`
+	Album         string         `gorm:"size:512" json:"album,omitempty"`
+	// Library rows are the catalog of scanned local files; requests copy them into their own rows.
+	Library     bool       `gorm:"not null;default:false;index" json:"library"`
+	FileSize    int64      `gorm:"not null;default:0" json:"file_size,omitempty"`
+	FileModTime *time.Time `json:"file_mod_time,omitempty"`
 	DurationSec   int            `gorm:"not null;default:0" json:"duration"`
 	AddedByUserID *uuid.UUID     `gorm:"type:char(36)" json:"added_by_user_id,omitempty"`
 	AddedByNick   string         `gorm:"size:128" json:"added_by_nick"`
@@ -37,6 +45,13 @@ type QueueEntry struct {
 	Status    string    `gorm:"size:16;not null;index" json:"status"` // prev|current|next
 	AddedAt   time.Time `gorm:"not null" json:"added_at"`
 	IsDonation bool     `gorm:"not null;default:false" json:"is_donation"`
+	DonationAmount   int64 `gorm:"not null;default:0" json:"donation_amount"`
+	DonationPriority int   `gorm:"not null;default:0" json:"donation_priority"` // tier at the time of the donation
+	IsFallback bool     `gorm:"not null;default:false" json:"is_fallback"` // queued by autopilot
+	FallbackPlaylistID *uuid.UUID `gorm:"type:char(36);index" json:"fallback_playlist_id,omitempty"` // autopilot playlist it came from
+	RequestCount int            `gorm:"not null;default:1" json:"request_count"` // merged duplicate requests
+	Requesters   datatypes.JSON `gorm:"type:json" json:"-"`                      // requester keys counted in RequestCount
+	PlayedAt     *time.Time     `gorm:"index" json:"played_at,omitempty"`        // last time it became current
 }
 
 type Donation struct {
@@ -55,8 +70,62 @@ type Integration struct {
 	ConnectedAt *time.Time     `json:"connected_at,omitempty"`
 }
 
+// Playlist is a fallback ("autopilot") source played when the user queue is empty.
 type Playlist struct {
 	ID        uuid.UUID `gorm:"type:char(36);primaryKey" json:"id"`
 	URL       string    `gorm:"size:2048;not null" json:"url"`
+	Name      string    `gorm:"size:256" json:"name"`
+	Mode      string    `gorm:"size:16;not null;default:random" json:"mode"` // random|sequential
+	Enabled   bool      `gorm:"not null;default:true" json:"enabled"`
+	NextIndex int       `gorm:"not null;default:0" json:"next_index"` // next item in sequential mode
 	CreatedAt time.Time `json:"created_at"`
 }
+
+// PlaylistItem is one resolved entry of a fallback playlist.
+type PlaylistItem struct {
+	ID          uuid.UUID  `gorm:"type:char(36);primaryKey" json:"id"`
+	PlaylistID  uuid.UUID  `gorm:"type:char(36);not null;index" json:"playlist_id"`
+	Position    int        `gorm:"not null" json:"position"`
+	Title       string     `gorm:"size:512;not null" json:"title"`
+	SourceURL   string     `gorm:"size:2048;not null" json:"source_url"`
+	DurationSec int        `gorm:"not null;default:0" json:"duration"`
+	TrackID     *uuid.UUID `gorm:"type:char(36)" json:"track_id,omitempty"` // reused each time the item is queued
+}
+
+// PlayerRuntime is the saved playback state (single row, ID 1) restored on boot.
+type PlayerRuntime struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	QueueEntryID string    `gorm:"size:36" json:"queue_entry_id"`
+	IsPlaying    bool      `gorm:"not null" json:"is_playing"`
+	IsPaused     bool      `gorm:"not null" json:"is_paused"`
+	Volume       float64   `gorm:"not null" json:"volume"`
+	PositionSec  int       `gorm:"not null" json:"position_sec"`
+	Shuffle      bool      `gorm:"not null" json:"shuffle"`
+	ShuffleSeed  int64     `gorm:"not null" json:"shuffle_seed"`
+	Repeat       string    `gorm:"size:8;not null" json:"repeat"`
+	FairQueue    bool      `gorm:"not null;default:false" json:"fair_queue"`
+	RepeatSince  *time.Time `json:"repeat_since,omitempty"` // start of the repeat-all run
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// PlayHistory is one play of a queue entry: written when it starts, closed when it ends.
+type PlayHistory struct {
+	ID           uuid.UUID  `gorm:"type:char(36);primaryKey" json:"id"`
+	QueueEntryID uuid.UUID  `gorm:"type:char(36);index" json:"queue_entry_id"`
+	TrackID      uuid.UUID  `gorm:"type:char(36);index" json:"track_id"`
+	Title        string     `gorm:"size:512;not null" json:"title"`
+	SourceURL    string     `gorm:"size:2048;not null" json:"source_url"`
+	AddedByNick  string     `gorm:"size:128" json:"added_by_nick"`
+	DurationSec  int        `gorm:"not null;default:0" json:"duration"`
+	StartedAt    time.Time  `gorm:"not null;index" json:"started_at"`
+	EndedAt      *time.Time `json:"ended_at,omitempty"`
+	ListenedSec  int        `gorm:"not null;default:0" json:"listened_sec"` // position reached
+	Skipped      bool       `gorm:"not null;default:false" json:"skipped"`
+}
+
+// Setting is an owner-editable JSON setting (request rules, donation tiers, ...).
+type Setting struct {
+	Key       string         `gorm:"size:64;primaryKey" json:"key"`
+	ValueJSON datatypes.JSON `gorm:"type:json" json:"value_json"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
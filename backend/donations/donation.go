@@ -1,4 +1,5 @@
-// Purpose: Donation webhook parsing + insert-next track logic.
+// Purpose: Donation webhook parsing + insert-next track logic (placement by amount tier is
+// done by the player, see player.DonationTiers).
 
 package donations
 
@@ -33,6 +34,7 @@ type Deps struct {
 
 var ytURL = regexp.MustCompile(`https?://(www\.)?(youtube\.com/watch\?v=[\w-]+|youtu\.be/[\w-]+)`)
 var ytPlaylist = regexp.MustCompile(`https?://(www\.)?youtube\.com/playlist\?list=[\w-]+`)
+var srCommand = regexp.MustCompile(`(?i)(?:^|\s)!sr\s+([^\r\n]+)`)
 
 func HandleDonation(ctx context.Context, d Deps, payload IncomingDonation) error {
 	if strings.TrimSpace(payload.DonorNick) == "" {
@@ -45,6 +47,11 @@ func HandleDonation(ctx context.Context, d Deps, payload IncomingDonation) error
 	if trackURL == "" && msg != "" {
 		trackURL = extractFirstTrackURL(msg)
 	}
+	// no link: "!sr artist - song" requests the top search result
+	request := trackURL
+	if request == "" && msg != "" {
+		request = extractSongRequest(msg)
+	}
 
 	// persist donation (always)
 	row := db.Donation{
@@ -67,9 +74,15 @@ func HandleDonation(ctx context.Context, d Deps, payload IncomingDonation) error
 		},
 	})
 
-	// if we have track link -> insert next in queue
-	if trackURL != "" {
-		_, err := d.Player.AddTrack(trackURL, nil, payload.DonorNick, true, true)
+	// if we have track link or query -> insert next in queue
+	if request != "" {
+		_, err := d.Player.AddTrack(player.AddRequest{
+			URL:            request,
+			Nick:           payload.DonorNick,
+			InsertNext:     true,
+			IsDonation:     true,
+			DonationAmount: payload.Amount,
+		})
 		return err
 	}
 
@@ -87,4 +100,13 @@ func extractFirstTrackURL(msg string) string {
 	return ""
 }
 
+// extractSongRequest returns the text after "!sr" (up to the end of the line), "" if absent.
+func extractSongRequest(msg string) string {
+	m := srCommand.FindStringSubmatch(msg)
+	if m == nil {
+		return ""
+	}
+	return strings.TrimSpace(m[1])
+}
+
 var _ = errors.New
@@ -0,0 +1,37 @@
+package donations
+
+import "testing"
+
+func TestExtractSongRequest(t *testing.T) {
+	cases := []struct {
+		msg, want string
+	}{
+		{"!sr Artist - Song", "Artist - Song"},
+		{"спасибо за стрим! !SR Artist - Song\nи привет чату", "Artist - Song"},
+		{"хочу трек !sr   Song  ", "Song"},
+		{"!sr", ""},
+		{"nice!sr song", ""},
+		{"просто донат", ""},
+	}
+	for _, tc := range cases {
+		if got := extractSongRequest(tc.msg); got != tc.want {
+			t.Errorf("extractSongRequest(%q) = %q, want %q", tc.msg, got, tc.want)
+		}
+	}
+}
+
+func TestExtractFirstTrackURL(t *testing.T) {
+	cases := []struct {
+		msg, want string
+	}{
+		{"вот https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=10 и ещё https://youtu.be/other", "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{"https://youtu.be/dQw4w9WgXcQ", "https://youtu.be/dQw4w9WgXcQ"},
+		{"плейлист https://youtube.com/playlist?list=PL123", "https://youtube.com/playlist?list=PL123"},
+		{"!sr Artist - Song", ""},
+	}
+	for _, tc := range cases {
+		if got := extractFirstTrackURL(tc.msg); got != tc.want {
+			t.Errorf("extractFirstTrackURL(%q) = %q, want %q", tc.msg, got, tc.want)
+		}
+	}
+}
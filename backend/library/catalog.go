@@ -0,0 +1,79 @@
+// Purpose: Library listing and search (GET /api/library).
+
+package library
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+
+	"radiokpowka/backend/db"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+type TrackDTO struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Artist      string `json:"artist,omitempty"`
+	Album       string `json:"album,omitempty"`
+	DurationSec int    `json:"durationSec"`
+}
+
+type Query struct {
+	Search string // case-insensitive substring of title, artist or album
+	Offset int
+	Limit  int
+}
+
+type Page struct {
+	Items []TrackDTO `json:"items"`
+	Total int64      `json:"total"`
+}
+
+// List returns catalog tracks ordered by artist, album and title.
+func (l *Library) List(q Query) (Page, error) {
+	if l == nil {
+		return Page{Items: []TrackDTO{}}, nil
+	}
+	if q.Limit <= 0 {
+		q.Limit = defaultPageSize
+	}
+	q.Limit = min(q.Limit, maxPageSize)
+	q.Offset = max(q.Offset, 0)
+
+	tx := l.db.Model(&db.Track{}).Where("library = ?", true)
+	if s := strings.ToLower(strings.TrimSpace(q.Search)); s != "" {
+		like := "%" + escapeLike(s) + "%"
+		tx = tx.Where("LOWER(title) LIKE ? ESCAPE '!' OR LOWER(artist) LIKE ? ESCAPE '!' OR LOWER(album) LIKE ? ESCAPE '!'", like, like, like)
+	}
+
+	tx = tx.Session(&gorm.Session{}) // reused for the count and the page
+
+	var page Page
+	if err := tx.Count(&page.Total).Error; err != nil {
+		return Page{}, err
+	}
+	var rows []db.Track
+	if err := tx.Order("artist, album, title").Offset(q.Offset).Limit(q.Limit).Find(&rows).Error; err != nil {
+		return Page{}, err
+	}
+	page.Items = make([]TrackDTO, 0, len(rows))
+	for _, t := range rows {
+		page.Items = append(page.Items, TrackDTO{
+			ID:          t.ID.String(),
+			Title:       t.Title,
+			Artist:      t.Artist,
+			Album:       t.Album,
+			DurationSec: t.DurationSec,
+		})
+	}
+	return page, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
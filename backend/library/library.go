@@ -0,0 +1,306 @@
+// Purpose: Local music library.
+// - LOCAL_MEDIA_DIRS are scanned for audio files; title/artist/album/duration come from ffprobe.
+// - Each file is one catalog Track row (provider "local", file:// source URL, Library = true).
+// - The folders are polled for changes: new and modified files are probed again, removed ones
+//   leave the catalog. Queued requests copy the catalog row, so the queue is never affected.
+// - Listing and search for the UI; queueing by ID goes through player.AddTrack.
+
+package library
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"radiokpowka/backend/db"
+	"radiokpowka/backend/source"
+	"radiokpowka/backend/youtube"
+)
+
+var (
+	ErrDisabled    = errors.New("library is disabled (LOCAL_MEDIA_DIRS is empty)")
+	ErrScanRunning = errors.New("library scan is already running")
+)
+
+type Config struct {
+	Dirs        []string
+	FFProbePath string
+	// Interval between rescans (0 = scan only at start and on demand).
+	Interval time.Duration
+}
+
+// ScanResult summarizes one pass over the directories.
+type ScanResult struct {
+	Added      int       `json:"added"`
+	Updated    int       `json:"updated"`
+	Removed    int       `json:"removed"`
+	Failed     int       `json:"failed"` // files ffprobe could not read
+	Total      int       `json:"total"`
+	FinishedAt time.Time `json:"finishedAt"`
+}
+
+type Status struct {
+	Enabled bool        `json:"enabled"`
+	Running bool        `json:"running"`
+	Last    *ScanResult `json:"last,omitempty"`
+}
+
+type Library struct {
+	db  *gorm.DB
+	cfg Config
+
+	scanMu sync.Mutex // held for the whole scan
+
+	mu      sync.Mutex
+	running bool
+	last    *ScanResult
+}
+
+// New returns nil when no directories are configured; all methods are nil-safe.
+func New(database *gorm.DB, cfg Config) *Library {
+	if len(cfg.Dirs) == 0 {
+		return nil
+	}
+	if cfg.FFProbePath == "" {
+		cfg.FFProbePath = "ffprobe"
+	}
+	return &Library{db: database, cfg: cfg}
+}
+
+// Start runs the initial scan and then polls the directories every Interval.
+func (l *Library) Start() {
+	if l == nil {
+		return
+	}
+	go func() {
+		for {
+			if _, err := l.Scan(context.Background()); err != nil && !errors.Is(err, ErrScanRunning) {
+				log.Printf("библиотека: сканирование не удалось: %v", err)
+			}
+			if l.cfg.Interval <= 0 {
+				return
+			}
+			time.Sleep(l.cfg.Interval)
+		}
+	}()
+}
+
+// ScanAsync starts a scan in the background (POST /api/library/scan).
+func (l *Library) ScanAsync() error {
+	if l == nil {
+		return ErrDisabled
+	}
+	// claimed before returning: a second request right after this one must get ErrScanRunning
+	if !l.beginScan() {
+		return ErrScanRunning
+	}
+	go func() {
+		defer l.endScan()
+		if _, err := l.scan(context.Background()); err != nil {
+			log.Printf("библиотека: сканирование не удалось: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (l *Library) Status() Status {
+	if l == nil {
+		return Status{}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return Status{Enabled: true, Running: l.running, Last: l.last}
+}
+
+// libraryFile is a catalog row as needed for change detection.
+type libraryFile struct {
+	ID          uuid.UUID
+	SourceURL   string
+	FileSize    int64
+	FileModTime *time.Time
+}
+
+// Scan walks all directories and syncs the catalog with what is on disk.
+func (l *Library) Scan(ctx context.Context) (ScanResult, error) {
+	if l == nil {
+		return ScanResult{}, ErrDisabled
+	}
+	if !l.beginScan() {
+		return ScanResult{}, ErrScanRunning
+	}
+	defer l.endScan()
+	return l.scan(ctx)
+}
+
+// beginScan claims the scanner; false when a scan is already running.
+func (l *Library) beginScan() bool {
+	if !l.scanMu.TryLock() {
+		return false
+	}
+	l.setRunning(true)
+	return true
+}
+
+func (l *Library) endScan() {
+	l.setRunning(false)
+	l.scanMu.Unlock()
+}
+
+// scan does the work of Scan; the caller holds the scanner (beginScan).
+func (l *Library) scan(ctx context.Context) (ScanResult, error) {
+	var rows []libraryFile
+	if err := l.db.Model(&db.Track{}).Where("library = ?", true).
+		Select("id, source_url, file_size, file_mod_time").Scan(&rows).Error; err != nil {
+		return ScanResult{}, err
+	}
+	known := make(map[string]libraryFile, len(rows))
+	for _, r := range rows {
+		known[r.SourceURL] = r
+	}
+
+	var res ScanResult
+	seen := make(map[string]bool, len(rows))
+	complete := true
+	for _, dir := range l.cfg.Dirs {
+		root, err := filepath.Abs(dir)
+		if err == nil {
+			// same resolution as the local source, so the URLs pass its root check
+			if real, err := filepath.EvalSymlinks(root); err == nil {
+				root = real
+			}
+		}
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if path == root {
+					return err
+				}
+				log.Printf("библиотека: пропускаем %s: %v", path, err)
+				return nil
+			}
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if strings.HasPrefix(d.Name(), ".") && path != root {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() || !d.Type().IsRegular() || !source.IsAudioFile(d.Name()) {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			url := source.FileURL(path)
+			seen[url] = true
+			res.Total++
+
+			prev, ok := known[url]
+			if ok && prev.FileSize == info.Size() && prev.FileModTime != nil && prev.FileModTime.Equal(info.ModTime().UTC()) {
+				return nil
+			}
+			if err := l.syncFile(ctx, path, url, info, prev, ok); err != nil {
+				log.Printf("библиотека: не удалось прочитать %s: %v", path, err)
+				res.Failed++
+				return nil
+			}
+			if ok {
+				res.Updated++
+			} else {
+				res.Added++
+			}
+			return nil
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return res, ctx.Err()
+			}
+			// an unavailable directory (unmounted disk) must not empty the catalog
+			log.Printf("библиотека: каталог %s недоступен: %v", dir, err)
+			complete = false
+		}
+	}
+
+	if complete {
+		var gone []uuid.UUID
+		for url, r := range known {
+			if !seen[url] {
+				gone = append(gone, r.ID)
+			}
+		}
+		if len(gone) > 0 {
+			if err := l.db.Where("id IN ? AND library = ?", gone, true).Delete(&db.Track{}).Error; err != nil {
+				return res, err
+			}
+			res.Removed = len(gone)
+		}
+	}
+
+	res.FinishedAt = time.Now().UTC()
+	l.mu.Lock()
+	l.last = &res
+	l.mu.Unlock()
+	if res.Added+res.Updated+res.Removed+res.Failed > 0 {
+		log.Printf("библиотека: файлов %d, добавлено %d, обновлено %d, удалено %d, ошибок %d",
+			res.Total, res.Added, res.Updated, res.Removed, res.Failed)
+	}
+	return res, nil
+}
+
+func (l *Library) setRunning(v bool) {
+	l.mu.Lock()
+	l.running = v
+	l.mu.Unlock()
+}
+
+// syncFile probes a new or changed file and creates or updates its catalog row.
+func (l *Library) syncFile(ctx context.Context, path, url string, info os.FileInfo, prev libraryFile, exists bool) error {
+	m, err := source.Probe(ctx, l.cfg.FFProbePath, path)
+	if err != nil {
+		return err
+	}
+	title := m.Title
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	mod := info.ModTime().UTC()
+
+	if exists {
+		// the file changed: any stored loudness measurement is stale
+		return l.db.Model(&db.Track{}).Where("id = ?", prev.ID).Updates(map[string]any{
+			"title":         title,
+			"artist":        m.Channel,
+			"album":         m.Album,
+			"duration_sec":  m.DurationSec,
+			"file_size":     info.Size(),
+			"file_mod_time": mod,
+			"metadata_json": []byte(`{}`),
+		}).Error
+	}
+	return l.db.Create(&db.Track{
+		ID:           uuid.New(),
+		Title:        title,
+		SourceURL:    url,
+		CanonicalID:  youtube.CanonicalID(url),
+		Provider:     "local",
+		Artist:       m.Channel,
+		Album:        m.Album,
+		DurationSec:  m.DurationSec,
+		Library:      true,
+		FileSize:     info.Size(),
+		FileModTime:  &mod,
+		MetadataJSON: []byte(`{}`),
+		CreatedAt:    time.Now().UTC(),
+	}).Error
+}
@@ -0,0 +1,222 @@
+package library
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"radiokpowka/backend/db"
+)
+
+// fakeProbe is an ffprobe stand-in: test "audio" files hold the JSON ffprobe would print,
+// files named *broken* fail like unreadable media and *slow* ones take a while.
+const fakeProbe = `#!/bin/sh
+for a; do f="$a"; done
+case "$f" in *broken*) echo "Invalid data found when processing input" >&2; exit 1;; esac
+case "$f" in *slow*) sleep 0.3;; esac
+cat "$f"
+`
+
+func newTestLibrary(t *testing.T, dirs ...string) *Library {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ffprobe is a shell script")
+	}
+	probe := filepath.Join(t.TempDir(), "ffprobe")
+	if err := os.WriteFile(probe, []byte(fakeProbe), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	database, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(database); err != nil {
+		t.Fatal(err)
+	}
+	return New(database, Config{Dirs: dirs, FFProbePath: probe})
+}
+
+// writeTrack creates a fake audio file with the given tags.
+func writeTrack(t *testing.T, path, title, artist, album string, dur int) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	js := `{"format":{"duration":"` + strconv.Itoa(dur) + `.5","tags":{"TITLE":"` + title + `","artist":"` + artist + `","album":"` + album + `"}}}`
+	if err := os.WriteFile(path, []byte(js), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func scan(t *testing.T, l *Library) ScanResult {
+	t.Helper()
+	res, err := l.Scan(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func titles(p Page) []string {
+	out := make([]string, 0, len(p.Items))
+	for _, it := range p.Items {
+		out = append(out, it.Title)
+	}
+	return out
+}
+
+func TestScanSyncsCatalog(t *testing.T) {
+	dir := t.TempDir()
+	writeTrack(t, filepath.Join(dir, "a.mp3"), "Alpha", "Band", "One", 200)
+	writeTrack(t, filepath.Join(dir, "sub", "b.FLAC"), "", "Band", "One", 120)
+	writeTrack(t, filepath.Join(dir, ".hidden", "c.mp3"), "Hidden", "", "", 100)
+	writeTrack(t, filepath.Join(dir, "notes.txt"), "Notes", "", "", 1)
+	writeTrack(t, filepath.Join(dir, "broken.mp3"), "", "", "", 0)
+	l := newTestLibrary(t, dir)
+
+	res := scan(t, l)
+	if res.Added != 2 || res.Failed != 1 || res.Total != 3 {
+		t.Fatalf("first scan = %+v", res)
+	}
+	page, err := l.List(Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a file without a title tag is listed by its name
+	if got := titles(page); !slices.Equal(got, []string{"Alpha", "b"}) {
+		t.Fatalf("catalog = %v", got)
+	}
+	if page.Items[0].Artist != "Band" || page.Items[0].DurationSec != 200 {
+		t.Fatalf("tags not read: %+v", page.Items[0])
+	}
+
+	// nothing changed: no files are probed again
+	if res := scan(t, l); res.Added+res.Updated+res.Removed != 0 {
+		t.Fatalf("idle rescan = %+v", res)
+	}
+
+	writeTrack(t, filepath.Join(dir, "a.mp3"), "Alpha (remaster)", "Band", "One", 201)
+	later := time.Now().Add(time.Minute)
+	_ = os.Chtimes(filepath.Join(dir, "a.mp3"), later, later)
+	_ = os.Remove(filepath.Join(dir, "sub", "b.FLAC"))
+	if res := scan(t, l); res.Updated != 1 || res.Removed != 1 {
+		t.Fatalf("rescan = %+v", res)
+	}
+	page, _ = l.List(Query{})
+	if got := titles(page); !slices.Equal(got, []string{"Alpha (remaster)"}) {
+		t.Fatalf("catalog after changes = %v", got)
+	}
+}
+
+func TestScanKeepsCatalogOfMissingDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "music")
+	writeTrack(t, filepath.Join(dir, "a.mp3"), "Alpha", "", "", 200)
+	l := newTestLibrary(t, dir)
+	scan(t, l)
+
+	// an unmounted disk must not empty the catalog
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if res := scan(t, l); res.Removed != 0 {
+		t.Fatalf("scan of a missing dir = %+v", res)
+	}
+	if page, _ := l.List(Query{}); page.Total != 1 {
+		t.Fatalf("catalog total = %d", page.Total)
+	}
+}
+
+func TestListSearchAndPaging(t *testing.T) {
+	dir := t.TempDir()
+	writeTrack(t, filepath.Join(dir, "1.mp3"), "100% Pure", "Zed", "", 100)
+	writeTrack(t, filepath.Join(dir, "2.mp3"), "1000 Pure", "Zed", "", 100)
+	writeTrack(t, filepath.Join(dir, "3.mp3"), "snake_case", "Abba", "Gold", 100)
+	writeTrack(t, filepath.Join(dir, "4.mp3"), "snakeXcase", "Abba", "Gold", 100)
+	writeTrack(t, filepath.Join(dir, "5.mp3"), "Waterloo", "Abba", "Gold", 100)
+	l := newTestLibrary(t, dir)
+	scan(t, l)
+
+	cases := []struct {
+		search string
+		want   []string
+	}{
+		{"100%", []string{"100% Pure"}},                            // % is literal
+		{"snake_", []string{"snake_case"}},                         // _ is literal
+		{"GOLD", []string{"Waterloo", "snakeXcase", "snake_case"}}, // album, case-insensitive
+		{"  zed ", []string{"100% Pure", "1000 Pure"}},
+		{"nothing", []string{}},
+	}
+	for _, tc := range cases {
+		page, err := l.List(Query{Search: tc.search})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := titles(page); !slices.Equal(got, tc.want) || page.Total != int64(len(tc.want)) {
+			t.Errorf("search %q = %v (total %d), want %v", tc.search, got, page.Total, tc.want)
+		}
+	}
+
+	page, _ := l.List(Query{Offset: 1, Limit: 2})
+	if page.Total != 5 || len(page.Items) != 2 || page.Items[0].Title != "snakeXcase" {
+		t.Fatalf("page = %v (total %d)", titles(page), page.Total)
+	}
+}
+
+func TestScanAsyncRejectsSecondScan(t *testing.T) {
+	dir := t.TempDir()
+	writeTrack(t, filepath.Join(dir, "slow.mp3"), "Slow", "", "", 100)
+	l := newTestLibrary(t, dir)
+
+	if err := l.ScanAsync(); err != nil {
+		t.Fatal(err)
+	}
+	// the first scan is running as soon as the request returns
+	if !l.Status().Running {
+		t.Fatal("status does not report the started scan")
+	}
+	if err := l.ScanAsync(); err != ErrScanRunning {
+		t.Fatalf("second scan = %v, want ErrScanRunning", err)
+	}
+
+	wait := func() {
+		deadline := time.Now().Add(5 * time.Second)
+		for l.Status().Running {
+			if time.Now().After(deadline) {
+				t.Fatal("scan never finished")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	wait()
+	if last := l.Status().Last; last == nil || last.Added != 1 {
+		t.Fatalf("last scan = %+v", last)
+	}
+	if err := l.ScanAsync(); err != nil {
+		t.Fatalf("scan after the first finished = %v", err)
+	}
+	wait()
+}
+
+func TestDisabledLibrary(t *testing.T) {
+	var l *Library
+	if New(nil, Config{}) != nil {
+		t.Fatal("library without dirs must be nil")
+	}
+	if _, err := l.Scan(context.Background()); err != ErrDisabled {
+		t.Fatalf("scan = %v", err)
+	}
+	if page, err := l.List(Query{}); err != nil || page.Items == nil {
+		t.Fatalf("list = %+v, %v", page, err)
+	}
+}
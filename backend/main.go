@@ -5,6 +5,8 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -15,6 +17,7 @@ import (
 	"radiokpowka/backend/bot"
 	"radiokpowka/backend/config"
 	"radiokpowka/backend/db"
+	"radiokpowka/backend/player"
 )
 
 func main() {
@@ -33,7 +36,7 @@ func main() {
 		log.Fatalf("seed admin failed: %v", err)
 	}
 
-	handler := api.NewRouter(cfg, database)
+	handler, ctrl := api.NewRouter(cfg, database)
 
 	// Optionally start Twitch bot (non-blocking)
 	if cfg.RunTwitchBot {
@@ -46,12 +49,41 @@ func main() {
 				SpamMax:               cfg.TwitchSpamMax,
 				SpamDelay:             time.Duration(cfg.TwitchSpamDelayMs) * time.Millisecond,
 				GlobalRateLimitPerMin: cfg.TwitchRateLimitPerMin,
+				MaxConcurrentRequests: cfg.TwitchMaxConcurrentSR,
 				GetCurrentTrackText: func() string {
 					// The bot will call backend logic via HTTP in a future iteration.
 					// For now, it prints a placeholder. In "tests later" mode, keep it simple.
 					// You can later wire it to an internal player controller getter.
 					return "RadioKpowka: трек пока недоступен (заглушка)."
 				},
+				VoteSkip: func(nick string) string {
+					p, err := ctrl.VoteSkip(player.TwitchVoter(nick))
+					switch {
+					case errors.Is(err, player.ErrVoteSkipDisabled), errors.Is(err, player.ErrAlreadySkipped):
+						return ""
+					case errors.Is(err, player.ErrAlreadyVoted):
+						return fmt.Sprintf("@%s, вы уже голосовали (%d/%d)", nick, p.Votes, p.Needed)
+					case err != nil:
+						return ""
+					case p.Skipped:
+						return fmt.Sprintf("Голосование: трек пропущен (%d/%d)", p.Votes, p.Needed)
+					default:
+						return fmt.Sprintf("Голосование за пропуск: %d/%d", p.Votes, p.Needed)
+					}
+				},
+				RequestTrack: func(nick, query string) string {
+					_, err := ctrl.AddTrack(player.AddRequest{URL: query, Nick: nick})
+					var rerr *player.RequestError
+					switch {
+					case errors.As(err, &rerr):
+						return fmt.Sprintf("@%s, %s", nick, rerr.Message)
+					case err != nil:
+						log.Printf("twitch: !sr от %s не удался: %v", nick, err)
+						return fmt.Sprintf("@%s, не удалось добавить трек", nick)
+					default:
+						return fmt.Sprintf("@%s, трек добавлен в очередь", nick)
+					}
+				},
 			}); err != nil {
 				log.Printf("twitch bot stopped: %v", err)
 			}
@@ -0,0 +1,23 @@
+-- Purpose: Fallback ("autopilot") playlists for MySQL.
+
+ALTER TABLE queue_entries ADD COLUMN is_fallback BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE queue_entries ADD COLUMN fallback_playlist_id CHAR(36) NULL;
+CREATE INDEX idx_queue_entries_fallback_playlist_id ON queue_entries(fallback_playlist_id);
+
+ALTER TABLE playlists
+  ADD COLUMN name VARCHAR(256) NULL,
+  ADD COLUMN mode VARCHAR(16) NOT NULL DEFAULT 'random',
+  ADD COLUMN enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  ADD COLUMN next_index INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS playlist_items (
+  id CHAR(36) PRIMARY KEY,
+  playlist_id CHAR(36) NOT NULL,
+  position INT NOT NULL,
+  title VARCHAR(512) NOT NULL,
+  source_url TEXT NOT NULL,
+  duration_sec INT NOT NULL DEFAULT 0,
+  track_id CHAR(36) NULL,
+  CONSTRAINT fk_playlist_items_playlist FOREIGN KEY (playlist_id) REFERENCES playlists(id) ON DELETE CASCADE,
+  INDEX idx_playlist_items_playlist (playlist_id)
+);
@@ -0,0 +1,14 @@
+-- Purpose: Duplicate and recently-played detection for MySQL.
+
+ALTER TABLE tracks
+  ADD COLUMN canonical_id VARCHAR(256) NULL,
+  ADD INDEX idx_tracks_canonical_id (canonical_id);
+
+ALTER TABLE queue_entries
+  ADD COLUMN request_count INT NOT NULL DEFAULT 1,
+  ADD COLUMN requesters JSON NULL,
+  ADD COLUMN played_at TIMESTAMP NULL,
+  ADD INDEX idx_queue_played_at (played_at);
+
+-- start of the repeat-all run: only entries played since then are replayed
+ALTER TABLE player_runtimes ADD COLUMN repeat_since TIMESTAMP(3) NULL;
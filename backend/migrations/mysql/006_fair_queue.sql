@@ -0,0 +1,3 @@
+-- Purpose: Fair queue mode flag for MySQL.
+
+ALTER TABLE player_runtimes ADD COLUMN fair_queue BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,5 @@
+-- Purpose: Donation amount and tier priority on queue entries for MySQL.
+
+ALTER TABLE queue_entries
+  ADD COLUMN donation_amount BIGINT NOT NULL DEFAULT 0,
+  ADD COLUMN donation_priority INT NOT NULL DEFAULT 0;
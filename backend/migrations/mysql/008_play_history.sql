@@ -0,0 +1,18 @@
+-- Purpose: Play history for MySQL (one row per play of a queue entry).
+
+CREATE TABLE IF NOT EXISTS play_histories (
+  id CHAR(36) PRIMARY KEY,
+  queue_entry_id CHAR(36) NULL,
+  track_id CHAR(36) NULL,
+  title VARCHAR(512) NOT NULL,
+  source_url VARCHAR(2048) NOT NULL,
+  added_by_nick VARCHAR(128) NULL,
+  duration_sec INT NOT NULL DEFAULT 0,
+  started_at TIMESTAMP(3) NOT NULL,
+  ended_at TIMESTAMP(3) NULL,
+  listened_sec INT NOT NULL DEFAULT 0,
+  skipped BOOLEAN NOT NULL DEFAULT FALSE,
+  INDEX idx_play_histories_started_at (started_at),
+  INDEX idx_play_histories_queue_entry_id (queue_entry_id),
+  INDEX idx_play_histories_track_id (track_id)
+);
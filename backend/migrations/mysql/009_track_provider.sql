@@ -0,0 +1,4 @@
+-- Purpose: Audio source provider on tracks for MySQL.
+
+ALTER TABLE tracks
+  ADD COLUMN provider VARCHAR(32) NOT NULL DEFAULT 'youtube';
@@ -0,0 +1,9 @@
+-- Purpose: Local music library columns on tracks for MySQL.
+
+ALTER TABLE tracks
+  ADD COLUMN artist VARCHAR(512) NULL,
+  ADD COLUMN album VARCHAR(512) NULL,
+  ADD COLUMN library BOOLEAN NOT NULL DEFAULT FALSE,
+  ADD COLUMN file_size BIGINT NOT NULL DEFAULT 0,
+  ADD COLUMN file_mod_time TIMESTAMP(3) NULL;
+CREATE INDEX idx_tracks_library ON tracks(library);
@@ -0,0 +1,23 @@
+-- Purpose: Fallback ("autopilot") playlists for Postgres.
+
+ALTER TABLE queue_entries ADD COLUMN IF NOT EXISTS is_fallback BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE queue_entries ADD COLUMN IF NOT EXISTS fallback_playlist_id UUID NULL;
+CREATE INDEX IF NOT EXISTS idx_queue_entries_fallback_playlist_id ON queue_entries(fallback_playlist_id);
+
+ALTER TABLE playlists
+  ADD COLUMN IF NOT EXISTS name VARCHAR(256) NULL,
+  ADD COLUMN IF NOT EXISTS mode VARCHAR(16) NOT NULL DEFAULT 'random',
+  ADD COLUMN IF NOT EXISTS enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  ADD COLUMN IF NOT EXISTS next_index INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS playlist_items (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  playlist_id UUID NOT NULL REFERENCES playlists(id) ON DELETE CASCADE,
+  position INTEGER NOT NULL,
+  title VARCHAR(512) NOT NULL,
+  source_url TEXT NOT NULL,
+  duration_sec INTEGER NOT NULL DEFAULT 0,
+  track_id UUID NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_playlist_items_playlist ON playlist_items(playlist_id);
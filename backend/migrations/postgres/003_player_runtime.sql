@@ -0,0 +1,14 @@
+-- Purpose: Saved playback state for Postgres (single row, id = 1).
+
+CREATE TABLE IF NOT EXISTS player_runtimes (
+  id INTEGER PRIMARY KEY,
+  queue_entry_id VARCHAR(36) NULL,
+  is_playing BOOLEAN NOT NULL DEFAULT FALSE,
+  is_paused BOOLEAN NOT NULL DEFAULT TRUE,
+  volume DOUBLE PRECISION NOT NULL DEFAULT 0.8,
+  position_sec INTEGER NOT NULL DEFAULT 0,
+  shuffle BOOLEAN NOT NULL DEFAULT FALSE,
+  shuffle_seed BIGINT NOT NULL DEFAULT 0,
+  repeat VARCHAR(8) NOT NULL DEFAULT 'off',
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
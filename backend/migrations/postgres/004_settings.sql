@@ -0,0 +1,7 @@
+-- Purpose: Owner-editable JSON settings for Postgres.
+
+CREATE TABLE IF NOT EXISTS settings (
+  key VARCHAR(64) PRIMARY KEY,
+  value_json JSONB NOT NULL DEFAULT '{}'::jsonb,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,13 @@
+-- Purpose: Duplicate and recently-played detection for Postgres.
+
+ALTER TABLE tracks ADD COLUMN IF NOT EXISTS canonical_id VARCHAR(256) NULL;
+CREATE INDEX IF NOT EXISTS idx_tracks_canonical_id ON tracks(canonical_id);
+
+ALTER TABLE queue_entries
+  ADD COLUMN IF NOT EXISTS request_count INTEGER NOT NULL DEFAULT 1,
+  ADD COLUMN IF NOT EXISTS requesters JSONB NULL,
+  ADD COLUMN IF NOT EXISTS played_at TIMESTAMPTZ NULL;
+CREATE INDEX IF NOT EXISTS idx_queue_played_at ON queue_entries(played_at);
+
+-- start of the repeat-all run: only entries played since then are replayed
+ALTER TABLE player_runtimes ADD COLUMN IF NOT EXISTS repeat_since TIMESTAMPTZ NULL;
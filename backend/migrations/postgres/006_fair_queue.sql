@@ -0,0 +1,3 @@
+-- Purpose: Fair queue mode flag for Postgres.
+
+ALTER TABLE player_runtimes ADD COLUMN IF NOT EXISTS fair_queue BOOLEAN NOT NULL DEFAULT FALSE;
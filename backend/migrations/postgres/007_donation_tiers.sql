@@ -0,0 +1,5 @@
+-- Purpose: Donation amount and tier priority on queue entries for Postgres.
+
+ALTER TABLE queue_entries
+  ADD COLUMN IF NOT EXISTS donation_amount BIGINT NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS donation_priority INTEGER NOT NULL DEFAULT 0;
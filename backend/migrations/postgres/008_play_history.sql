@@ -0,0 +1,19 @@
+-- Purpose: Play history for Postgres (one row per play of a queue entry).
+
+CREATE TABLE IF NOT EXISTS play_histories (
+  id UUID PRIMARY KEY,
+  queue_entry_id UUID NULL,
+  track_id UUID NULL,
+  title VARCHAR(512) NOT NULL,
+  source_url VARCHAR(2048) NOT NULL,
+  added_by_nick VARCHAR(128) NULL,
+  duration_sec INTEGER NOT NULL DEFAULT 0,
+  started_at TIMESTAMPTZ NOT NULL,
+  ended_at TIMESTAMPTZ NULL,
+  listened_sec INTEGER NOT NULL DEFAULT 0,
+  skipped BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS idx_play_histories_started_at ON play_histories(started_at);
+CREATE INDEX IF NOT EXISTS idx_play_histories_queue_entry_id ON play_histories(queue_entry_id);
+CREATE INDEX IF NOT EXISTS idx_play_histories_track_id ON play_histories(track_id);
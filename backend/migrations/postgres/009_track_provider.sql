@@ -0,0 +1,4 @@
+-- Purpose: Audio source provider on tracks for Postgres.
+
+ALTER TABLE tracks
+  ADD COLUMN IF NOT EXISTS provider VARCHAR(32) NOT NULL DEFAULT 'youtube';
@@ -0,0 +1,9 @@
+-- Purpose: Local music library columns on tracks for Postgres.
+
+ALTER TABLE tracks
+  ADD COLUMN IF NOT EXISTS artist VARCHAR(512) NULL,
+  ADD COLUMN IF NOT EXISTS album VARCHAR(512) NULL,
+  ADD COLUMN IF NOT EXISTS library BOOLEAN NOT NULL DEFAULT FALSE,
+  ADD COLUMN IF NOT EXISTS file_size BIGINT NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS file_mod_time TIMESTAMPTZ NULL;
+CREATE INDEX IF NOT EXISTS idx_tracks_library ON tracks(library);
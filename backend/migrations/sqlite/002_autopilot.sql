@@ -0,0 +1,22 @@
+-- Purpose: Fallback ("autopilot") playlists for SQLite.
+
+ALTER TABLE queue_entries ADD COLUMN is_fallback INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE queue_entries ADD COLUMN fallback_playlist_id TEXT NULL;
+CREATE INDEX IF NOT EXISTS idx_queue_entries_fallback_playlist_id ON queue_entries(fallback_playlist_id);
+
+ALTER TABLE playlists ADD COLUMN name TEXT NULL;
+ALTER TABLE playlists ADD COLUMN mode TEXT NOT NULL DEFAULT 'random';
+ALTER TABLE playlists ADD COLUMN enabled INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE playlists ADD COLUMN next_index INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS playlist_items (
+  id TEXT PRIMARY KEY,
+  playlist_id TEXT NOT NULL REFERENCES playlists(id) ON DELETE CASCADE,
+  position INTEGER NOT NULL,
+  title TEXT NOT NULL,
+  source_url TEXT NOT NULL,
+  duration_sec INTEGER NOT NULL DEFAULT 0,
+  track_id TEXT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_playlist_items_playlist ON playlist_items(playlist_id);
@@ -0,0 +1,14 @@
+-- Purpose: Saved playback state for SQLite (single row, id = 1).
+
+CREATE TABLE IF NOT EXISTS player_runtimes (
+  id INTEGER PRIMARY KEY,
+  queue_entry_id TEXT NULL,
+  is_playing INTEGER NOT NULL DEFAULT 0,
+  is_paused INTEGER NOT NULL DEFAULT 1,
+  volume REAL NOT NULL DEFAULT 0.8,
+  position_sec INTEGER NOT NULL DEFAULT 0,
+  shuffle INTEGER NOT NULL DEFAULT 0,
+  shuffle_seed INTEGER NOT NULL DEFAULT 0,
+  repeat TEXT NOT NULL DEFAULT 'off',
+  updated_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
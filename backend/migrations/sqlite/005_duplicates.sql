@@ -0,0 +1,12 @@
+-- Purpose: Duplicate and recently-played detection for SQLite.
+
+ALTER TABLE tracks ADD COLUMN canonical_id TEXT NULL;
+CREATE INDEX IF NOT EXISTS idx_tracks_canonical_id ON tracks(canonical_id);
+
+ALTER TABLE queue_entries ADD COLUMN request_count INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE queue_entries ADD COLUMN requesters TEXT NULL;
+ALTER TABLE queue_entries ADD COLUMN played_at TEXT NULL;
+CREATE INDEX IF NOT EXISTS idx_queue_played_at ON queue_entries(played_at);
+
+-- start of the repeat-all run: only entries played since then are replayed
+ALTER TABLE player_runtimes ADD COLUMN repeat_since TEXT NULL;
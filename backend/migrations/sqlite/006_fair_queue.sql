@@ -0,0 +1,3 @@
+-- Purpose: Fair queue mode flag for SQLite.
+
+ALTER TABLE player_runtimes ADD COLUMN fair_queue INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+-- Purpose: Donation amount and tier priority on queue entries for SQLite.
+
+ALTER TABLE queue_entries ADD COLUMN donation_amount INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE queue_entries ADD COLUMN donation_priority INTEGER NOT NULL DEFAULT 0;
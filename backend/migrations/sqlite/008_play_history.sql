@@ -0,0 +1,19 @@
+-- Purpose: Play history for SQLite (one row per play of a queue entry).
+
+CREATE TABLE IF NOT EXISTS play_histories (
+  id TEXT PRIMARY KEY,
+  queue_entry_id TEXT NULL,
+  track_id TEXT NULL,
+  title TEXT NOT NULL,
+  source_url TEXT NOT NULL,
+  added_by_nick TEXT NULL,
+  duration_sec INTEGER NOT NULL DEFAULT 0,
+  started_at TEXT NOT NULL,
+  ended_at TEXT NULL,
+  listened_sec INTEGER NOT NULL DEFAULT 0,
+  skipped INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_play_histories_started_at ON play_histories(started_at);
+CREATE INDEX IF NOT EXISTS idx_play_histories_queue_entry_id ON play_histories(queue_entry_id);
+CREATE INDEX IF NOT EXISTS idx_play_histories_track_id ON play_histories(track_id);
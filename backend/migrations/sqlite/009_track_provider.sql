@@ -0,0 +1,3 @@
+-- Purpose: Audio source provider on tracks for SQLite.
+
+ALTER TABLE tracks ADD COLUMN provider VARCHAR(32) NOT NULL DEFAULT 'youtube';
@@ -0,0 +1,8 @@
+-- Purpose: Local music library columns on tracks for SQLite.
+
+ALTER TABLE tracks ADD COLUMN artist TEXT NULL;
+ALTER TABLE tracks ADD COLUMN album TEXT NULL;
+ALTER TABLE tracks ADD COLUMN library INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tracks ADD COLUMN file_size INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tracks ADD COLUMN file_mod_time TEXT NULL;
+CREATE INDEX IF NOT EXISTS idx_tracks_library ON tracks(library);
@@ -0,0 +1,255 @@
+// Purpose: Fallback ("autopilot") playlists.
+// - Owners register playlists; their entries are resolved once and stored as PlaylistItem rows.
+// - Whenever nothing user-requested is upcoming, one fallback track is queued as "next"
+//   (so prefetch and crossfade work as for any other track).
+// - User requests are inserted before queued fallback entries, so they always play first.
+// - Random mode avoids tracks played recently; sequential mode walks the playlist in order.
+
+package player
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"radiokpowka/backend/db"
+)
+
+const (
+	AutopilotRandom     = "random"
+	AutopilotSequential = "sequential"
+
+	autopilotNick = "autopilot"
+	// at most this many recently played tracks are avoided in random mode
+	autopilotRecentMax = 50
+)
+
+var ErrInvalidAutopilotMode = errors.New("mode must be random or sequential")
+
+type PlaylistDTO struct {
+	ID        string `json:"id"`
+	URL       string `json:"url"`
+	Name      string `json:"name"`
+	Mode      string `json:"mode"`
+	Enabled   bool   `json:"enabled"`
+	Items     int    `json:"items"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// AddFallbackPlaylist resolves a playlist URL and registers it as an autopilot source.
+func (c *Controller) AddFallbackPlaylist(url, name, mode string) (PlaylistDTO, error) {
+	if mode == "" {
+		mode = AutopilotRandom
+	}
+	if mode != AutopilotRandom && mode != AutopilotSequential {
+		return PlaylistDTO{}, ErrInvalidAutopilotMode
+	}
+
+	metas, err := c.sources.Resolve(context.Background(), url, true)
+	if err != nil {
+		return PlaylistDTO{}, err
+	}
+	if len(metas) == 0 {
+		return PlaylistDTO{}, errors.New("no tracks resolved")
+	}
+	if name == "" {
+		name = url
+	}
+
+	p := db.Playlist{
+		ID:        uuid.New(),
+		URL:       url,
+		Name:      name,
+		Mode:      mode,
+		Enabled:   true,
+		CreatedAt: time.Now().UTC(),
+	}
+	items := make([]db.PlaylistItem, 0, len(metas))
+	for i, m := range metas {
+		items = append(items, db.PlaylistItem{
+			ID:          uuid.New(),
+			PlaylistID:  p.ID,
+			Position:    i,
+			Title:       m.Title,
+			SourceURL:   m.WebpageURL,
+			DurationSec: m.DurationSec,
+		})
+	}
+	if err := createPlaylist(c.db, p, items); err != nil {
+		return PlaylistDTO{}, err
+	}
+	log.Printf("автопилот: добавлен плейлист %q (%d треков, %s)", name, len(items), mode)
+
+	c.refillAutopilot()
+	return playlistDTO(p, len(items)), nil
+}
+
+func (c *Controller) ListFallbackPlaylists() ([]PlaylistDTO, error) {
+	return listPlaylists(c.db)
+}
+
+// UpdateFallbackPlaylist changes mode and/or enabled flag (nil = keep). Disabling a playlist
+// also drops its upcoming autopilot entries, as deleting it does.
+func (c *Controller) UpdateFallbackPlaylist(id string, mode *string, enabled *bool) error {
+	pid, err := uuid.Parse(id)
+	if err != nil {
+		return ErrPlaylistNotFound
+	}
+	if mode != nil && *mode != AutopilotRandom && *mode != AutopilotSequential {
+		return ErrInvalidAutopilotMode
+	}
+	if enabled != nil && !*enabled {
+		return c.editQueue(func() error {
+			return updatePlaylist(c.db, pid, mode, enabled)
+		})
+	}
+	if err := updatePlaylist(c.db, pid, mode, enabled); err != nil {
+		return err
+	}
+	c.refillAutopilot()
+	return nil
+}
+
+// DeleteFallbackPlaylist removes a playlist together with its upcoming autopilot entries;
+// the queue is then refilled from the remaining playlists.
+func (c *Controller) DeleteFallbackPlaylist(id string) error {
+	pid, err := uuid.Parse(id)
+	if err != nil {
+		return ErrPlaylistNotFound
+	}
+	return c.editQueue(func() error {
+		return deletePlaylist(c.db, pid)
+	})
+}
+
+// refillAutopilot queues a fallback track if needed and starts the station when it was idle.
+func (c *Controller) refillAutopilot() {
+	c.mu.Lock()
+	queued := c.ensureAutopilotLocked()
+	if queued {
+		_ = c.refreshCurrentFromDBLocked()
+		c.schedulePrefetchLocked()
+		c.broadcastQueueLocked()
+	}
+	c.mu.Unlock()
+	if queued {
+		c.autoStartIfStopped()
+		c.broadcastState()
+	}
+}
+
+// ensureAutopilotLocked queues one fallback track when no entry is upcoming.
+// Reports whether a track was queued.
+func (c *Controller) ensureAutopilotLocked() bool {
+	var upcoming int64
+	if err := c.db.Model(&db.QueueEntry{}).Where("status = ?", "next").Count(&upcoming).Error; err != nil || upcoming > 0 {
+		return false
+	}
+	if c.rt.repeat == RepeatAll && c.hasRepeatRunLocked() {
+		// the played entries come round again when the queue ends
+		return false
+	}
+
+	tx := c.db.Begin()
+	defer func() { _ = tx.Rollback() }()
+
+	item, err := pickFallbackItem(tx)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("автопилот: ошибка выбора трека: %v", err)
+		}
+		return false
+	}
+	t, err := fallbackTrack(tx, item, c.sources.Provider(item.SourceURL))
+	if err != nil {
+		return false
+	}
+	pos, err := maxPosition(tx)
+	if err != nil {
+		return false
+	}
+	if _, err := insertFallbackEntry(tx, t.ID, item.PlaylistID, pos+1); err != nil {
+		return false
+	}
+	if err := tx.Commit().Error; err != nil {
+		return false
+	}
+
+	c.cache.Prefetch(t.SourceURL)
+	if trackLoudness(t.MetadataJSON) == nil {
+		c.queueLoudness(t.ID, t.SourceURL)
+	}
+	log.Printf("автопилот: в очередь %q", t.Title)
+	return true
+}
+
+// hasRepeatRunLocked reports whether repeat-all has something to replay: a played entry of the
+// run, or a current entry that will become one.
+func (c *Controller) hasRepeatRunLocked() bool {
+	var n int64
+	if err := repeatRun(c.db, c.rt.repeatSince).Count(&n).Error; err != nil || n > 0 {
+		return true
+	}
+	err := c.db.Model(&db.QueueEntry{}).Where("status = ? AND is_fallback = ?", "current", false).Count(&n).Error
+	return err != nil || n > 0
+}
+
+// pickFallbackItem chooses the next fallback track from a random enabled playlist.
+func pickFallbackItem(tx *gorm.DB) (db.PlaylistItem, error) {
+	var playlists []db.Playlist
+	if err := tx.Where("enabled = ?", true).Find(&playlists).Error; err != nil {
+		return db.PlaylistItem{}, err
+	}
+	for _, i := range rand.Perm(len(playlists)) {
+		p := playlists[i]
+		var items []db.PlaylistItem
+		if err := tx.Where("playlist_id = ?", p.ID).Order("position asc").Find(&items).Error; err != nil {
+			return db.PlaylistItem{}, err
+		}
+		if len(items) == 0 {
+			continue
+		}
+
+		if p.Mode == AutopilotSequential {
+			item := items[p.NextIndex%len(items)]
+			next := (p.NextIndex + 1) % len(items)
+			if err := tx.Model(&db.Playlist{}).Where("id = ?", p.ID).Update("next_index", next).Error; err != nil {
+				return db.PlaylistItem{}, err
+			}
+			return item, nil
+		}
+
+		recent, err := recentSourceURLs(tx, min(autopilotRecentMax, len(items)/2))
+		if err != nil {
+			return db.PlaylistItem{}, err
+		}
+		candidates := make([]db.PlaylistItem, 0, len(items))
+		for _, it := range items {
+			if !recent[it.SourceURL] {
+				candidates = append(candidates, it)
+			}
+		}
+		if len(candidates) == 0 {
+			candidates = items
+		}
+		return candidates[rand.Intn(len(candidates))], nil
+	}
+	return db.PlaylistItem{}, gorm.ErrRecordNotFound
+}
+
+func playlistDTO(p db.Playlist, items int) PlaylistDTO {
+	return PlaylistDTO{
+		ID:        p.ID.String(),
+		URL:       p.URL,
+		Name:      p.Name,
+		Mode:      p.Mode,
+		Enabled:   p.Enabled,
+		Items:     items,
+		CreatedAt: p.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
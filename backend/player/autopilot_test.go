@@ -0,0 +1,86 @@
+package player
+
+import (
+	"slices"
+	"testing"
+
+	"radiokpowka/backend/db"
+)
+
+func TestDeleteFallbackPlaylistDropsItsEntries(t *testing.T) {
+	c := newTestController(t, nil)
+	request(t, c, "alice", "a")
+	gone, err := c.AddFallbackPlaylist("http://tracks.test/list/x1,x2", "gone", AutopilotSequential)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := queueTitles(t, c); !slices.Equal(got, []string{"x1"}) {
+		t.Fatalf("upcoming = %v, want [x1]", got)
+	}
+	if _, err := c.AddFallbackPlaylist("http://tracks.test/list/y1,y2", "kept", AutopilotSequential); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.DeleteFallbackPlaylist(gone.ID); err != nil {
+		t.Fatal(err)
+	}
+	// x1 is dropped and the queue is refilled from the remaining playlist
+	if got := queueTitles(t, c); !slices.Equal(got, []string{"y1"}) {
+		t.Fatalf("upcoming after delete = %v, want [y1]", got)
+	}
+	if got := currentTitle(c); got != "a" {
+		t.Fatalf("current = %q, want a", got)
+	}
+	if err := c.DeleteFallbackPlaylist(gone.ID); err != ErrPlaylistNotFound {
+		t.Fatalf("second delete: err = %v, want ErrPlaylistNotFound", err)
+	}
+}
+
+func TestDisableFallbackPlaylistDropsItsEntries(t *testing.T) {
+	c := newTestController(t, nil)
+	request(t, c, "alice", "a")
+	off, err := c.AddFallbackPlaylist("http://tracks.test/list/x1,x2", "off", AutopilotSequential)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.AddFallbackPlaylist("http://tracks.test/list/y1,y2", "on", AutopilotSequential); err != nil {
+		t.Fatal(err)
+	}
+
+	disabled := false
+	if err := c.UpdateFallbackPlaylist(off.ID, nil, &disabled); err != nil {
+		t.Fatal(err)
+	}
+	// x1 must not play after its playlist was switched off
+	if got := queueTitles(t, c); !slices.Equal(got, []string{"y1"}) {
+		t.Fatalf("upcoming after disable = %v, want [y1]", got)
+	}
+	if got := currentTitle(c); got != "a" {
+		t.Fatalf("current = %q, want a", got)
+	}
+}
+
+func TestAutopilotReusesTrackRows(t *testing.T) {
+	c := newTestController(t, nil)
+	if _, err := c.AddFallbackPlaylist("http://tracks.test/list/x1,x2", "fb", AutopilotSequential); err != nil {
+		t.Fatal(err)
+	}
+	var played []string
+	for range 6 {
+		played = append(played, currentTitle(c))
+		if err := c.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !slices.Equal(played, []string{"x1", "x2", "x1", "x2", "x1", "x2"}) {
+		t.Fatalf("played %v", played)
+	}
+	// every replay of an item queues the same track row
+	var tracks int64
+	if err := c.db.Model(&db.Track{}).Count(&tracks).Error; err != nil {
+		t.Fatal(err)
+	}
+	if tracks != 2 {
+		t.Fatalf("tracks = %d, want 2", tracks)
+	}
+}
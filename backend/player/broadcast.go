@@ -0,0 +1,483 @@
+// Purpose: Shared station pipeline for /stream and HLS.
+// - Runs while there are /stream listeners or recent HLS requests (Hold), stopped otherwise.
+// - A pump produces raw PCM in real time (the current track's decoder, or silence) and feeds
+//   every output encoder (see encoder.go), so all outputs follow the same timeline.
+// - Track changes, pause and play only swap the PCM source, so listeners never reconnect.
+// - A crossfade keeps the previous track's decoder running and mixes it out under the new one.
+// - Each mount's output (see mount.go) is fanned out to its listeners through per-client ring buffers.
+// - A listener whose buffer overflows is dropped instead of slowing everyone down.
+
+package player
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"radiokpowka/backend/cache"
+	"radiokpowka/backend/source"
+)
+
+const (
+	// ~10 seconds of 192k MP3 per listener.
+	subscriberBufferBytes = 256 << 10
+	// Recent output handed to a new listener so playback starts without waiting.
+	burstBytes = 64 << 10
+)
+
+var ErrSubscriberDropped = errors.New("listener dropped: buffer overflow")
+
+type Broadcaster struct {
+	ctrl    ControllerStreamer
+	sources *source.Registry
+	ffmpeg  string
+	cache   *cache.Cache
+	hls     HLSConfig
+
+	mounts []*mount // fixed at construction
+
+	mu        sync.Mutex
+	ctx       context.Context    // pipeline lifetime; nil when stopped
+	stop      context.CancelFunc // stops the pipeline
+	done      chan struct{}      // closed once the last started pipeline's encoders have exited
+	holdUntil time.Time
+	holdTimer *time.Timer
+	snap      StreamSnapshot // last applied controller state
+	dec       *trackDecoder
+	fading    *trackDecoder // previous track during a crossfade
+}
+
+func NewBroadcaster(ctrl ControllerStreamer, sources *source.Registry, ffmpegPath string, ac *cache.Cache, hls HLSConfig, mounts []Mount) *Broadcaster {
+	b := &Broadcaster{
+		ctrl:    ctrl,
+		sources: sources,
+		ffmpeg:  ffmpegPath,
+		cache:   ac,
+		hls:     hls,
+	}
+	if len(mounts) == 0 {
+		mounts = []Mount{{Codec: CodecMP3, Bitrate: 192}}
+	}
+	for _, m := range mounts {
+		b.mounts = append(b.mounts, newMount(b, m))
+	}
+	return b
+}
+
+// Subscribe attaches a listener to a mount and starts the pipeline if it is not running yet.
+func (b *Broadcaster) Subscribe(m Mount) (*Subscriber, error) {
+	mt := b.mountByKey(m.Key())
+	if mt == nil {
+		return nil, fmt.Errorf("unknown mount %s", m.Key())
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := mt.subscribeLocked()
+	b.startLocked()
+	return s, nil
+}
+
+// Unsubscribe detaches a listener and stops the encoder when nobody is left.
+func (b *Broadcaster) Unsubscribe(s *Subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.removeLocked(s, nil)
+}
+
+func (b *Broadcaster) removeLocked(s *Subscriber, reason error) {
+	if _, ok := s.m.subs[s]; !ok {
+		return
+	}
+	delete(s.m.subs, s)
+	s.rb.closeWithError(reason)
+	b.stopIfIdleLocked()
+}
+
+// Hold keeps the pipeline running for d even without /stream listeners (used by HLS).
+func (b *Broadcaster) Hold(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(b.holdUntil) {
+		b.holdUntil = until
+	}
+	if b.holdTimer == nil {
+		b.holdTimer = time.AfterFunc(d, b.releaseHold)
+	} else {
+		b.holdTimer.Reset(d)
+	}
+	b.startLocked()
+}
+
+func (b *Broadcaster) releaseHold() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stopIfIdleLocked()
+}
+
+func (b *Broadcaster) startLocked() {
+	if b.stop != nil {
+		return
+	}
+	if b.hls.Enabled {
+		// the HLS request that started us must wait for this run's playlist, not get a stopped one's
+		_ = os.Remove(filepath.Join(b.hls.Dir, HLSPlaylistName))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	prev, done := b.done, make(chan struct{})
+	b.ctx = ctx
+	b.stop = cancel
+	b.done = done
+	go b.run(ctx, prev, done)
+	log.Printf("стрим: энкодер запущен")
+}
+
+func (b *Broadcaster) stopIfIdleLocked() {
+	if b.stop == nil || b.listenersLocked() > 0 || time.Now().Before(b.holdUntil) {
+		return
+	}
+	b.stop()
+	b.stop = nil
+	b.ctx = nil
+	b.dec = nil
+	b.fading = nil
+	for _, m := range b.mounts {
+		m.resetLocked()
+	}
+	log.Printf("стрим: слушателей нет, энкодер остановлен")
+}
+
+// Listeners returns the number of attached listeners across all mounts.
+func (b *Broadcaster) Listeners() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.listenersLocked()
+}
+
+func (b *Broadcaster) listenersLocked() int {
+	n := 0
+	for _, m := range b.mounts {
+		n += len(m.subs)
+	}
+	return n
+}
+
+// Reload makes the stream follow a new controller state (track, pause, seek, volume).
+func (b *Broadcaster) Reload(s StreamSnapshot) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.applyLocked(s)
+}
+
+func (b *Broadcaster) applyLocked(s StreamSnapshot) {
+	if s.Seq < b.snap.Seq {
+		return
+	}
+	b.snap = s
+	if b.ctx == nil {
+		return
+	}
+
+	if s.Paused {
+		b.stopDecoderLocked()
+		return
+	}
+	if b.dec != nil && b.dec.queueID == s.QueueID && b.dec.seekSeq == s.SeekSeq {
+		// same track keeps playing (volume is applied by the pump)
+		return
+	}
+	if s.Fade > 0 && b.dec != nil && !b.dec.finished {
+		frames := fadeFrames(s.Fade)
+		b.stopFadingLocked()
+		b.fading = b.dec
+		b.fading.env = fadeOut(b.fading.env, frames)
+		b.dec = startDecoder(b.ctx, b, s)
+		b.dec.env = &fade{frames: frames}
+		return
+	}
+	b.stopDecoderLocked()
+	b.dec = startDecoder(b.ctx, b, s)
+}
+
+func (b *Broadcaster) stopDecoderLocked() {
+	b.stopFadingLocked()
+	if b.dec != nil {
+		b.dec.stop()
+		b.dec = nil
+	}
+}
+
+func (b *Broadcaster) stopFadingLocked() {
+	if b.fading != nil {
+		b.fading.stop()
+		b.fading = nil
+	}
+}
+
+// run drives one pipeline until ctx is cancelled. It starts once the previous pipeline's
+// encoders are gone (prev), so their output cannot mix with this run's, and closes done
+// when its own encoders have exited.
+func (b *Broadcaster) run(ctx context.Context, prev <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	if prev != nil {
+		<-prev
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	snap := b.ctrl.StreamSnapshot()
+	b.mu.Lock()
+	if ctx.Err() == nil {
+		b.applyLocked(snap)
+	}
+	b.mu.Unlock()
+
+	if b.hls.Enabled {
+		// once per pipeline start: an ffmpeg restart keeps the live segments clients are fetching
+		if err := resetHLSDir(b.hls.Dir); err != nil {
+			log.Printf("стрим: не удалось подготовить HLS каталог: %v", err)
+		}
+	}
+	encoders := b.newEncoders()
+	var wg sync.WaitGroup
+	for _, e := range encoders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.run(ctx)
+		}()
+	}
+	b.pump(ctx, encoders)
+	wg.Wait()
+}
+
+func (b *Broadcaster) newEncoders() []*encoder {
+	ffmpegPath := b.ffmpeg
+	encoders := make([]*encoder, 0, len(b.mounts)+1)
+	for _, m := range b.mounts {
+		encoders = append(encoders, newEncoder(m.Key(), func(ctx context.Context) *exec.Cmd {
+			return NewEncoderFFMPEG(ctx, ffmpegPath, m.Mount, &countWriter{w: m})
+		}))
+	}
+	if b.hls.Enabled {
+		encoders = append(encoders, newEncoder("hls", func(ctx context.Context) *exec.Cmd {
+			return NewHLSFFMPEG(ctx, ffmpegPath, b.hls)
+		}))
+	}
+	return encoders
+}
+
+// pump hands one PCM frame per frame duration to every encoder, keeping the
+// whole station on a wall-clock timeline.
+func (b *Broadcaster) pump(ctx context.Context, encoders []*encoder) {
+	silence := make([]byte, pcmFrameBytes)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+
+	next := time.Now()
+	for ctx.Err() == nil {
+		frame := b.nextFrame()
+		if frame == nil {
+			frame = silence
+		}
+		for _, e := range encoders {
+			e.feed(frame)
+		}
+
+		next = next.Add(pcmFrameDuration)
+		wait := time.Until(next)
+		if wait < -time.Second {
+			// fell far behind (machine stalled): resync instead of bursting
+			next = time.Now()
+			continue
+		}
+		if wait <= 0 {
+			continue
+		}
+		timer.Reset(wait)
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// nextFrame returns the next frame of the current track (mixed with the fading
+// previous one during a crossfade), or nil for silence.
+func (b *Broadcaster) nextFrame() []byte {
+	b.mu.Lock()
+	cur, prev := b.dec, b.fading
+	volume := b.snap.Volume
+	b.mu.Unlock()
+
+	frame, g := b.pull(cur)
+	prevFrame, pg := b.pull(prev)
+
+	if prev != nil {
+		b.mu.Lock()
+		if b.fading == prev && (prev.finished || prev.env.done()) {
+			b.stopFadingLocked()
+		}
+		b.mu.Unlock()
+	}
+
+	switch {
+	case frame == nil && prevFrame == nil:
+		return nil
+	case prevFrame == nil:
+		applyGain(frame, volume*g)
+		return frame
+	case frame == nil:
+		applyGain(prevFrame, volume*pg)
+		return prevFrame
+	default:
+		mixFrames(frame, prevFrame, volume*g, volume*pg)
+		return frame
+	}
+}
+
+// pull takes one frame from a decoder without waiting, along with its envelope gain.
+func (b *Broadcaster) pull(d *trackDecoder) ([]byte, float64) {
+	if d == nil {
+		return nil, 0
+	}
+	b.mu.Lock()
+	finished := d.finished
+	b.mu.Unlock()
+	if finished {
+		return nil, 0
+	}
+
+	select {
+	case f, ok := <-d.frames:
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if !ok {
+			d.finished = true
+			return nil, 0
+		}
+		return f, d.env.gain()
+	default:
+		// decoder is still starting or the network is slow
+		return nil, 0
+	}
+}
+
+// mixFrames adds src into dst, scaling each by its gain.
+func mixFrames(dst, src []byte, gDst, gSrc float64) {
+	for i := 0; i+1 < len(dst) && i+1 < len(src); i += 2 {
+		v := float64(int16(binary.LittleEndian.Uint16(dst[i:])))*gDst +
+			float64(int16(binary.LittleEndian.Uint16(src[i:])))*gSrc
+		v = math.Max(math.MinInt16, math.Min(math.MaxInt16, v))
+		binary.LittleEndian.PutUint16(dst[i:], uint16(int16(v)))
+	}
+}
+
+func applyGain(frame []byte, gain float64) {
+	if gain == 1 {
+		return
+	}
+	for i := 0; i+1 < len(frame); i += 2 {
+		v := float64(int16(binary.LittleEndian.Uint16(frame[i:]))) * gain
+		v = math.Max(math.MinInt16, math.Min(math.MaxInt16, v))
+		binary.LittleEndian.PutUint16(frame[i:], uint16(int16(v)))
+	}
+}
+
+// Subscriber is one listener's view of a mount.
+type Subscriber struct {
+	rb *ringBuffer
+	m  *mount
+}
+
+func (s *Subscriber) Read(p []byte) (int, error) {
+	return s.rb.Read(p)
+}
+
+// ringBuffer is a fixed-size byte ring. Writes never block: they fail when the
+// reader has fallen too far behind. Reads block until data arrives or it is closed.
+type ringBuffer struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	buf    []byte
+	r      int
+	n      int
+	closed bool
+	err    error
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	rb := &ringBuffer{buf: make([]byte, size)}
+	rb.cond = sync.NewCond(&rb.mu)
+	return rb
+}
+
+func (rb *ringBuffer) write(p []byte) bool {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	if rb.closed {
+		return true
+	}
+	if len(p) > len(rb.buf)-rb.n {
+		return false
+	}
+	w := (rb.r + rb.n) % len(rb.buf)
+	c := copy(rb.buf[w:], p)
+	if c < len(p) {
+		copy(rb.buf, p[c:])
+	}
+	rb.n += len(p)
+	rb.cond.Signal()
+	return true
+}
+
+func (rb *ringBuffer) Read(p []byte) (int, error) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	for rb.n == 0 && !rb.closed {
+		rb.cond.Wait()
+	}
+	if rb.n == 0 {
+		if rb.err != nil {
+			return 0, rb.err
+		}
+		return 0, io.EOF
+	}
+
+	end := rb.r + rb.n
+	if end > len(rb.buf) {
+		end = len(rb.buf)
+	}
+	c := copy(p, rb.buf[rb.r:end])
+	rb.r = (rb.r + c) % len(rb.buf)
+	rb.n -= c
+	return c, nil
+}
+
+func (rb *ringBuffer) closeWithError(err error) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	rb.closed = true
+	rb.err = err
+	if err != nil {
+		// a dropped listener must not drain a stale backlog
+		rb.n = 0
+	}
+	rb.cond.Broadcast()
+}
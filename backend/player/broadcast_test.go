@@ -0,0 +1,105 @@
+package player
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// subscribe attaches a listener without starting the encoder pipeline.
+func subscribe(b *Broadcaster, m *mount) *Subscriber {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return m.subscribeLocked()
+}
+
+// drain reads everything buffered for s.
+func drain(t *testing.T, s *Subscriber, n int) []byte {
+	t.Helper()
+	out := make([]byte, 0, n)
+	buf := make([]byte, 4096)
+	for len(out) < n {
+		k, err := s.Read(buf)
+		if err != nil {
+			t.Fatalf("read after %d bytes: %v", len(out), err)
+		}
+		out = append(out, buf[:k]...)
+	}
+	return out
+}
+
+func TestBroadcastFanOut(t *testing.T) {
+	b := NewBroadcaster(nil, nil, "ffmpeg", nil, HLSConfig{}, nil)
+	m := b.mounts[0]
+	a := subscribe(b, m)
+	c := subscribe(b, m)
+
+	_, _ = m.Write([]byte("one "))
+	_, _ = m.Write([]byte("two "))
+	for _, s := range []*Subscriber{a, c} {
+		if got := drain(t, s, 8); string(got) != "one two " {
+			t.Fatalf("listener got %q", got)
+		}
+	}
+
+	// a late listener starts with the recent burst instead of silence
+	late := subscribe(b, m)
+	if got := drain(t, late, 8); string(got) != "one two " {
+		t.Fatalf("late listener got %q", got)
+	}
+	if b.Listeners() != 3 {
+		t.Fatalf("listeners = %d, want 3", b.Listeners())
+	}
+}
+
+func TestBroadcastDropsSlowListener(t *testing.T) {
+	b := NewBroadcaster(nil, nil, "ffmpeg", nil, HLSConfig{}, nil)
+	m := b.mounts[0]
+	slow := subscribe(b, m)
+	fast := subscribe(b, m)
+
+	chunk := bytes.Repeat([]byte{1}, 32<<10)
+	for written := 0; written <= subscriberBufferBytes; written += len(chunk) {
+		_, _ = m.Write(chunk)
+		// fast keeps up, slow never reads
+		drain(t, fast, len(chunk))
+	}
+	if _, err := slow.Read(make([]byte, 1)); !errors.Is(err, ErrSubscriberDropped) {
+		t.Fatalf("slow listener: err = %v, want ErrSubscriberDropped", err)
+	}
+	if b.Listeners() != 1 {
+		t.Fatalf("listeners = %d, want 1", b.Listeners())
+	}
+
+	b.Unsubscribe(fast)
+	if _, err := fast.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("unsubscribed listener: err = %v, want EOF", err)
+	}
+}
+
+func TestRingBufferWraps(t *testing.T) {
+	rb := newRingBuffer(8)
+	buf := make([]byte, 8)
+	if !rb.write([]byte("abcdef")) {
+		t.Fatal("write failed")
+	}
+	n, _ := rb.Read(buf[:4])
+	if string(buf[:n]) != "abcd" {
+		t.Fatalf("read %q", buf[:n])
+	}
+	if !rb.write([]byte("ghijkl")) {
+		t.Fatal("wrapping write failed")
+	}
+	if rb.write([]byte("m")) {
+		t.Fatal("write into a full buffer succeeded")
+	}
+	var got []byte
+	for len(got) < 8 {
+		n, _ := rb.Read(buf)
+		got = append(got, buf[:n]...)
+	}
+	if string(got) != "efghijkl" {
+		t.Fatalf("read %q, want efghijkl", got)
+	}
+}
@@ -1,53 +1,126 @@
 // Purpose: Server-authoritative playback state, queue transitions, WS broadcasts.
-// Note: Audio is streamed per-client via /stream, but server state controls pause/play and position.
+// Note: Audio is encoded once per station (Broadcaster) and shared by all /stream clients;
+// server state controls pause/play and position.
 
 package player
 
 import (
 	"context"
 	"errors"
+	"log"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
+	"radiokpowka/backend/cache"
+	"radiokpowka/backend/db"
+	"radiokpowka/backend/source"
 	"radiokpowka/backend/websocket"
-	"radiokpowka/backend/youtube"
 )
 
 type ControllerDeps struct {
-	DB  *gorm.DB
-	Hub *websocket.Hub
-	YT  *youtube.Client
+	DB         *gorm.DB
+	Hub        *websocket.Hub
+	Sources    *source.Registry
+	FFMPEGPath string
+	Cache      *cache.Cache // optional local audio cache
+	HLS        HLSConfig
+	// Mounts lists stream outputs; the first one is served at /stream.
+	Mounts   []Mount
+	Loudness LoudnessConfig
+	// Crossfade between consecutive tracks (0 = hard cut).
+	Crossfade time.Duration
+	Quota     QuotaConfig
+	// Duplicate and recently-played request handling.
+	Duplicates DuplicateConfig
+	VoteSkip   VoteSkipConfig
+	// Size of the queue window in GET /api/playlist and queue_update events.
+	QueueWindow QueueWindowConfig
 }
 
 type Controller struct {
-	db  *gorm.DB
-	hub *websocket.Hub
-	yt  *youtube.Client
+	db      *gorm.DB
+	hub     *websocket.Hub
+	sources *source.Registry
+	ffmpeg  string
+	cache   *cache.Cache
+	bc      *Broadcaster
+
+	loudness     LoudnessConfig
+	loudnessJobs chan loudnessJob
+	crossfade    time.Duration
+
+	quota       QuotaConfig
+	quotaMu     sync.Mutex
+	lastRequest map[string]time.Time // requester key -> last accepted request
+	duplicates  DuplicateConfig
+
+	rulesMu sync.RWMutex
+	rules   Rules
+
+	tiersMu sync.RWMutex
+	tiers   DonationTiers
+
+	voteSkip VoteSkipConfig
+	votesMu  sync.Mutex
+	votes    skipVotes
+
+	window       QueueWindowConfig
+	queueMu      sync.Mutex // guards the published window (taken after mu)
+	queueVersion uint64
+	lastWindow   QueueWindow
 
 	mu sync.RWMutex
 	rt runtime
+
+	pfMu sync.Mutex
+	pf   *prefetchEntry // upcoming entry being prefetched
 }
 
 func NewController(d ControllerDeps) *Controller {
 	c := &Controller{
-		db:  d.DB,
-		hub: d.Hub,
-		yt:  d.YT,
-	}
+		db:      d.DB,
+		hub:     d.Hub,
+		sources: d.Sources,
+		ffmpeg:  d.FFMPEGPath,
+		cache:   d.Cache,
+
+		loudness:     d.Loudness,
+		loudnessJobs: make(chan loudnessJob, loudnessQueueSize),
+		crossfade:    d.Crossfade,
+		quota:        d.Quota,
+		lastRequest:  map[string]time.Time{},
+		duplicates:   d.Duplicates,
+		voteSkip:     d.VoteSkip,
+		window:       d.QueueWindow,
+	}
+	c.bc = NewBroadcaster(c, d.Sources, d.FFMPEGPath, d.Cache, d.HLS, d.Mounts)
 	// defaults
 	c.rt.volume = 0.8
 	c.rt.isPaused = true
 	c.rt.isPlaying = false
+	c.rt.repeat = RepeatOff
+	c.loadRules()
+	c.loadDonationTiers()
+	c.backfillCanonicalIDs()
 
 	// background auto-advance based on duration (approx)
 	go c.autoAdvanceLoop()
+	// background loudness analysis of added tracks
+	go c.loudnessWorker()
+	c.queueMissingLoudness()
+	c.prefetchUpcoming()
 
-	// initialize current from DB if exists
-	_ = c.refreshCurrentFromDB()
-	c.autoStartIfStopped()
+	// resume the saved state, or initialize current from DB and start playing
+	if !c.restoreRuntime() {
+		_ = c.refreshCurrentFromDB()
+		c.autoStartIfStopped()
+	}
+	c.closeStaleHistory()
+	c.refillAutopilot()
 
 	// broadcast initial state
 	c.broadcastState()
@@ -59,7 +132,10 @@ func NewController(d ControllerDeps) *Controller {
 func (c *Controller) State() PlayerState {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
+	return c.stateLocked()
+}
 
+func (c *Controller) stateLocked() PlayerState {
 	pos := c.positionLocked()
 	st := PlayerState{
 		IsPlaying:   c.rt.isPlaying,
@@ -67,6 +143,12 @@ func (c *Controller) State() PlayerState {
 		Volume:      c.rt.volume,
 		PositionSec: pos,
 		DurationSec: c.rt.durationSec,
+		Shuffle:     c.rt.shuffle,
+		Repeat:      c.rt.repeat,
+		FairQueue:   c.rt.fairQueue,
+	}
+	if c.rt.shuffle {
+		st.ShuffleSeed = c.rt.shuffleSeed
 	}
 	if c.rt.currentTrackID != "" {
 		st.Current = &TrackDTO{
@@ -98,6 +180,7 @@ func (c *Controller) Play() error {
 		c.rt.startedAt = time.Now().UTC()
 		// basePosSec remains as stored
 	}
+	c.reloadStreamLocked()
 	c.broadcastStateLocked()
 	return nil
 }
@@ -109,6 +192,7 @@ func (c *Controller) Pause() error {
 	if !c.rt.isPlaying {
 		// still broadcast paused state
 		c.rt.isPaused = true
+		c.reloadStreamLocked()
 		c.broadcastStateLocked()
 		return nil
 	}
@@ -117,21 +201,51 @@ func (c *Controller) Pause() error {
 	c.rt.basePosSec = c.positionLocked()
 	c.rt.isPaused = true
 	c.rt.startedAt = time.Time{} // reset
+	c.reloadStreamLocked()
 	c.broadcastStateLocked()
 	return nil
 }
 
+// fadeTracks asks advance to derive the crossfade from the outgoing and incoming tracks.
+const fadeTracks time.Duration = -1
+
+// Next moves to the next queue entry, crossfading into it.
 func (c *Controller) Next() error {
+	return c.advance(fadeTracks, false)
+}
+
+// NextWithoutFade moves to the next queue entry with a hard cut.
+func (c *Controller) NextWithoutFade() error {
+	return c.advance(0, false)
+}
+
+// advance switches tracks; auto is set when the current track has ended (repeat-one applies).
+// fade is the crossfade (0 = hard cut, fadeTracks = from the tracks' lengths); it is still capped
+// for the incoming track.
+func (c *Controller) advance(fade time.Duration, auto bool) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	return c.advanceLocked(fade, auto)
+}
+
+func (c *Controller) advanceLocked(fade time.Duration, auto bool) error {
+	if auto && c.rt.repeat == RepeatOne && c.rt.currentTrackID != "" {
+		c.replayCurrentLocked(fade)
+		return nil
+	}
+
+	// an empty queue falls back to autopilot playlists
+	c.ensureAutopilotLocked()
 
 	tx := c.db.Begin()
 	defer func() { _ = tx.Rollback() }()
 
-	q, t, err := nextTrack(tx)
+	q, t, err := nextTrack(tx, c.playModeLocked())
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			// end of queue: stop
+			// end of queue: stop (commit first: the history write must not wait on this tx)
+			_ = tx.Commit()
+			c.finishHistoryLocked(!auto)
 			c.rt.isPlaying = false
 			c.rt.isPaused = true
 			c.rt.basePosSec = 0
@@ -142,7 +256,9 @@ func (c *Controller) Next() error {
 			c.rt.currentURL = ""
 			c.rt.currentAddedBy = ""
 			c.rt.durationSec = 0
-			_ = tx.Commit()
+			c.rt.currentLoudness = nil
+			c.rt.currentInput = ""
+			c.reloadStreamLocked()
 			c.broadcastStateLocked()
 			c.broadcastQueueLocked()
 			return nil
@@ -153,12 +269,78 @@ func (c *Controller) Next() error {
 		return err
 	}
 
-	c.applyCurrentLocked(q.ID.String(), t.ID.String(), t.Title, t.SourceURL, t.AddedByNick, t.DurationSec)
+	if fade != 0 {
+		c.rt.crossfade = c.transitionFade(c.rt.durationSec, t.DurationSec)
+		if fade > 0 {
+			c.rt.crossfade = min(c.rt.crossfade, fade)
+		}
+	}
+	c.finishHistoryLocked(!auto)
+	c.applyCurrentLocked(q.ID.String(), &t)
+	c.rt.crossfade = 0
+	if c.ensureAutopilotLocked() {
+		c.schedulePrefetchLocked()
+	}
 	c.broadcastQueueLocked()
 	c.broadcastStateLocked()
 	return nil
 }
 
+// replayCurrentLocked restarts the current track from the beginning (repeat-one).
+func (c *Controller) replayCurrentLocked(fade time.Duration) {
+	if fade != 0 {
+		c.rt.crossfade = c.transitionFade(c.rt.durationSec, c.rt.durationSec)
+		if fade > 0 {
+			c.rt.crossfade = min(c.rt.crossfade, fade)
+		}
+	}
+	c.finishHistoryLocked(false)
+	c.rt.basePosSec = 0
+	c.rt.startedAt = time.Now().UTC()
+	c.startHistoryLocked()
+	c.rt.seekSeq++
+	c.reloadStreamLocked()
+	c.rt.crossfade = 0
+	c.broadcastStateLocked()
+}
+
+// crossfadeFor caps the fade at half of a track's length so short tracks are still heard.
+func (c *Controller) crossfadeFor(durationSec int) time.Duration {
+	if half := time.Duration(durationSec) * time.Second / 2; durationSec > 0 && c.crossfade > half {
+		return half
+	}
+	return c.crossfade
+}
+
+// transitionFade is the crossfade between two tracks: short enough for both of them.
+func (c *Controller) transitionFade(fromSec, toSec int) time.Duration {
+	return min(c.crossfadeFor(fromSec), c.crossfadeFor(toSec))
+}
+
+// autoFadeLocked returns the crossfade into whatever plays after the current track: the
+// next entry, or the same track under repeat-one. An unknown next (end of queue, autopilot)
+// counts as a track of the same length.
+func (c *Controller) autoFadeLocked() time.Duration {
+	next := c.rt.durationSec
+	if c.rt.repeat != RepeatOne {
+		if _, t, err := firstNext(c.db, c.playModeLocked()); err == nil {
+			next = t.DurationSec
+		}
+	}
+	return c.transitionFade(c.rt.durationSec, next)
+}
+
+// autoAdvance moves on from an ending track with the fade its early start was timed for,
+// unless playback already moved to another entry.
+func (c *Controller) autoAdvance(qid string, fade time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rt.currentQueueID != qid {
+		return
+	}
+	_ = c.advanceLocked(fade, true)
+}
+
 func (c *Controller) Prev() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -174,12 +356,44 @@ func (c *Controller) Prev() error {
 		return err
 	}
 
-	c.applyCurrentLocked(q.ID.String(), t.ID.String(), t.Title, t.SourceURL, t.AddedByNick, t.DurationSec)
+	c.applyCurrentLocked(q.ID.String(), &t)
 	c.broadcastQueueLocked()
 	c.broadcastStateLocked()
 	return nil
 }
 
+var ErrNothingPlaying = errors.New("nothing is playing")
+
+// Seek jumps inside the current track. With relative set, sec is an offset from the
+// current position (negative rewinds). The result is clamped to the track length.
+func (c *Controller) Seek(sec int, relative bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.rt.currentTrackID == "" {
+		return ErrNothingPlaying
+	}
+	pos := sec
+	if relative {
+		pos += c.positionLocked()
+	}
+	if pos < 0 {
+		pos = 0
+	}
+	if c.rt.durationSec > 0 && pos >= c.rt.durationSec {
+		pos = c.rt.durationSec - 1
+	}
+
+	c.rt.basePosSec = pos
+	if c.rt.isPlaying && !c.rt.isPaused {
+		c.rt.startedAt = time.Now().UTC()
+	}
+	c.rt.seekSeq++
+	c.reloadStreamLocked()
+	c.broadcastStateLocked()
+	return nil
+}
+
 func (c *Controller) SetVolume(v float64) {
 	if v < 0 {
 		v = 0
@@ -189,23 +403,133 @@ func (c *Controller) SetVolume(v float64) {
 	}
 	c.mu.Lock()
 	c.rt.volume = v
+	c.reloadStreamLocked()
 	c.broadcastStateLocked()
 	c.mu.Unlock()
 }
 
-func (c *Controller) AddTrack(url string, addedByUser *uuid.UUID, addedByNick string, insertNext bool, isDonation bool) (string, error) {
-	// Resolve meta(s)
-	metas, err := c.yt.ResolveMetas(context.Background(), url)
+// AddRequest is one song request.
+type AddRequest struct {
+	URL        string
+	UserID     *uuid.UUID
+	Nick       string
+	InsertNext bool
+	IsDonation bool
+	// DonationAmount picks the donation tier (see DonationTiers).
+	DonationAmount int64
+	Privileged     bool // owner request: quotas do not apply
+	// LibraryTrackID queues a local library track instead of URL.
+	LibraryTrackID *uuid.UUID
+}
+
+// AddTrack queues req.URL, which may also be a plain text query (the top search result is used).
+func (c *Controller) AddTrack(req AddRequest) (string, error) {
+	var lib *db.Track
+	if req.LibraryTrackID != nil {
+		t, err := libraryTrack(c.db, *req.LibraryTrackID)
+		if err != nil {
+			return "", err
+		}
+		lib = &t
+		req.URL = t.SourceURL
+	}
+	query := ""
+	if lib == nil && !source.IsURL(req.URL) {
+		if query = strings.TrimSpace(req.URL); query == "" {
+			return "", errors.New("empty request")
+		}
+	}
+
+	rules := c.Rules()
+	// a query's result URL is checked with the other rules once it is known
+	if !req.Privileged && query == "" {
+		if err := rules.checkURL(req.URL); err != nil {
+			return "", err
+		}
+	}
+
+	limited := !req.IsDonation && !req.Privileged
+	key := requesterKey(req.UserID, req.Nick)
+	if limited {
+		pending, err := countPending(c.db, req.UserID, req.Nick)
+		if err != nil {
+			return "", err
+		}
+		if err := c.checkPending(pending, 1); err != nil {
+			return "", err
+		}
+		// before resolving: a lookup costs a yt-dlp run whether or not it succeeds
+		if err := c.claimCooldown(key); err != nil {
+			return "", err
+		}
+	}
+
+	// Resolve meta(s); library tracks were probed by the scanner
+	var metas []source.Meta
+	var err error
+	switch {
+	case lib != nil:
+		metas = []source.Meta{{
+			Title:       lib.Title,
+			DurationSec: lib.DurationSec,
+			WebpageURL:  lib.SourceURL,
+			Channel:     lib.Artist,
+			Album:       lib.Album,
+			Provider:    lib.Provider,
+		}}
+	case query != "":
+		metas, err = c.searchTop(query)
+	default:
+		metas, err = c.sources.Resolve(context.Background(), req.URL, req.Privileged)
+		if errors.Is(err, source.ErrRestricted) {
+			err = ruleRejected("ссылки на произвольные сайты может добавлять только владелец")
+		}
+	}
 	if err != nil {
 		return "", err
 	}
 	if len(metas) == 0 {
 		return "", errors.New("no tracks resolved")
 	}
+	if !req.Privileged {
+		if metas, err = rules.filterMetas(metas); err != nil {
+			return "", err
+		}
+	}
+
+	c.mu.RLock()
+	mode := c.playModeLocked()
+	c.mu.RUnlock()
 
 	tx := c.db.Begin()
 	defer func() { _ = tx.Rollback() }()
 
+	merged := ""
+	if limited {
+		if err := c.checkPlaylistSize(len(metas)); err != nil {
+			return "", err
+		}
+		if metas, merged, err = c.dedupeMetas(tx, metas, key); err != nil {
+			return "", err
+		}
+		if len(metas) == 0 {
+			// every track was already queued: only request counters changed
+			if err := tx.Commit().Error; err != nil {
+				return "", err
+			}
+			c.broadcastQueue()
+			return merged, nil
+		}
+		// re-check inside the transaction with the real track count
+		pending, err := countPending(tx, req.UserID, req.Nick)
+		if err != nil {
+			return "", err
+		}
+		if err := c.checkPending(pending, len(metas)); err != nil {
+			return "", err
+		}
+	}
+
 	// Determine insert position
 	pos, err := maxPosition(tx)
 	if err != nil {
@@ -215,12 +539,31 @@ func (c *Controller) AddTrack(url string, addedByUser *uuid.UUID, addedByNick st
 	status := "next"
 	insertPos := pos + 1
 
-	// InsertNext means: position right after current
-	if insertNext {
-		// find current position
-		var cur struct{ Position int }
-		if err := tx.Table("queue_entries").Select("position").Where("status = ?", "current").Scan(&cur).Error; err == nil && cur.Position > 0 {
-			insertPos = cur.Position + 1
+	// user requests go before queued autopilot tracks
+	if fb, err := firstFallbackPosition(tx); err == nil && fb > 0 && !req.InsertNext {
+		insertPos = fb
+		if err := shiftPositionsFrom(tx, insertPos, len(metas)); err != nil {
+			return "", err
+		}
+	}
+
+	priority, interrupt := 0, false
+	if req.IsDonation {
+		tiers := c.DonationTiers()
+		priority, interrupt = tiers.priority(req.DonationAmount), tiers.interrupts(req.DonationAmount)
+	}
+
+	// InsertNext means: position right after current; a donation also goes after upcoming
+	// donations of the same or a higher tier unless it interrupts
+	interrupted := false
+	if req.InsertNext {
+		p, err := insertNextPosition(tx, req.IsDonation && !interrupt, priority)
+		if err != nil {
+			return "", err
+		}
+		if p > 0 {
+			insertPos = p
+			interrupted = interrupt
 			if err := shiftPositionsFrom(tx, insertPos, len(metas)); err != nil {
 				return "", err
 			}
@@ -231,16 +574,25 @@ func (c *Controller) AddTrack(url string, addedByUser *uuid.UUID, addedByNick st
 		queueID string
 		track   TrackDTO
 	}, 0, len(metas))
+	analyze := make([]loudnessJob, 0, len(metas))
 	for i, meta := range metas {
-		t, err := addTrack(tx, meta.WebpageURL, meta.Title, meta.DurationSec, addedByUser, addedByNick)
+		t, err := addTrack(tx, meta.WebpageURL, meta.Provider, meta.Title, meta.DurationSec, req.UserID, req.Nick)
 		if err != nil {
 			return "", err
 		}
 
-		q, err := insertQueueEntry(tx, t.ID, insertPos+i, status, isDonation)
+		q, err := insertQueueEntry(tx, db.QueueEntry{
+			TrackID:          t.ID,
+			Position:         insertPos + i,
+			Status:           status,
+			IsDonation:       req.IsDonation,
+			DonationAmount:   req.DonationAmount,
+			DonationPriority: priority,
+		})
 		if err != nil {
 			return "", err
 		}
+		analyze = append(analyze, loudnessJob{trackID: t.ID, url: t.SourceURL})
 		inserted = append(inserted, struct {
 			queueID string
 			track   TrackDTO
@@ -255,17 +607,24 @@ func (c *Controller) AddTrack(url string, addedByUser *uuid.UUID, addedByNick st
 		})
 	}
 
-	if err := ensureQueueHasCurrent(tx); err != nil {
+	if err := ensureQueueHasCurrent(tx, mode); err != nil {
 		return "", err
 	}
 
 	if err := tx.Commit().Error; err != nil {
 		return "", err
 	}
+	for _, job := range analyze {
+		c.cache.Prefetch(job.url)
+		c.queueLoudness(job.trackID, job.url)
+	}
 
 	// refresh current runtime if empty
 	_ = c.refreshCurrentFromDB()
 	c.autoStartIfStopped()
+	c.mu.Lock()
+	c.schedulePrefetchLocked()
+	c.mu.Unlock()
 
 	// broadcast
 	for _, item := range inserted {
@@ -274,27 +633,69 @@ func (c *Controller) AddTrack(url string, addedByUser *uuid.UUID, addedByNick st
 			"title":       item.track.Title,
 			"url":         item.track.URL,
 			"addedByNick": item.track.AddedByNick,
-			"isDonation":  isDonation,
+			"isDonation":  req.IsDonation,
 		}})
 	}
 	c.broadcastQueue()
 	c.broadcastState()
 
+	if interrupted {
+		log.Printf("донат %d от %s прерывает текущий трек", req.DonationAmount, req.Nick)
+		if err := c.Next(); err != nil {
+			log.Printf("донат: не удалось переключить трек: %v", err)
+		}
+	}
+
 	return inserted[0].queueID, nil
 }
 
-func (c *Controller) ListQueue() ([]QueueEntryDTO, error) {
-	return listQueue(c.db)
+// prefetchUpcoming queues cache downloads for the current and next tracks (e.g. after a restart).
+func (c *Controller) prefetchUpcoming() {
+	if c.cache == nil {
+		return
+	}
+	tracks, err := upcomingTracks(c.db)
+	if err != nil {
+		return
+	}
+	for _, t := range tracks {
+		c.cache.Prefetch(t.SourceURL)
+	}
+}
+
+func (c *Controller) CacheStats() cache.Stats {
+	return c.cache.Stats()
 }
 
-func (c *Controller) CurrentForStreaming() (url string, posSec int, vol float64, paused bool, ok bool) {
+func (c *Controller) StreamSnapshot() StreamSnapshot {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
+	return c.streamSnapshotLocked()
+}
 
-	if c.rt.currentURL == "" {
-		return "", 0, c.rt.volume, true, false
+func (c *Controller) streamSnapshotLocked() StreamSnapshot {
+	input := c.rt.currentInput
+	if time.Since(c.rt.currentInputAt) >= prefetchTTL {
+		input = ""
+	}
+	return StreamSnapshot{
+		Seq:     c.rt.streamSeq,
+		QueueID: c.rt.currentQueueID,
+		URL:     c.rt.currentURL,
+		PosSec:  c.positionLocked(),
+		Volume:  c.rt.volume,
+		Paused:  c.rt.currentURL == "" || c.rt.isPaused || !c.rt.isPlaying,
+		Filter:  c.loudness.filter(c.rt.currentLoudness),
+		Input:   input,
+		Fade:    c.rt.crossfade,
+		SeekSeq: c.rt.seekSeq,
 	}
-	return c.rt.currentURL, c.positionLocked(), c.rt.volume, c.rt.isPaused || !c.rt.isPlaying, true
+}
+
+// reloadStreamLocked pushes the current playback state to the station encoder.
+func (c *Controller) reloadStreamLocked() {
+	c.rt.streamSeq++
+	c.bc.Reload(c.streamSnapshotLocked())
 }
 
 func (c *Controller) refreshCurrentFromDB() error {
@@ -304,26 +705,49 @@ func (c *Controller) refreshCurrentFromDB() error {
 }
 
 func (c *Controller) refreshCurrentFromDBLocked() error {
-	curQ, curT, err := getCurrent(c.db)
+	curQ, curT, err := getCurrent(c.db, c.playModeLocked())
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			// empty
-			c.applyCurrentLocked("", "", "", "", "", 0)
+			if c.rt.currentQueueID == "" {
+				return nil
+			}
+			c.applyCurrentLocked("", nil)
 			return nil
 		}
 		return err
 	}
-	c.applyCurrentLocked(curQ.ID.String(), curT.ID.String(), curT.Title, curT.SourceURL, curT.AddedByNick, curT.DurationSec)
+	if curQ.ID.String() == c.rt.currentQueueID {
+		// same entry: keep position and the running encoder
+		return nil
+	}
+	c.applyCurrentLocked(curQ.ID.String(), &curT)
 	return nil
 }
 
-func (c *Controller) applyCurrentLocked(qid, tid, title, url, addedBy string, duration int) {
+// applyCurrentLocked switches the runtime to a queue entry (t == nil clears it).
+func (c *Controller) applyCurrentLocked(qid string, t *db.Track) {
+	// leaving a track early counts as a skip unless advance already closed it as finished
+	c.finishHistoryLocked(true)
+
 	c.rt.currentQueueID = qid
-	c.rt.currentTrackID = tid
-	c.rt.currentTitle = title
-	c.rt.currentURL = url
-	c.rt.currentAddedBy = addedBy
-	c.rt.durationSec = duration
+	if t != nil {
+		c.rt.currentTrackID = t.ID.String()
+		c.rt.currentTitle = t.Title
+		c.rt.currentURL = t.SourceURL
+		c.rt.currentAddedBy = t.AddedByNick
+		c.rt.durationSec = t.DurationSec
+		c.rt.currentLoudness = trackLoudness(t.MetadataJSON)
+		c.rt.currentInput, c.rt.currentInputAt = c.takePrefetch(qid)
+	} else {
+		c.rt.currentTrackID = ""
+		c.rt.currentTitle = ""
+		c.rt.currentURL = ""
+		c.rt.currentAddedBy = ""
+		c.rt.durationSec = 0
+		c.rt.currentLoudness = nil
+		c.rt.currentInput = ""
+	}
 
 	// reset position when switching
 	c.rt.basePosSec = 0
@@ -332,6 +756,11 @@ func (c *Controller) applyCurrentLocked(qid, tid, title, url, addedBy string, du
 	} else {
 		c.rt.startedAt = time.Time{}
 	}
+	if t != nil {
+		c.startHistoryLocked()
+	}
+	c.reloadStreamLocked()
+	c.schedulePrefetchLocked()
 }
 
 func (c *Controller) autoStartIfStopped() {
@@ -349,6 +778,7 @@ func (c *Controller) autoStartIfStopped() {
 	c.rt.isPaused = false
 	c.rt.basePosSec = 0
 	c.rt.startedAt = time.Now().UTC()
+	c.reloadStreamLocked()
 }
 
 func (c *Controller) positionLocked() int {
@@ -363,47 +793,58 @@ func (c *Controller) positionLocked() int {
 	return p
 }
 
+// broadcastState sends player_state and saves the runtime (every state change ends up here).
 func (c *Controller) broadcastState() {
 	c.mu.RLock()
-	st := c.State()
+	st := c.stateLocked()
+	c.persistRuntimeLocked()
 	c.mu.RUnlock()
 	c.hub.Broadcast(websocket.Event{Type: websocket.EventPlayerState, Data: st})
 }
 
 func (c *Controller) broadcastStateLocked() {
-	st := c.State()
+	st := c.stateLocked()
+	c.persistRuntimeLocked()
 	c.hub.Broadcast(websocket.Event{Type: websocket.EventPlayerState, Data: st})
 }
 
 func (c *Controller) broadcastQueue() {
-	items, err := c.ListQueue()
-	if err != nil {
-		return
-	}
-	c.hub.Broadcast(websocket.Event{Type: websocket.EventQueueUpdate, Data: items})
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	c.broadcastQueueLocked()
 }
 
+// broadcastQueueLocked publishes the queue window diff. Needs at least the read lock.
 func (c *Controller) broadcastQueueLocked() {
-	items, err := c.ListQueue()
-	if err != nil {
-		return
-	}
-	c.hub.Broadcast(websocket.Event{Type: websocket.EventQueueUpdate, Data: items})
+	_, _ = c.publishQueueWindowLocked()
 }
 
 func (c *Controller) autoAdvanceLoop() {
 	t := time.NewTicker(1 * time.Second)
 	defer t.Stop()
 
+	lastSave := time.Now()
 	for range t.C {
 		c.mu.RLock()
 		playing := c.rt.isPlaying && !c.rt.isPaused
 		dur := c.rt.durationSec
 		pos := c.positionLocked()
+		qid := c.rt.currentQueueID
+		if playing && time.Since(lastSave) >= runtimeCheckpointEvery {
+			c.persistRuntimeLocked()
+			lastSave = time.Now()
+		}
+		// start the next track early so it overlaps the fade-out of this one; the fade into
+		// the next track is never longer than this track's own, so look it up only near the end
+		var fade time.Duration
+		near := playing && dur > 0 && pos >= dur-int(c.crossfadeFor(dur)/time.Second)
+		if near {
+			fade = c.autoFadeLocked()
+		}
 		c.mu.RUnlock()
 
-		if playing && dur > 0 && pos >= dur {
-			_ = c.Next()
+		if near && pos >= dur-int(fade/time.Second) {
+			c.autoAdvance(qid, fade)
 		}
 	}
 }
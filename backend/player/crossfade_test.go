@@ -0,0 +1,58 @@
+package player
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAutoFadeUsesShorterOfBothTracks(t *testing.T) {
+	c := newTestController(t, func(d *ControllerDeps) {
+		d.Crossfade = 10 * time.Second
+	})
+	request(t, c, "alice", "long?dur=200")
+	request(t, c, "alice", "short?dur=6")
+
+	fade := func() time.Duration {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		return c.autoFadeLocked()
+	}
+	// the early start is timed for the 3 s fade advance will use, not the current track's 10 s
+	if got := fade(); got != 3*time.Second {
+		t.Fatalf("fade into short track = %v, want 3s", got)
+	}
+
+	_ = c.SetRepeat(RepeatOne)
+	if got := fade(); got != 10*time.Second {
+		t.Fatalf("fade under repeat-one = %v, want 10s", got)
+	}
+	_ = c.SetRepeat(RepeatOff)
+
+	if err := c.Next(); err != nil {
+		t.Fatal(err)
+	}
+	// end of queue: nothing known to fade into
+	if got := fade(); got != 3*time.Second {
+		t.Fatalf("fade at queue end = %v, want 3s", got)
+	}
+}
+
+func TestAutoAdvanceIgnoresStaleEntry(t *testing.T) {
+	c := newTestController(t, nil)
+	request(t, c, "alice", "a")
+	request(t, c, "alice", "b")
+	request(t, c, "alice", "c")
+
+	c.mu.RLock()
+	stale := c.rt.currentQueueID
+	c.mu.RUnlock()
+	if err := c.Next(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the loop saw "a" ending, but "b" started meanwhile
+	c.autoAdvance(stale, 0)
+	if got := currentTitle(c); got != "b" {
+		t.Fatalf("current = %q, want b", got)
+	}
+}
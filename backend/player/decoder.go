@@ -0,0 +1,171 @@
+// Purpose: Per-track decoder. Resolves the audio input (cached file or direct URL) and decodes
+// it to raw PCM frames that the station pump pulls in real time. ffmpeg runs ahead until the
+// frame buffer is full.
+
+package player
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+
+	"radiokpowka/backend/cache"
+	"radiokpowka/backend/source"
+)
+
+// ~2 seconds of decoded audio buffered ahead of the pump.
+const decoderBufferFrames = 100
+
+type trackDecoder struct {
+	queueID string
+	seekSeq uint64
+	frames  chan []byte
+	cancel  context.CancelFunc
+
+	// guarded by Broadcaster.mu
+	finished bool  // frames drained
+	env      *fade // crossfade envelope, nil = full volume
+}
+
+func startDecoder(ctx context.Context, b *Broadcaster, s StreamSnapshot) *trackDecoder {
+	ctx, cancel := context.WithCancel(ctx)
+	d := &trackDecoder{
+		queueID: s.QueueID,
+		seekSeq: s.SeekSeq,
+		frames:  make(chan []byte, decoderBufferFrames),
+		cancel:  cancel,
+	}
+
+	go func() {
+		defer close(d.frames)
+
+		prefetched := s.Input
+		for {
+			input, err := resolveAudioInput(ctx, b.sources, b.cache, s.URL, prefetched)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("стрим: ошибка получения direct URL: %v", err)
+				}
+				return
+			}
+
+			log.Printf("стрим: старт трека url=%s pos=%d", s.URL, s.PosSec)
+			fw := &frameWriter{ctx: ctx, out: d.frames}
+			counter := &countWriter{w: fw}
+			cmd := NewDecoderFFMPEG(ctx, b.ffmpeg, input, s.PosSec, s.Filter, counter)
+			err = runFFMPEG(ctx, cmd, "decoder", counter)
+			if err == nil {
+				fw.flush()
+				return
+			}
+			if ctx.Err() != nil || counter.Count() > 0 || input != prefetched || prefetched == "" {
+				return
+			}
+			// the prefetched URL did not open (expired?): resolve it again once
+			log.Printf("стрим: предзагруженный URL не открылся, получаем заново url=%s", s.URL)
+			prefetched = ""
+		}
+	}()
+	return d
+}
+
+// resolveAudioInput prefers the locally cached file, then an already prefetched direct URL,
+// and falls back to asking the track's source for its input.
+func resolveAudioInput(ctx context.Context, sources *source.Registry, c *cache.Cache, url, prefetched string) (string, error) {
+	if path, ok := c.Lookup(url); ok {
+		return path, nil
+	}
+	c.Prefetch(url)
+	if prefetched != "" {
+		return prefetched, nil
+	}
+	return sources.Input(ctx, url)
+}
+
+func (d *trackDecoder) stop() {
+	d.cancel()
+}
+
+// fade is a per-frame equal-power gain envelope used for crossfades.
+type fade struct {
+	out    bool // fading out instead of in
+	frames int
+	pos    int
+}
+
+func fadeFrames(d time.Duration) int {
+	return max(1, int(d/pcmFrameDuration))
+}
+
+// fadeOut starts a fade-out that continues from the current gain of env (if it was fading in).
+func fadeOut(env *fade, frames int) *fade {
+	f := &fade{out: true, frames: frames}
+	if env != nil && !env.out && !env.done() {
+		// cos((1-x)·π/2) == sin(x·π/2): pick the point with the same gain
+		f.pos = frames - env.pos*frames/env.frames
+	}
+	return f
+}
+
+// gain returns the envelope value for the next frame and advances it.
+func (f *fade) gain() float64 {
+	if f == nil {
+		return 1
+	}
+	x := math.Min(1, float64(f.pos)/float64(f.frames))
+	f.pos++
+	if f.out {
+		return math.Cos(x * math.Pi / 2)
+	}
+	return math.Sin(x * math.Pi / 2)
+}
+
+func (f *fade) done() bool {
+	return f == nil || f.pos >= f.frames
+}
+
+// frameWriter slices the decoder output into fixed-size PCM frames.
+// Write blocks while the frame buffer is full, which throttles ffmpeg.
+type frameWriter struct {
+	ctx context.Context
+	out chan<- []byte
+	buf []byte
+}
+
+func (fw *frameWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		if fw.buf == nil {
+			fw.buf = make([]byte, 0, pcmFrameBytes)
+		}
+		c := copy(fw.buf[len(fw.buf):pcmFrameBytes], p)
+		fw.buf = fw.buf[:len(fw.buf)+c]
+		p = p[c:]
+		if len(fw.buf) == pcmFrameBytes {
+			if err := fw.send(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// flush pads and sends the trailing partial frame.
+func (fw *frameWriter) flush() {
+	if len(fw.buf) == 0 {
+		return
+	}
+	fw.buf = fw.buf[:pcmFrameBytes]
+	_ = fw.send()
+}
+
+func (fw *frameWriter) send() error {
+	select {
+	case fw.out <- fw.buf:
+		fw.buf = nil
+		return nil
+	case <-fw.ctx.Done():
+		return fw.ctx.Err()
+	}
+}
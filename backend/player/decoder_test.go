@@ -0,0 +1,172 @@
+package player
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestFrameWriterSlicesFrames(t *testing.T) {
+	out := make(chan []byte, 4)
+	fw := &frameWriter{ctx: context.Background(), out: out}
+
+	data := make([]byte, pcmFrameBytes+pcmFrameBytes/2)
+	for i := range data {
+		data[i] = 1
+	}
+	// uneven writes still come out as whole frames
+	if _, err := fw.Write(data[:7]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.Write(data[7:]); err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 1 {
+		t.Fatalf("frames = %d, want 1 before flush", len(out))
+	}
+	fw.flush()
+	if len(out) != 2 {
+		t.Fatalf("frames = %d, want 2 after flush", len(out))
+	}
+
+	first, last := <-out, <-out
+	if len(first) != pcmFrameBytes || len(last) != pcmFrameBytes {
+		t.Fatalf("frame sizes %d/%d, want %d", len(first), len(last), pcmFrameBytes)
+	}
+	if last[pcmFrameBytes/2-1] != 1 || last[pcmFrameBytes/2] != 0 {
+		t.Fatal("trailing frame must be padded with silence")
+	}
+
+	fw.flush() // nothing buffered
+	if len(out) != 0 {
+		t.Fatal("empty flush must not send a frame")
+	}
+}
+
+func TestFrameWriterStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	fw := &frameWriter{ctx: ctx, out: make(chan []byte)}
+	cancel()
+	if _, err := fw.Write(make([]byte, pcmFrameBytes)); err == nil {
+		t.Fatal("write into a full buffer after cancel must fail")
+	}
+}
+
+func TestFadeEnvelope(t *testing.T) {
+	if fadeFrames(0) != 1 || fadeFrames(time.Second) != 50 {
+		t.Fatalf("fadeFrames = %d/%d", fadeFrames(0), fadeFrames(time.Second))
+	}
+
+	in := &fade{frames: 4}
+	gains := []float64{}
+	for !in.done() {
+		gains = append(gains, in.gain())
+	}
+	if len(gains) != 4 || gains[0] != 0 {
+		t.Fatalf("fade-in gains %v", gains)
+	}
+	for i := 1; i < len(gains); i++ {
+		if gains[i] <= gains[i-1] {
+			t.Fatalf("fade-in not rising: %v", gains)
+		}
+	}
+	if g := in.gain(); g != 1 {
+		t.Fatalf("gain after fade-in = %v, want 1", g)
+	}
+
+	out := fadeOut(nil, 4)
+	if g := out.gain(); g != 1 {
+		t.Fatalf("fade-out starts at %v, want 1", g)
+	}
+	for !out.done() {
+		out.gain()
+	}
+	if g := out.gain(); math.Abs(g) > 1e-9 {
+		t.Fatalf("gain after fade-out = %v, want 0", g)
+	}
+
+	var none *fade
+	if none.gain() != 1 || !none.done() {
+		t.Fatal("nil envelope is full volume")
+	}
+}
+
+func TestFadeOutContinuesFadeIn(t *testing.T) {
+	in := &fade{frames: 10}
+	for range 3 {
+		in.gain()
+	}
+	peek := *in
+	want := peek.gain()
+
+	// a skip in the middle of a fade-in must not jump to full volume
+	out := fadeOut(in, 10)
+	if got := out.gain(); math.Abs(got-want) > 1e-9 {
+		t.Fatalf("fade-out starts at %v, want %v", got, want)
+	}
+}
+
+func pcmFrame(v int16) []byte {
+	f := make([]byte, pcmFrameBytes)
+	for i := 0; i+1 < len(f); i += 2 {
+		binary.LittleEndian.PutUint16(f[i:], uint16(v))
+	}
+	return f
+}
+
+func sample(f []byte) int16 {
+	return int16(binary.LittleEndian.Uint16(f))
+}
+
+func testDecoder(frames ...[]byte) *trackDecoder {
+	d := &trackDecoder{frames: make(chan []byte, len(frames)), cancel: func() {}}
+	for _, f := range frames {
+		d.frames <- f
+	}
+	close(d.frames)
+	return d
+}
+
+func TestNextFrameSilenceAndMix(t *testing.T) {
+	b := NewBroadcaster(nil, nil, "ffmpeg", nil, HLSConfig{}, nil)
+	b.snap.Volume = 1
+
+	// nothing decoded yet: the pump keeps the stream alive with silence
+	if f := b.nextFrame(); f != nil {
+		t.Fatal("expected silence without a decoder")
+	}
+
+	b.dec = testDecoder(pcmFrame(1000), pcmFrame(1000))
+	b.fading = testDecoder(pcmFrame(2000))
+	b.fading.env = &fade{out: true, frames: 1}
+
+	f := b.nextFrame()
+	if f == nil || sample(f) != 3000 {
+		t.Fatal("expected the fading track mixed into the current one")
+	}
+	if b.fading != nil {
+		t.Fatal("finished fade-out must be dropped")
+	}
+	if f := b.nextFrame(); f == nil || sample(f) != 1000 {
+		t.Fatal("expected the current track alone after the crossfade")
+	}
+
+	// a drained decoder yields silence, not an end of stream
+	if f := b.nextFrame(); f != nil {
+		t.Fatal("expected silence after the track ran out")
+	}
+	if !b.dec.finished {
+		t.Fatal("drained decoder must be marked finished")
+	}
+}
+
+func TestNextFrameVolume(t *testing.T) {
+	b := NewBroadcaster(nil, nil, "ffmpeg", nil, HLSConfig{}, nil)
+	b.snap.Volume = 0.5
+	b.dec = testDecoder(pcmFrame(math.MaxInt16))
+	if f := b.nextFrame(); f == nil || sample(f) != math.MaxInt16/2 {
+		t.Fatal("volume must scale the frame")
+	}
+}
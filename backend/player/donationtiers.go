@@ -0,0 +1,98 @@
+// Purpose: Donation priority tiers.
+// - Donation entries go right after the current track, ordered by tier (highest first) and
+//   first come, first served within a tier; regular requests follow them.
+// - A donation of at least InterruptAmount (0 = off) cuts the current track and plays at once.
+// - Owner-editable; stored in the settings table under "donation_tiers".
+
+package player
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"strings"
+)
+
+const donationTiersSettingKey = "donation_tiers"
+
+type DonationTier struct {
+	Name      string `json:"name"`
+	MinAmount int64  `json:"minAmount"`
+}
+
+type DonationTiers struct {
+	Tiers           []DonationTier `json:"tiers"`           // sorted by minAmount on save
+	InterruptAmount int64          `json:"interruptAmount"` // 0 = never interrupt
+}
+
+func DefaultDonationTiers() DonationTiers {
+	return DonationTiers{Tiers: []DonationTier{}}
+}
+
+func (t DonationTiers) normalize() (DonationTiers, error) {
+	if t.InterruptAmount < 0 {
+		return DonationTiers{}, errors.New("interruptAmount must not be negative")
+	}
+	tiers := make([]DonationTier, 0, len(t.Tiers))
+	for _, tier := range t.Tiers {
+		if tier.MinAmount < 0 {
+			return DonationTiers{}, errors.New("minAmount must not be negative")
+		}
+		tier.Name = strings.TrimSpace(tier.Name)
+		tiers = append(tiers, tier)
+	}
+	sort.SliceStable(tiers, func(i, j int) bool { return tiers[i].MinAmount < tiers[j].MinAmount })
+	for i := 1; i < len(tiers); i++ {
+		if tiers[i].MinAmount == tiers[i-1].MinAmount {
+			return DonationTiers{}, errors.New("tiers must have different minAmount")
+		}
+	}
+	t.Tiers = tiers
+	return t, nil
+}
+
+// priority returns how many tier thresholds the amount reaches (higher plays earlier).
+func (t DonationTiers) priority(amount int64) int {
+	n := 0
+	for _, tier := range t.Tiers {
+		if amount >= tier.MinAmount {
+			n++
+		}
+	}
+	return n
+}
+
+func (t DonationTiers) interrupts(amount int64) bool {
+	return t.InterruptAmount > 0 && amount >= t.InterruptAmount
+}
+
+func (c *Controller) DonationTiers() DonationTiers {
+	c.tiersMu.RLock()
+	defer c.tiersMu.RUnlock()
+	return c.tiers
+}
+
+// SetDonationTiers validates and stores new tiers. Queued donations keep their priority.
+func (c *Controller) SetDonationTiers(t DonationTiers) (DonationTiers, error) {
+	t, err := t.normalize()
+	if err != nil {
+		return DonationTiers{}, err
+	}
+	if err := saveSetting(c.db, donationTiersSettingKey, t); err != nil {
+		return DonationTiers{}, err
+	}
+	c.tiersMu.Lock()
+	c.tiers = t
+	c.tiersMu.Unlock()
+	return t, nil
+}
+
+func (c *Controller) loadDonationTiers() {
+	t := DefaultDonationTiers()
+	if _, err := loadSetting(c.db, donationTiersSettingKey, &t); err != nil {
+		log.Printf("уровни донатов: не удалось загрузить, используются значения по умолчанию: %v", err)
+	}
+	c.tiersMu.Lock()
+	c.tiers = t
+	c.tiersMu.Unlock()
+}
@@ -0,0 +1,90 @@
+package player
+
+import (
+	"slices"
+	"testing"
+)
+
+func donate(t *testing.T, c *Controller, nick, name string, amount int64) {
+	t.Helper()
+	_, err := c.AddTrack(AddRequest{URL: "http://tracks.test/" + name, Nick: nick, InsertNext: true, IsDonation: true, DonationAmount: amount})
+	if err != nil {
+		t.Fatalf("donation %s: %v", name, err)
+	}
+}
+
+func TestDonationTiersNormalize(t *testing.T) {
+	tiers, err := DonationTiers{Tiers: []DonationTier{{" gold ", 1000}, {"silver", 100}}}.normalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tiers.Tiers[0].Name != "silver" || tiers.Tiers[1].Name != "gold" {
+		t.Fatalf("tiers not sorted: %+v", tiers.Tiers)
+	}
+	for _, amount := range []struct {
+		amount int64
+		want   int
+	}{{10, 0}, {100, 1}, {999, 1}, {1000, 2}, {50000, 2}} {
+		if got := tiers.priority(amount.amount); got != amount.want {
+			t.Errorf("priority(%d) = %d, want %d", amount.amount, got, amount.want)
+		}
+	}
+
+	bad := []DonationTiers{
+		{InterruptAmount: -1},
+		{Tiers: []DonationTier{{"a", -5}}},
+		{Tiers: []DonationTier{{"a", 100}, {"b", 100}}},
+	}
+	for _, b := range bad {
+		if _, err := b.normalize(); err == nil {
+			t.Errorf("accepted %+v", b)
+		}
+	}
+	if (DonationTiers{}).interrupts(1 << 40) {
+		t.Fatal("interrupt is off by default")
+	}
+}
+
+func TestDonationsOrderedByTier(t *testing.T) {
+	c := newTestController(t, nil)
+	if _, err := c.SetDonationTiers(DonationTiers{Tiers: []DonationTier{{"silver", 100}, {"gold", 1000}}}); err != nil {
+		t.Fatal(err)
+	}
+	request(t, c, "alice", "now")
+	request(t, c, "alice", "regular")
+	donate(t, c, "d1", "small", 10)
+	donate(t, c, "d2", "silver1", 500)
+	donate(t, c, "d3", "gold", 2000)
+	donate(t, c, "d4", "silver2", 600)
+
+	// higher tier first, first come first served within a tier, regular requests after
+	want := []string{"gold", "silver1", "silver2", "small", "regular"}
+	if got := queueTitles(t, c); !slices.Equal(got, want) {
+		t.Fatalf("upcoming = %v, want %v", got, want)
+	}
+	if currentTitle(c) != "now" {
+		t.Fatalf("current = %q: donations below the interrupt amount must wait", currentTitle(c))
+	}
+}
+
+func TestDonationInterruptsCurrentTrack(t *testing.T) {
+	c := newTestController(t, nil)
+	if _, err := c.SetDonationTiers(DonationTiers{InterruptAmount: 5000}); err != nil {
+		t.Fatal(err)
+	}
+	request(t, c, "alice", "now")
+	donate(t, c, "d1", "queued", 100)
+	donate(t, c, "rich", "big", 5000)
+
+	if got := currentTitle(c); got != "big" {
+		t.Fatalf("current = %q, want the interrupting donation", got)
+	}
+	if got := queueTitles(t, c); !slices.Equal(got, []string{"queued"}) {
+		t.Fatalf("upcoming = %v", got)
+	}
+
+	// the tiers survive a restart
+	if got := restart(t, c).DonationTiers(); got.InterruptAmount != 5000 {
+		t.Fatalf("tiers after restart = %+v", got)
+	}
+}
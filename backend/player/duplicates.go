@@ -0,0 +1,170 @@
+// Purpose: Duplicate and recently-played detection for song requests.
+// - Tracks carry a canonical ID (youtube:<video id>), so different links to one video match.
+// - A video that is already upcoming is either rejected or merged into the queued entry,
+//   which then counts how many people requested it (DUPLICATE_POLICY=merge|reject|off).
+// - A video played within the recent window (the current one included) is rejected.
+// Donations and owner requests are exempt, like quotas.
+
+package player
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+
+	"radiokpowka/backend/db"
+	"radiokpowka/backend/source"
+	"radiokpowka/backend/youtube"
+)
+
+const (
+	DuplicateMerge  = "merge"
+	DuplicateReject = "reject"
+	DuplicateOff    = "off"
+)
+
+type DuplicateConfig struct {
+	Policy       string        // merge|reject|off for upcoming duplicates
+	RecentWindow time.Duration // 0 = allow replaying right away
+}
+
+// Request rejection codes for duplicates.
+const (
+	CodeDuplicate      = "duplicate"
+	CodeRecentlyPlayed = "recently_played"
+)
+
+// dedupeMetas drops tracks that are already upcoming or were played recently.
+// Merged duplicates bump the request counter of the queued entry; the first merged queue ID
+// is returned. When nothing is left to add and nothing was merged, the first rejection is returned.
+func (c *Controller) dedupeMetas(tx *gorm.DB, metas []source.Meta, key string) ([]source.Meta, string, error) {
+	if c.duplicates.Policy == DuplicateOff && c.duplicates.RecentWindow <= 0 {
+		return metas, "", nil
+	}
+
+	kept := make([]source.Meta, 0, len(metas))
+	merged := ""
+	var rejected error
+	for _, m := range metas {
+		qid, err := c.checkDuplicate(tx, youtube.CanonicalID(m.WebpageURL), m.Title, key)
+		var re *RequestError
+		switch {
+		case errors.As(err, &re):
+			if rejected == nil {
+				rejected = err
+			}
+		case err != nil:
+			return nil, "", err
+		case qid != "":
+			if merged == "" {
+				merged = qid
+			}
+		default:
+			kept = append(kept, m)
+		}
+	}
+	if len(kept) == 0 && merged == "" {
+		return nil, "", rejected
+	}
+	return kept, merged, nil
+}
+
+// checkDuplicate returns the queue ID the request was merged into ("" = not a duplicate).
+func (c *Controller) checkDuplicate(tx *gorm.DB, canonicalID, title, key string) (string, error) {
+	if canonicalID == "" {
+		return "", nil
+	}
+
+	if c.duplicates.RecentWindow > 0 {
+		last, err := lastPlayedAt(tx, canonicalID)
+		if err != nil {
+			return "", err
+		}
+		if last != nil {
+			if wait := time.Until(last.Add(c.duplicates.RecentWindow)); wait > 0 {
+				return "", &RequestError{
+					Code:       CodeRecentlyPlayed,
+					Message:    fmt.Sprintf("«%s» недавно звучал, повторно можно заказать через %d мин", title, int(wait.Minutes())+1),
+					RetryAfter: wait,
+				}
+			}
+		}
+	}
+
+	if c.duplicates.Policy == DuplicateOff {
+		return "", nil
+	}
+	q, err := upcomingByCanonicalID(tx, canonicalID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if c.duplicates.Policy == DuplicateReject {
+		return "", &RequestError{
+			Code:    CodeDuplicate,
+			Message: fmt.Sprintf("«%s» уже в очереди", title),
+		}
+	}
+	if err := mergeRequest(tx, q, key); err != nil {
+		return "", err
+	}
+	return q.ID.String(), nil
+}
+
+// mergeRequest counts another requester on a queued entry (once per requester).
+func mergeRequest(tx *gorm.DB, q db.QueueEntry, key string) error {
+	var keys []string
+	if len(q.Requesters) > 0 {
+		_ = json.Unmarshal(q.Requesters, &keys)
+	}
+	if len(keys) == 0 {
+		// seed with the original requester
+		var t db.Track
+		if err := tx.Where("id = ?", q.TrackID).First(&t).Error; err != nil {
+			return err
+		}
+		keys = append(keys, requesterKey(t.AddedByUserID, t.AddedByNick))
+	}
+	if slices.Contains(keys, key) {
+		return nil
+	}
+	keys = append(keys, key)
+	raw, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+	return tx.Model(&db.QueueEntry{}).Where("id = ?", q.ID).Updates(map[string]any{
+		"requesters":    raw,
+		"request_count": len(keys),
+	}).Error
+}
+
+// backfillCanonicalIDs fills canonical IDs of tracks added before they were stored, and
+// recomputes those of non-YouTube links with a query (older IDs dropped the query).
+func (c *Controller) backfillCanonicalIDs() {
+	var tracks []db.Track
+	err := c.db.Select("id", "source_url", "canonical_id").
+		Where("canonical_id IS NULL OR canonical_id = ? OR (source_url LIKE ? AND canonical_id NOT LIKE ?)", "", "%?%", "youtube:%").
+		Find(&tracks).Error
+	if err != nil {
+		log.Printf("дубликаты: не удалось прочитать треки: %v", err)
+		return
+	}
+	for _, t := range tracks {
+		id := youtube.CanonicalID(t.SourceURL)
+		if id == t.CanonicalID {
+			continue
+		}
+		if err := c.db.Model(&db.Track{}).Where("id = ?", t.ID).Update("canonical_id", id).Error; err != nil {
+			log.Printf("дубликаты: не удалось обновить трек %s: %v", t.ID, err)
+			return
+		}
+	}
+}
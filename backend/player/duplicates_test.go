@@ -0,0 +1,68 @@
+package player
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDuplicateQueryPicksDifferentTrack(t *testing.T) {
+	c := newTestController(t, func(d *ControllerDeps) {
+		d.Duplicates = DuplicateConfig{Policy: DuplicateReject}
+	})
+	request(t, c, "alice", "now")
+	request(t, c, "alice", "play.mp3?id=1")
+	request(t, c, "bob", "play.mp3?id=2")
+
+	_, err := c.AddTrack(AddRequest{URL: "http://tracks.test/play.mp3?utm_source=share&id=1", Nick: "carol"})
+	if requestCode(err) != CodeDuplicate {
+		t.Fatalf("err = %v, want %s", err, CodeDuplicate)
+	}
+	if got := queueTitles(t, c); !slices.Equal(got, []string{"play.mp3", "play.mp3"}) {
+		t.Fatalf("upcoming = %v, want both play.mp3 entries", got)
+	}
+}
+
+func TestDuplicateMergeCountsRequesters(t *testing.T) {
+	c := newTestController(t, func(d *ControllerDeps) {
+		d.Duplicates = DuplicateConfig{Policy: DuplicateMerge}
+	})
+	request(t, c, "alice", "now")
+	id := request(t, c, "alice", "a")
+	if got := request(t, c, "bob", "a"); got != id {
+		t.Fatalf("merged into %q, want %q", got, id)
+	}
+	request(t, c, "Bob", "a") // the same requester again does not count twice
+
+	w, err := c.QueueWindow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for _, e := range w.Items {
+		if e.Status == "next" && e.Title == "a" {
+			count = e.RequestCount
+		}
+	}
+	if count != 2 {
+		t.Fatalf("request count = %d, want 2", count)
+	}
+	if got := queueTitles(t, c); !slices.Equal(got, []string{"a"}) {
+		t.Fatalf("upcoming = %v, want [a]", got)
+	}
+}
+
+func TestDuplicateRecentlyPlayed(t *testing.T) {
+	c := newTestController(t, func(d *ControllerDeps) {
+		d.Duplicates = DuplicateConfig{Policy: DuplicateOff, RecentWindow: time.Hour}
+	})
+	request(t, c, "alice", "a")
+	_, err := c.AddTrack(AddRequest{URL: "http://tracks.test/a", Nick: "bob"})
+	if requestCode(err) != CodeRecentlyPlayed {
+		t.Fatalf("err = %v, want %s", err, CodeRecentlyPlayed)
+	}
+	// donations are exempt
+	if _, err := c.AddTrack(AddRequest{URL: "http://tracks.test/a", Nick: "bob", IsDonation: true}); err != nil {
+		t.Fatalf("donation: %v", err)
+	}
+}
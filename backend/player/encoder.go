@@ -0,0 +1,87 @@
+// Purpose: Station output encoders. Each one is a long-running ffmpeg fed the shared PCM
+// timeline on stdin (MP3 for /stream, HLS segments, ...). A crashed encoder is restarted
+// without affecting the others.
+
+package player
+
+import (
+	"context"
+	"log"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// ~1 second of PCM queued per encoder before frames are dropped.
+const encoderBufferFrames = 50
+
+type encoder struct {
+	name   string
+	newCmd func(ctx context.Context) *exec.Cmd
+	in     chan []byte
+}
+
+func newEncoder(name string, newCmd func(ctx context.Context) *exec.Cmd) *encoder {
+	return &encoder{
+		name:   name,
+		newCmd: newCmd,
+		in:     make(chan []byte, encoderBufferFrames),
+	}
+}
+
+// feed hands a frame to the encoder without ever blocking the pump.
+func (e *encoder) feed(frame []byte) {
+	select {
+	case e.in <- frame:
+	default:
+		// encoder stalled or restarting: drop the frame
+	}
+}
+
+// run keeps the encoder alive until ctx is cancelled.
+func (e *encoder) run(ctx context.Context) {
+	for ctx.Err() == nil {
+		if err := e.runOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("стрим: энкодер %s завершился с ошибкой: %v", e.name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (e *encoder) runOnce(ctx context.Context) error {
+	encCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cmd := e.newCmd(encCtx)
+	counter, _ := cmd.Stdout.(*countWriter)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer stdin.Close()
+		for {
+			select {
+			case <-encCtx.Done():
+				return
+			case frame := <-e.in:
+				if _, err := stdin.Write(frame); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	err = runFFMPEG(encCtx, cmd, e.name, counter)
+	cancel()
+	wg.Wait()
+	return err
+}
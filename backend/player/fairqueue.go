@@ -0,0 +1,68 @@
+// Purpose: Fair queue mode. Upcoming user entries are interleaved round-robin by requester,
+// so one active chatter cannot push everyone else back. Each requester's entries keep their
+// relative order; donation and autopilot entries keep their slots. The next entry always
+// belongs to the requester served least recently (never served first), so the announced
+// order is the order that plays even as entries are played one by one. Combined with shuffle,
+// the shuffled order decides the order within each requester and who comes first in a round.
+
+package player
+
+// fairHistoryRows bounds how many played entries are read to find who was served recently.
+const fairHistoryRows = 500
+
+// fairOrder returns the play order (indexes into keys) of upcoming entries given in
+// position order. keys are requester keys, served lists requesters by their latest played
+// entry, most recent first.
+func fairOrder(keys []string, fixed []bool, served []string) []int {
+	// lastServed orders requesters: never served < served long ago < served recently
+	lastServed := map[string]int{}
+	for i, k := range served {
+		if _, ok := lastServed[k]; !ok {
+			lastServed[k] = -1 - i
+		}
+	}
+	const never = -1 << 31
+
+	order := make([]int, len(keys))
+	var slots []int
+	pending := map[string][]int{} // requester -> entries in order
+	var requesters []string       // by first entry
+	for i, k := range keys {
+		order[i] = i
+		if fixed[i] {
+			continue
+		}
+		slots = append(slots, i)
+		if _, ok := pending[k]; !ok {
+			requesters = append(requesters, k)
+			if _, ok := lastServed[k]; !ok {
+				lastServed[k] = never
+			}
+		}
+		pending[k] = append(pending[k], i)
+	}
+
+	for step, slot := range slots {
+		pick := ""
+		for _, k := range requesters {
+			if len(pending[k]) > 0 && (pick == "" || lastServed[k] < lastServed[pick]) {
+				pick = k
+			}
+		}
+		order[slot] = pending[pick][0]
+		pending[pick] = pending[pick][1:]
+		lastServed[pick] = step
+	}
+	return order
+}
+
+// SetFairQueue turns fair queue ordering on or off.
+func (c *Controller) SetFairQueue(on bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.rt.fairQueue = on
+	c.schedulePrefetchLocked()
+	c.broadcastQueueLocked()
+	c.broadcastStateLocked()
+}
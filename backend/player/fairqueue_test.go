@@ -0,0 +1,125 @@
+package player
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFairOrder(t *testing.T) {
+	cases := []struct {
+		name   string
+		keys   []string
+		fixed  []bool
+		served []string
+		want   []int
+	}{
+		{
+			name: "round robin by first request",
+			keys: []string{"a", "a", "a", "b", "c", "b"},
+			want: []int{0, 3, 4, 1, 5, 2},
+		},
+		{
+			name:   "requester heard last waits for the round",
+			keys:   []string{"a", "a", "b"},
+			served: []string{"a"},
+			want:   []int{2, 0, 1},
+		},
+		{
+			name:   "least recently served first, never served before everyone",
+			keys:   []string{"a", "b", "c", "a", "b"},
+			served: []string{"b", "x", "a", "b"},
+			want:   []int{2, 0, 1, 3, 4},
+		},
+		{
+			name:  "fixed entries keep their slots",
+			keys:  []string{"a", "a", "", "b"},
+			fixed: []bool{false, false, true, false},
+			want:  []int{0, 3, 2, 1},
+		},
+	}
+	for _, tc := range cases {
+		fixed := tc.fixed
+		if fixed == nil {
+			fixed = make([]bool, len(tc.keys))
+		}
+		if got := fairOrder(tc.keys, fixed, tc.served); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestFairQueueInterleavesRequesters(t *testing.T) {
+	c := newTestController(t, nil)
+	request(t, c, "dj", "intro")
+	request(t, c, "alice", "a1")
+	request(t, c, "alice", "a2")
+	request(t, c, "alice", "a3")
+	request(t, c, "bob", "b1")
+	request(t, c, "Carol", "c1")
+	request(t, c, "carol ", "c2") // same requester, nick case and spaces differ
+
+	if got := queueTitles(t, c); !slices.Equal(got, []string{"a1", "a2", "a3", "b1", "c1", "c2"}) {
+		t.Fatalf("first come first served: %v", got)
+	}
+	c.SetFairQueue(true)
+	want := []string{"a1", "b1", "c1", "a2", "c2", "a3"}
+	if got := queueTitles(t, c); !slices.Equal(got, want) {
+		t.Fatalf("fair order: %v, want %v", got, want)
+	}
+
+	// the list shows what actually plays
+	var played []string
+	for range want {
+		if err := c.Next(); err != nil {
+			t.Fatal(err)
+		}
+		played = append(played, currentTitle(c))
+	}
+	if !slices.Equal(played, want) {
+		t.Fatalf("played %v, want %v", played, want)
+	}
+}
+
+func TestFairQueueKeepsDonationPriority(t *testing.T) {
+	c := newTestController(t, nil)
+	request(t, c, "dj", "intro")
+	c.SetFairQueue(true)
+	request(t, c, "alice", "a1")
+	request(t, c, "alice", "a2")
+	request(t, c, "bob", "b1")
+	if _, err := c.AddTrack(AddRequest{URL: "http://tracks.test/d", Nick: "alice", InsertNext: true, IsDonation: true, DonationAmount: 500}); err != nil {
+		t.Fatal(err)
+	}
+	if got := queueTitles(t, c); !slices.Equal(got, []string{"d", "a1", "b1", "a2"}) {
+		t.Fatalf("upcoming = %v", got)
+	}
+}
+
+func TestFairQueueRemovingCurrent(t *testing.T) {
+	c := newTestController(t, nil)
+	request(t, c, "dj", "intro")
+	request(t, c, "alice", "a1")
+	request(t, c, "alice", "a2")
+	b1 := request(t, c, "bob", "b1")
+	c.SetFairQueue(true)
+	for range 2 {
+		if err := c.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := currentTitle(c); got != "b1" {
+		t.Fatalf("current = %q, want b1", got)
+	}
+	request(t, c, "carol", "c1")
+	if got := queueTitles(t, c); !slices.Equal(got, []string{"c1", "a2"}) {
+		t.Fatalf("upcoming = %v", got)
+	}
+
+	// alice was served more recently than carol, so carol takes over the removed slot
+	if err := c.RemoveEntry(b1); err != nil {
+		t.Fatal(err)
+	}
+	if got := currentTitle(c); got != "c1" {
+		t.Fatalf("current = %q, want c1", got)
+	}
+}
@@ -0,0 +1,139 @@
+package player
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"radiokpowka/backend/db"
+	"radiokpowka/backend/source"
+	"radiokpowka/backend/websocket"
+)
+
+// fakeSource serves http://tracks.test/<name>[?dur=N] without yt-dlp; /list/<a>,<b> expands
+// to a playlist and ?fail=1 makes the direct URL lookup fail.
+type fakeSource struct {
+	mu       sync.Mutex
+	resolved int
+}
+
+func (s *fakeSource) Name() string { return "fake" }
+
+func (s *fakeSource) Match(u *url.URL) bool { return u.Host == "tracks.test" }
+
+func (s *fakeSource) Resolve(_ context.Context, raw string) ([]source.Meta, error) {
+	s.mu.Lock()
+	s.resolved++
+	s.mu.Unlock()
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	dur := 200
+	if d := u.Query().Get("dur"); d != "" {
+		fmt.Sscan(d, &dur)
+	}
+	if rest, ok := strings.CutPrefix(u.Path, "/list/"); ok {
+		var metas []source.Meta
+		for _, name := range strings.Split(rest, ",") {
+			metas = append(metas, source.Meta{Title: name, DurationSec: dur, WebpageURL: "http://tracks.test/" + name})
+		}
+		return metas, nil
+	}
+	name := strings.TrimPrefix(u.Path, "/")
+	return []source.Meta{{Title: name, DurationSec: dur, WebpageURL: raw, Channel: u.Query().Get("ch")}}, nil
+}
+
+func (s *fakeSource) Input(_ context.Context, raw string) (string, error) {
+	if strings.Contains(raw, "fail=1") {
+		return "", errors.New("no formats")
+	}
+	return raw, nil
+}
+
+func (s *fakeSource) Search(_ context.Context, query string, limit int) ([]source.Meta, error) {
+	if query == "nothing" {
+		return nil, nil
+	}
+	slug := strings.ReplaceAll(strings.ToLower(query), " ", "-")
+	return []source.Meta{{Title: query, DurationSec: 200, WebpageURL: "http://tracks.test/" + slug}}, nil
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	database, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(database); err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+// newTestController builds a controller on a fresh SQLite database; tweak adjusts deps.
+func newTestController(t *testing.T, tweak func(*ControllerDeps)) *Controller {
+	t.Helper()
+	hub := websocket.NewHub()
+	go hub.Run()
+	d := ControllerDeps{
+		DB:          newTestDB(t),
+		Hub:         hub,
+		Sources:     source.NewRegistry(&fakeSource{}),
+		QueueWindow: QueueWindowConfig{Prev: 10, Next: 50},
+	}
+	if tweak != nil {
+		tweak(&d)
+	}
+	return NewController(d)
+}
+
+// request queues http://tracks.test/<name> as nick and fails the test on error.
+func request(t *testing.T, c *Controller, nick, name string) string {
+	t.Helper()
+	id, err := c.AddTrack(AddRequest{URL: "http://tracks.test/" + name, Nick: nick})
+	if err != nil {
+		t.Fatalf("request %s by %s: %v", name, nick, err)
+	}
+	return id
+}
+
+// queueTitles returns the titles of the upcoming entries in play order.
+func queueTitles(t *testing.T, c *Controller) []string {
+	t.Helper()
+	w, err := c.QueueWindow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, e := range w.Items {
+		if e.Status == "next" {
+			titles = append(titles, e.Title)
+		}
+	}
+	return titles
+}
+
+func currentTitle(c *Controller) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.rt.currentTitle
+}
+
+func requestCode(err error) string {
+	if rerr, ok := err.(*RequestError); ok {
+		return rerr.Code
+	}
+	return ""
+}
@@ -0,0 +1,211 @@
+// Purpose: Play history. The controller opens a play_histories row when an entry becomes
+// current and closes it when playback moves on, with the position reached and whether the
+// track was skipped (anything but reaching its end). Listeners browse it with cursor
+// pagination, a date range and title search.
+
+package player
+
+import (
+	"encoding/base64"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"radiokpowka/backend/db"
+)
+
+const (
+	historyDefaultLimit = 50
+	historyMaxLimit     = 200
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type HistoryEntryDTO struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	AddedByNick string `json:"addedByNick,omitempty"`
+	DurationSec int    `json:"durationSec"`
+	StartedAt   string `json:"startedAt"`
+	EndedAt     string `json:"endedAt,omitempty"` // empty while playing
+	ListenedSec int    `json:"listenedSec"`
+	Skipped     bool   `json:"skipped"`
+}
+
+type HistoryPage struct {
+	Items      []HistoryEntryDTO `json:"items"`
+	NextCursor string            `json:"nextCursor,omitempty"`
+}
+
+// HistoryQuery filters history, newest first. Zero values mean "no filter".
+type HistoryQuery struct {
+	Cursor string
+	Limit  int
+	From   time.Time
+	To     time.Time
+	Search string // case-insensitive title substring
+}
+
+// startHistoryLocked opens a history row for the current entry. After a restart the open row
+// of the restored entry is continued instead.
+func (c *Controller) startHistoryLocked() {
+	qID, err := uuid.Parse(c.rt.currentQueueID)
+	if err != nil {
+		return
+	}
+	trackID, _ := uuid.Parse(c.rt.currentTrackID)
+	var open db.PlayHistory
+	err = c.db.Where("queue_entry_id = ? AND ended_at IS NULL", qID).Order("started_at desc").Limit(1).Find(&open).Error
+	if err == nil && open.ID != uuid.Nil {
+		c.rt.historyID = open.ID
+		return
+	}
+
+	h := db.PlayHistory{
+		ID:           uuid.New(),
+		QueueEntryID: qID,
+		TrackID:      trackID,
+		Title:        c.rt.currentTitle,
+		SourceURL:    c.rt.currentURL,
+		AddedByNick:  c.rt.currentAddedBy,
+		DurationSec:  c.rt.durationSec,
+		StartedAt:    time.Now().UTC(),
+	}
+	if err := c.db.Create(&h).Error; err != nil {
+		log.Printf("история: не удалось записать старт: %v", err)
+		return
+	}
+	c.rt.historyID = h.ID
+}
+
+// finishHistoryLocked closes the open history row (no-op when there is none).
+func (c *Controller) finishHistoryLocked(skipped bool) {
+	if c.rt.historyID == uuid.Nil {
+		return
+	}
+	id := c.rt.historyID
+	c.rt.historyID = uuid.Nil
+	err := c.db.Model(&db.PlayHistory{}).Where("id = ?", id).Updates(map[string]any{
+		"ended_at":     time.Now().UTC(),
+		"listened_sec": c.positionLocked(),
+		"skipped":      skipped,
+	}).Error
+	if err != nil {
+		log.Printf("история: не удалось записать окончание: %v", err)
+	}
+}
+
+// checkpointHistoryLocked saves the position reached so far, so a crash loses little.
+func (c *Controller) checkpointHistoryLocked() {
+	if c.rt.historyID == uuid.Nil {
+		return
+	}
+	_ = c.db.Model(&db.PlayHistory{}).Where("id = ?", c.rt.historyID).Update("listened_sec", c.positionLocked()).Error
+}
+
+// closeStaleHistory ends rows left open by a previous run (except the resumed one).
+func (c *Controller) closeStaleHistory() {
+	c.mu.RLock()
+	keep := c.rt.historyID
+	c.mu.RUnlock()
+
+	var open []db.PlayHistory
+	if err := c.db.Where("ended_at IS NULL AND id <> ?", keep).Find(&open).Error; err != nil {
+		log.Printf("история: не удалось прочитать незакрытые записи: %v", err)
+		return
+	}
+	for _, h := range open {
+		ended := h.StartedAt.Add(time.Duration(h.ListenedSec) * time.Second)
+		_ = c.db.Model(&db.PlayHistory{}).Where("id = ?", h.ID).Update("ended_at", ended).Error
+	}
+}
+
+// History returns one page of play history, newest first.
+func (c *Controller) History(q HistoryQuery) (HistoryPage, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = historyDefaultLimit
+	}
+	limit = min(limit, historyMaxLimit)
+
+	tx := c.db.Model(&db.PlayHistory{})
+	if !q.From.IsZero() {
+		tx = tx.Where("started_at >= ?", q.From.UTC())
+	}
+	if !q.To.IsZero() {
+		tx = tx.Where("started_at < ?", q.To.UTC())
+	}
+	if s := strings.ToLower(strings.TrimSpace(q.Search)); s != "" {
+		tx = tx.Where("LOWER(title) LIKE ? ESCAPE '!'", "%"+escapeLike(s)+"%")
+	}
+	if q.Cursor != "" {
+		at, id, err := decodeHistoryCursor(q.Cursor)
+		if err != nil {
+			return HistoryPage{}, err
+		}
+		tx = tx.Where("started_at < ? OR (started_at = ? AND id < ?)", at, at, id)
+	}
+
+	var rows []db.PlayHistory
+	if err := tx.Order("started_at desc, id desc").Limit(limit + 1).Find(&rows).Error; err != nil {
+		return HistoryPage{}, err
+	}
+
+	page := HistoryPage{Items: make([]HistoryEntryDTO, 0, min(len(rows), limit))}
+	for i, h := range rows {
+		if i == limit {
+			last := rows[limit-1]
+			page.NextCursor = encodeHistoryCursor(last.StartedAt, last.ID)
+			break
+		}
+		e := HistoryEntryDTO{
+			ID:          h.ID.String(),
+			Title:       h.Title,
+			URL:         h.SourceURL,
+			AddedByNick: h.AddedByNick,
+			DurationSec: h.DurationSec,
+			StartedAt:   h.StartedAt.UTC().Format(time.RFC3339),
+			ListenedSec: h.ListenedSec,
+			Skipped:     h.Skipped,
+		}
+		if h.EndedAt != nil {
+			e.EndedAt = h.EndedAt.UTC().Format(time.RFC3339)
+		}
+		page.Items = append(page.Items, e)
+	}
+	return page, nil
+}
+
+// The cursor is the (started_at, id) of the last returned row.
+func encodeHistoryCursor(at time.Time, id uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(at.UTC().Format(time.RFC3339Nano) + "|" + id.String()))
+}
+
+func decodeHistoryCursor(s string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	ts, idStr, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	at, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	return at, id, nil
+}
+
+// escapeLike makes % and _ in user input match literally (ESCAPE '!' works in every dialect).
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
@@ -0,0 +1,78 @@
+package player
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"radiokpowka/backend/db"
+)
+
+func TestHistoryCursorPages(t *testing.T) {
+	c := newTestController(t, nil)
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	// two rows share a start time: the id breaks the tie, so none is lost between pages
+	for i, title := range []string{"a", "b", "c", "d", "e"} {
+		at := base.Add(time.Duration(min(i, 3)) * time.Minute)
+		row := db.PlayHistory{ID: uuid.New(), Title: title, SourceURL: "http://tracks.test/" + title, StartedAt: at}
+		if err := c.db.Create(&row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var seen []string
+	cursor := ""
+	for range 5 {
+		page, err := c.History(HistoryQuery{Cursor: cursor, Limit: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range page.Items {
+			seen = append(seen, e.Title)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	slices.Sort(seen)
+	if !slices.Equal(seen, []string{"a", "b", "c", "d", "e"}) {
+		t.Fatalf("paged titles = %v, want each row once", seen)
+	}
+
+	page, err := c.History(HistoryQuery{From: base.Add(time.Minute), To: base.Add(3 * time.Minute), Search: "B"})
+	if err != nil || len(page.Items) != 1 || page.Items[0].Title != "b" {
+		t.Fatalf("filtered page = %+v, %v", page, err)
+	}
+	if _, err := c.History(HistoryQuery{Cursor: "not a cursor"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("bad cursor: err = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestHistoryClosedAtQueueEnd(t *testing.T) {
+	c := newTestController(t, nil)
+	request(t, c, "alice", "a")
+	request(t, c, "alice", "b")
+	for range 2 {
+		if err := c.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var rows []db.PlayHistory
+	if err := c.db.Order("started_at asc").Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, h := range rows {
+		got = append(got, fmt.Sprintf("%s ended=%v skipped=%v", h.Title, h.EndedAt != nil, h.Skipped))
+	}
+	want := []string{"a ended=true skipped=true", "b ended=true skipped=true"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("history = %v, want %v", got, want)
+	}
+}
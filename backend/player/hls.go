@@ -0,0 +1,99 @@
+// Purpose: HLS output (/hls/live.m3u8 + rolling .ts segments) cut from the shared PCM timeline.
+// ffmpeg writes the playlist and segments into a directory; the API serves them as files so a
+// caching reverse proxy/CDN can sit in front.
+
+package player
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const HLSPlaylistName = "live.m3u8"
+
+type HLSConfig struct {
+	Enabled    bool
+	Dir        string
+	SegmentSec int
+	WindowSize int // segments listed in the playlist
+}
+
+// HoldDuration is how long a playlist request keeps the station running without /stream listeners.
+func (h HLSConfig) HoldDuration() time.Duration {
+	d := time.Duration(h.SegmentSec*h.WindowSize*2) * time.Second
+	if d < 30*time.Second {
+		d = 30 * time.Second
+	}
+	return d
+}
+
+var hlsSegmentName = regexp.MustCompile(`^seg\d+\.ts$`)
+
+// HLSFilePath maps a requested file name to a path inside the HLS dir.
+// Only the playlist and segment names produced by the encoder are accepted.
+func (h HLSConfig) HLSFilePath(name string) (string, bool) {
+	if name != HLSPlaylistName && !hlsSegmentName.MatchString(name) {
+		return "", false
+	}
+	return filepath.Join(h.Dir, name), true
+}
+
+func NewHLSFFMPEG(ctx context.Context, ffmpegPath string, cfg HLSConfig) *exec.Cmd {
+	// ffmpeg -f s16le -ar 48000 -ac 2 -i pipe:0 -c:a aac -b:a 128k -f hls -hls_time <seg> -hls_list_size <window> ... live.m3u8
+	args := []string{
+		"-hide_banner",
+		"-loglevel", "warning",
+	}
+	args = append(args, pcmInputArgs...)
+	args = append(args,
+		"-i", "pipe:0",
+		"-c:a", "aac",
+		"-b:a", "128k",
+		"-f", "hls",
+		"-hls_time", strconv.Itoa(cfg.SegmentSec),
+		"-hls_list_size", strconv.Itoa(cfg.WindowSize),
+		// segment numbers must not repeat after a restart, or a CDN would serve stale files
+		"-hls_start_number_source", "epoch",
+		"-hls_flags", "delete_segments+omit_endlist+discont_start",
+		"-hls_segment_filename", filepath.Join(cfg.Dir, "seg%d.ts"),
+		filepath.Join(cfg.Dir, HLSPlaylistName),
+	)
+	cmd := exec.CommandContext(ctx, ffmpegPath, args...)
+	cmd.Stdout = &countWriter{w: io.Discard}
+	return cmd
+}
+
+// resetHLSDir removes output of a previous run so clients never see a stale playlist.
+// Only files the encoder produces are touched.
+func resetHLSDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), ".tmp")
+		if name == HLSPlaylistName || hlsSegmentName.MatchString(name) {
+			_ = os.Remove(filepath.Join(dir, e.Name()))
+		}
+	}
+	return nil
+}
+
+func (c *Controller) HLSConfig() HLSConfig {
+	return c.bc.hls
+}
+
+// HoldHLS keeps the station pipeline (and so the HLS encoder) running for HLS clients.
+func (c *Controller) HoldHLS() {
+	c.bc.Hold(c.bc.hls.HoldDuration())
+}
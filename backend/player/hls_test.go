@@ -0,0 +1,141 @@
+package player
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeFFmpeg is an ffmpeg stand-in: an HLS encoder writes its playlist (the last argument)
+// right away, then every encoder swallows PCM until stdin closes.
+const fakeFFmpeg = `#!/bin/sh
+for a; do last="$a"; done
+case "$last" in *.m3u8) echo "#EXTM3U" > "$last";; esac
+exec cat > /dev/null
+`
+
+// pausedStreamer keeps the pipeline on silence, so no track decoder is started.
+type pausedStreamer struct{}
+
+func (pausedStreamer) StreamSnapshot() StreamSnapshot { return StreamSnapshot{Paused: true} }
+
+func newHLSBroadcaster(t *testing.T, dir string) *Broadcaster {
+	t.Helper()
+	ffmpeg := filepath.Join(t.TempDir(), "ffmpeg")
+	if err := os.WriteFile(ffmpeg, []byte(fakeFFmpeg), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	b := NewBroadcaster(pausedStreamer{}, nil, ffmpeg, nil, HLSConfig{Enabled: true, Dir: dir, SegmentSec: 4, WindowSize: 6}, nil)
+	t.Cleanup(func() {
+		stopPipeline(b)
+		b.mu.Lock()
+		done := b.done
+		b.mu.Unlock()
+		if done != nil {
+			<-done
+		}
+	})
+	return b
+}
+
+// stopPipeline stops the pipeline as if the last HLS hold had expired.
+func stopPipeline(b *Broadcaster) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.holdUntil = time.Time{}
+	b.stopIfIdleLocked()
+}
+
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func writeFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, n := range names {
+		if err := os.WriteFile(filepath.Join(dir, n), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// waitPlaylist waits for the running encoder to write a playlist.
+func waitPlaylist(t *testing.T, dir string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if data, err := os.ReadFile(filepath.Join(dir, HLSPlaylistName)); err == nil && len(data) > 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("encoder never wrote a playlist")
+}
+
+func TestHLSDirResetOncePerPipeline(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, HLSPlaylistName, "seg1.ts", "seg2.ts.tmp", "notes.txt")
+	b := newHLSBroadcaster(t, dir)
+	b.Hold(time.Minute)
+	waitPlaylist(t, dir)
+	// only encoder output of the previous run is removed
+	if got := listDir(t, dir); !slices.Equal(got, []string{HLSPlaylistName, "notes.txt"}) {
+		t.Fatalf("after pipeline start: %v", got)
+	}
+
+	// an encoder restart must not delete live segments
+	writeFiles(t, dir, "seg3.ts")
+	encoders := b.newEncoders()
+	hls := encoders[len(encoders)-1]
+	if hls.name != "hls" {
+		t.Fatalf("last encoder = %s, want hls", hls.name)
+	}
+	_ = hls.newCmd(context.Background())
+	if got := listDir(t, dir); !slices.Equal(got, []string{HLSPlaylistName, "notes.txt", "seg3.ts"}) {
+		t.Fatalf("after encoder restart: %v", got)
+	}
+}
+
+func TestHLSRestartDropsStalePlaylist(t *testing.T) {
+	dir := t.TempDir()
+	b := newHLSBroadcaster(t, dir)
+	b.Hold(time.Minute)
+	waitPlaylist(t, dir)
+	stopPipeline(b)
+
+	// the request that restarts the pipeline must wait for a fresh playlist, not get the stopped run's one
+	playlist := filepath.Join(dir, HLSPlaylistName)
+	if err := os.WriteFile(playlist, []byte("stale"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	b.Hold(time.Minute)
+	if data, err := os.ReadFile(playlist); err == nil && string(data) == "stale" {
+		t.Fatal("stale playlist still there after restart")
+	}
+	stopPipeline(b)
+
+	// quick stop/start cycles: a cancelled run must not clear the files of the one after it
+	for range 3 {
+		b.Hold(time.Minute)
+		stopPipeline(b)
+	}
+	b.Hold(time.Minute)
+	waitPlaylist(t, dir)
+	writeFiles(t, dir, "seg9.ts")
+	time.Sleep(200 * time.Millisecond)
+	if got := listDir(t, dir); !slices.Contains(got, "seg9.ts") || !slices.Contains(got, HLSPlaylistName) {
+		t.Fatalf("live run's files were removed: %v", got)
+	}
+}
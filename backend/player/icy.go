@@ -0,0 +1,89 @@
+// Purpose: ICY (Shoutcast) in-band metadata for /stream clients that send "Icy-MetaData: 1".
+// Every metaint audio bytes a metadata block is inserted: a length byte (in 16-byte units)
+// followed by StreamTitle='...'; padded with zeros. An unchanged title is sent as an empty block.
+
+package player
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// IcyMetaInt is the audio byte interval between metadata blocks (Shoutcast default).
+const IcyMetaInt = 16000
+
+const icyMaxMetaLen = 255 * 16
+
+type icyWriter struct {
+	w        io.Writer
+	metaInt  int
+	left     int
+	title    func() string
+	lastSent string
+}
+
+func newIcyWriter(w io.Writer, metaInt int, title func() string) *icyWriter {
+	return &icyWriter{w: w, metaInt: metaInt, left: metaInt, title: title}
+}
+
+func (iw *icyWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := len(p)
+		if n > iw.left {
+			n = iw.left
+		}
+		if _, err := iw.w.Write(p[:n]); err != nil {
+			return written, err
+		}
+		written += n
+		p = p[n:]
+		iw.left -= n
+
+		if iw.left == 0 {
+			if _, err := iw.w.Write(iw.metaBlock()); err != nil {
+				return written, err
+			}
+			iw.left = iw.metaInt
+		}
+	}
+	return written, nil
+}
+
+func (iw *icyWriter) metaBlock() []byte {
+	title := iw.title()
+	if title == iw.lastSent {
+		return []byte{0}
+	}
+	iw.lastSent = title
+
+	meta := "StreamTitle='" + title + "';"
+	if len(meta) > icyMaxMetaLen {
+		meta = meta[:icyMaxMetaLen]
+	}
+	blocks := (len(meta) + 15) / 16
+	out := make([]byte, 1+blocks*16)
+	out[0] = byte(blocks)
+	copy(out[1:], meta)
+	return out
+}
+
+// StreamTitle builds the ICY title for the current track ("Title (nick)").
+func (c *Controller) StreamTitle() string {
+	st := c.State()
+	if st.Current == nil {
+		return ""
+	}
+	title := st.Current.Title
+	if st.Current.AddedByNick != "" {
+		title = fmt.Sprintf("%s (%s)", title, st.Current.AddedByNick)
+	}
+	// the protocol has no escaping for the closing quote
+	title = strings.ReplaceAll(title, "'", "’")
+	// keep room for StreamTitle='';
+	if len(title) > icyMaxMetaLen-16 {
+		title = strings.ToValidUTF8(title[:icyMaxMetaLen-16], "")
+	}
+	return title
+}
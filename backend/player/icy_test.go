@@ -0,0 +1,64 @@
+package player
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestIcyWriterInsertsMetadata(t *testing.T) {
+	var out bytes.Buffer
+	title := "Song"
+	iw := newIcyWriter(&out, 4, func() string { return title })
+
+	// writes that straddle the interval are split around the block
+	if n, err := iw.Write([]byte("abcdef")); err != nil || n != 6 {
+		t.Fatalf("write = %d, %v", n, err)
+	}
+	meta := "StreamTitle='Song';"
+	block := append([]byte{2}, meta...)
+	block = append(block, make([]byte, 32-len(meta))...)
+	want := append([]byte("abcd"), block...)
+	want = append(want, "ef"...)
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Fatalf("got %q\nwant %q", out.Bytes(), want)
+	}
+
+	// unchanged title: an empty block
+	out.Reset()
+	_, _ = iw.Write([]byte("gh"))
+	if !bytes.Equal(out.Bytes(), []byte("gh\x00")) {
+		t.Fatalf("got %q, want an empty block", out.Bytes())
+	}
+
+	// a new title is sent again
+	out.Reset()
+	title = "Next"
+	_, _ = iw.Write([]byte("ijkl"))
+	if got := out.Bytes(); len(got) != 4+1+32 || !bytes.Contains(got, []byte("StreamTitle='Next';")) {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestIcyWriterCapsLongTitle(t *testing.T) {
+	var out bytes.Buffer
+	long := strings.Repeat("x", 5000)
+	iw := newIcyWriter(&out, 1, func() string { return long })
+	_, _ = iw.Write([]byte("a"))
+	got := out.Bytes()
+	if got[1] != 255 || len(got) != 1+1+icyMaxMetaLen {
+		t.Fatalf("block length byte %d, size %d", got[1], len(got))
+	}
+}
+
+func TestStreamTitle(t *testing.T) {
+	c := newTestController(t, nil)
+	if got := c.StreamTitle(); got != "" {
+		t.Fatalf("idle title = %q", got)
+	}
+
+	request(t, c, "alice", "don't stop")
+	if got := c.StreamTitle(); got != "don’t stop (alice)" {
+		t.Fatalf("title = %q", got)
+	}
+}
@@ -0,0 +1,198 @@
+// Purpose: EBU R128 loudness analysis and normalization.
+// - Each added track is measured once in the background (ffmpeg loudnorm, first pass).
+// - Measurements are stored in Track.MetadataJSON under "loudness".
+// - At playback the decoder applies loudnorm with the measured values (linear, second pass);
+//   tracks not measured yet fall back to single-pass dynamic loudnorm.
+
+package player
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+type LoudnessConfig struct {
+	Enabled   bool
+	TargetI   float64 // integrated loudness, LUFS
+	TargetTP  float64 // true peak, dBTP
+	TargetLRA float64 // loudness range, LU
+}
+
+// Loudness is the loudnorm first-pass measurement of a track.
+type Loudness struct {
+	InputI       float64 `json:"input_i"`
+	InputTP      float64 `json:"input_tp"`
+	InputLRA     float64 `json:"input_lra"`
+	InputThresh  float64 `json:"input_thresh"`
+	TargetOffset float64 `json:"target_offset"`
+}
+
+const loudnessQueueSize = 256
+
+type loudnessJob struct {
+	trackID uuid.UUID
+	url     string
+}
+
+// filter builds the decoder audio filter for a track ("" = no normalization).
+func (lc LoudnessConfig) filter(l *Loudness) string {
+	if !lc.Enabled {
+		return ""
+	}
+	base := fmt.Sprintf("loudnorm=I=%s:TP=%s:LRA=%s", ff(lc.TargetI), ff(lc.TargetTP), ff(lc.TargetLRA))
+	if l == nil {
+		return base
+	}
+	return fmt.Sprintf("%s:measured_I=%s:measured_TP=%s:measured_LRA=%s:measured_thresh=%s:offset=%s:linear=true",
+		base, ff(l.InputI), ff(l.InputTP), ff(l.InputLRA), ff(l.InputThresh), ff(l.TargetOffset))
+}
+
+func ff(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// AnalyzeLoudness runs the loudnorm measurement pass over the whole input.
+func AnalyzeLoudness(ctx context.Context, ffmpegPath, input string, lc LoudnessConfig) (Loudness, error) {
+	// ffmpeg -i <input> -vn -af loudnorm=...:print_format=json -f null -
+	cmd := exec.CommandContext(ctx, ffmpegPath,
+		"-hide_banner",
+		"-nostats",
+		"-i", input,
+		"-vn",
+		"-af", lc.filter(nil)+":print_format=json",
+		"-f", "null",
+		"-",
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return Loudness{}, fmt.Errorf("loudnorm: %w", err)
+	}
+	return parseLoudnorm(stderr.Bytes())
+}
+
+// parseLoudnorm extracts the JSON block loudnorm prints at the end of stderr.
+func parseLoudnorm(out []byte) (Loudness, error) {
+	start := bytes.LastIndexByte(out, '{')
+	end := bytes.LastIndexByte(out, '}')
+	if start < 0 || end < start {
+		return Loudness{}, errors.New("loudnorm: no measurement in output")
+	}
+
+	// loudnorm prints numbers as strings
+	var raw map[string]string
+	if err := json.Unmarshal(out[start:end+1], &raw); err != nil {
+		return Loudness{}, err
+	}
+	num := func(k string) (float64, error) {
+		// silent input is reported as "-inf", which loudnorm refuses as a measured value
+		v, err := strconv.ParseFloat(raw[k], 64)
+		if err != nil || math.IsInf(v, 0) || math.IsNaN(v) {
+			return 0, fmt.Errorf("loudnorm: bad %s=%q", k, raw[k])
+		}
+		return v, nil
+	}
+
+	var l Loudness
+	var err error
+	if l.InputI, err = num("input_i"); err != nil {
+		return Loudness{}, err
+	}
+	if l.InputTP, err = num("input_tp"); err != nil {
+		return Loudness{}, err
+	}
+	if l.InputLRA, err = num("input_lra"); err != nil {
+		return Loudness{}, err
+	}
+	if l.InputThresh, err = num("input_thresh"); err != nil {
+		return Loudness{}, err
+	}
+	if l.TargetOffset, err = num("target_offset"); err != nil {
+		return Loudness{}, err
+	}
+	return l, nil
+}
+
+// trackLoudness reads the stored measurement from track metadata.
+func trackLoudness(meta datatypes.JSON) *Loudness {
+	var m struct {
+		Loudness *Loudness `json:"loudness"`
+	}
+	if len(meta) == 0 || json.Unmarshal(meta, &m) != nil {
+		return nil
+	}
+	return m.Loudness
+}
+
+// queueLoudness schedules background analysis; silently skipped when the queue is full.
+func (c *Controller) queueLoudness(trackID uuid.UUID, url string) {
+	if !c.loudness.Enabled {
+		return
+	}
+	select {
+	case c.loudnessJobs <- loudnessJob{trackID: trackID, url: url}:
+	default:
+		log.Printf("громкость: очередь анализа переполнена, пропускаем %s", url)
+	}
+}
+
+// queueMissingLoudness schedules analysis for upcoming tracks that were never measured (e.g. after a restart).
+func (c *Controller) queueMissingLoudness() {
+	if !c.loudness.Enabled {
+		return
+	}
+	tracks, err := upcomingTracks(c.db)
+	if err != nil {
+		return
+	}
+	for _, t := range tracks {
+		if trackLoudness(t.MetadataJSON) == nil {
+			c.queueLoudness(t.ID, t.SourceURL)
+		}
+	}
+}
+
+func (c *Controller) loudnessWorker() {
+	for job := range c.loudnessJobs {
+		c.analyzeTrack(job)
+	}
+}
+
+func (c *Controller) analyzeTrack(job loudnessJob) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	input, err := resolveAudioInput(ctx, c.sources, c.cache, job.url, "")
+	if err != nil {
+		log.Printf("громкость: ошибка получения direct URL: %v", err)
+		return
+	}
+	l, err := AnalyzeLoudness(ctx, c.ffmpeg, input, c.loudness)
+	if err != nil {
+		log.Printf("громкость: анализ не удался url=%s: %v", job.url, err)
+		return
+	}
+	if err := saveTrackLoudness(c.db, job.trackID, l); err != nil {
+		log.Printf("громкость: не удалось сохранить: %v", err)
+		return
+	}
+	log.Printf("громкость: url=%s I=%.1f LUFS TP=%.1f dBTP", job.url, l.InputI, l.InputTP)
+
+	c.mu.Lock()
+	if c.rt.currentTrackID == job.trackID.String() {
+		// takes effect the next time the decoder starts (resume, seek)
+		c.rt.currentLoudness = &l
+	}
+	c.mu.Unlock()
+}
@@ -0,0 +1,98 @@
+package player
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"radiokpowka/backend/db"
+)
+
+const loudnormOutput = `[Parsed_loudnorm_0 @ 0x55d0c8a4e8c0] 
+{
+	"input_i" : "-9.41",
+	"input_tp" : "0.35",
+	"input_lra" : "4.20",
+	"input_thresh" : "-19.55",
+	"output_i" : "-14.02",
+	"output_tp" : "-1.00",
+	"output_lra" : "3.90",
+	"output_thresh" : "-24.10",
+	"normalization_type" : "dynamic",
+	"target_offset" : "0.02"
+}
+`
+
+func TestParseLoudnorm(t *testing.T) {
+	l, err := parseLoudnorm([]byte("Input #0, matroska,webm, from 'x': {metadata}\n" + loudnormOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Loudness{InputI: -9.41, InputTP: 0.35, InputLRA: 4.2, InputThresh: -19.55, TargetOffset: 0.02}
+	if l != want {
+		t.Fatalf("got %+v, want %+v", l, want)
+	}
+
+	if _, err := parseLoudnorm([]byte("no json here")); err == nil {
+		t.Fatal("expected an error without a measurement")
+	}
+	if _, err := parseLoudnorm([]byte(strings.Replace(loudnormOutput, `"-9.41"`, `"-inf"`, 1))); err == nil {
+		t.Fatal("expected an error for a non-numeric value")
+	}
+}
+
+func TestLoudnessFilter(t *testing.T) {
+	lc := LoudnessConfig{Enabled: true, TargetI: -14, TargetTP: -1, TargetLRA: 11}
+	if got := lc.filter(nil); got != "loudnorm=I=-14.00:TP=-1.00:LRA=11.00" {
+		t.Fatalf("dynamic filter = %q", got)
+	}
+	got := lc.filter(&Loudness{InputI: -9.41, InputTP: 0.35, InputLRA: 4.2, InputThresh: -19.55, TargetOffset: 0.02})
+	want := "loudnorm=I=-14.00:TP=-1.00:LRA=11.00:measured_I=-9.41:measured_TP=0.35:measured_LRA=4.20:measured_thresh=-19.55:offset=0.02:linear=true"
+	if got != want {
+		t.Fatalf("linear filter = %q", got)
+	}
+	if (LoudnessConfig{}).filter(&Loudness{}) != "" {
+		t.Fatal("disabled normalization must not add a filter")
+	}
+}
+
+func TestStoredLoudnessReachesDecoder(t *testing.T) {
+	c := newTestController(t, func(d *ControllerDeps) {
+		d.Loudness = LoudnessConfig{Enabled: true, TargetI: -14, TargetTP: -1, TargetLRA: 11}
+	})
+	request(t, c, "alice", "a")
+	request(t, c, "alice", "b")
+
+	var b db.Track
+	if err := c.db.Where("title = ?", "b").First(&b).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := c.db.Model(&b).Update("metadata_json", `{"channel":"x"}`).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := saveTrackLoudness(c.db, b.ID, Loudness{InputI: -9.5, TargetOffset: 0.1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the measurement is merged into the existing metadata
+	if err := c.db.First(&b, "id = ?", b.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	var meta map[string]any
+	if err := json.Unmarshal(b.MetadataJSON, &meta); err != nil || meta["channel"] != "x" {
+		t.Fatalf("metadata = %s", b.MetadataJSON)
+	}
+	if l := trackLoudness(b.MetadataJSON); l == nil || l.InputI != -9.5 {
+		t.Fatalf("stored loudness = %+v", l)
+	}
+
+	if f := c.StreamSnapshot().Filter; strings.Contains(f, "measured_I") {
+		t.Fatalf("unmeasured track got %q", f)
+	}
+	if err := c.NextWithoutFade(); err != nil {
+		t.Fatal(err)
+	}
+	if f := c.StreamSnapshot().Filter; !strings.Contains(f, "measured_I=-9.50") || !strings.Contains(f, "linear=true") {
+		t.Fatalf("measured track got %q", f)
+	}
+}
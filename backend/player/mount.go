@@ -0,0 +1,250 @@
+// Purpose: Stream mounts (/stream.mp3, /stream.ogg, /stream.aac) with their own codec and bitrate.
+// Every mount has its own encoder fed from the shared PCM timeline and its own listeners.
+// Ogg needs its stream headers in front of any page, so ogg output is fanned out page by page
+// and the header pages are replayed to every new listener.
+
+package player
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log"
+	"strconv"
+)
+
+const (
+	CodecMP3  = "mp3"
+	CodecOpus = "opus"
+	CodecAAC  = "aac"
+)
+
+type Mount struct {
+	Codec   string // mp3|opus|aac
+	Bitrate int    // kbps
+}
+
+func (m Mount) Key() string {
+	return m.Codec + ":" + strconv.Itoa(m.Bitrate)
+}
+
+func (m Mount) ContentType() string {
+	switch m.Codec {
+	case CodecOpus:
+		return "audio/ogg"
+	case CodecAAC:
+		return "audio/aac"
+	default:
+		return "audio/mpeg"
+	}
+}
+
+// SupportsICY reports whether in-band ICY metadata can be interleaved (not for ogg).
+func (m Mount) SupportsICY() bool {
+	return m.Codec != CodecOpus
+}
+
+func (m Mount) codecArgs() []string {
+	br := strconv.Itoa(m.Bitrate) + "k"
+	switch m.Codec {
+	case CodecOpus:
+		return []string{"-c:a", "libopus", "-b:a", br, "-f", "ogg"}
+	case CodecAAC:
+		return []string{"-c:a", "aac", "-b:a", br, "-f", "adts"}
+	default:
+		return []string{"-acodec", "libmp3lame", "-b:a", br, "-f", "mp3"}
+	}
+}
+
+// FindMount picks a mount by codec ("" = default mount) and bitrate (0 = first of that codec).
+func (b *Broadcaster) FindMount(codec string, bitrate int) (Mount, bool) {
+	for _, m := range b.mounts {
+		if codec != "" && m.Codec != codec {
+			continue
+		}
+		if bitrate != 0 && m.Bitrate != bitrate {
+			continue
+		}
+		return m.Mount, true
+	}
+	return Mount{}, false
+}
+
+func (b *Broadcaster) mountByKey(key string) *mount {
+	for _, m := range b.mounts {
+		if m.Key() == key {
+			return m
+		}
+	}
+	return nil
+}
+
+type mount struct {
+	Mount
+	b *Broadcaster
+
+	// guarded by b.mu
+	subs       map[*Subscriber]struct{}
+	burst      []byte
+	header     []byte // ogg stream headers, sent to every new listener first
+	headerDone bool
+	pager      *oggPager // nil for self-synchronizing formats (mp3, adts)
+}
+
+func newMount(b *Broadcaster, m Mount) *mount {
+	mt := &mount{Mount: m, b: b, subs: map[*Subscriber]struct{}{}}
+	if m.Codec == CodecOpus {
+		mt.pager = &oggPager{}
+	}
+	return mt
+}
+
+// Write fans encoded audio out to the mount's listeners. Called from the encoder stdout copier.
+func (m *mount) Write(p []byte) (int, error) {
+	m.b.mu.Lock()
+	defer m.b.mu.Unlock()
+
+	if m.pager == nil {
+		m.distributeLocked(p, true)
+		return len(p), nil
+	}
+	for _, page := range m.pager.push(p) {
+		switch {
+		case page.bos():
+			// encoder (re)started: a new logical stream begins
+			m.header = append([]byte(nil), page...)
+			m.headerDone = false
+			m.burst = nil
+			m.distributeLocked(page, false)
+		case !m.headerDone && page.granule() == 0:
+			m.header = append(m.header, page...)
+			m.distributeLocked(page, false)
+		default:
+			m.headerDone = true
+			m.distributeLocked(page, true)
+		}
+	}
+	return len(p), nil
+}
+
+func (m *mount) distributeLocked(p []byte, keep bool) {
+	if keep {
+		m.burst = append(m.burst, p...)
+		if over := len(m.burst) - burstBytes; over > 0 {
+			cut := over
+			if m.pager != nil {
+				cut = oggPageBoundary(m.burst, over)
+			}
+			m.burst = append(m.burst[:0], m.burst[cut:]...)
+		}
+	}
+
+	for s := range m.subs {
+		if !s.rb.write(p) {
+			log.Printf("стрим: слушатель %s не успевает, отключаем", m.Key())
+			m.b.removeLocked(s, ErrSubscriberDropped)
+		}
+	}
+}
+
+// subscribeLocked attaches a listener and primes it with headers and the recent burst.
+func (m *mount) subscribeLocked() *Subscriber {
+	s := &Subscriber{rb: newRingBuffer(subscriberBufferBytes), m: m}
+	m.subs[s] = struct{}{}
+	s.rb.write(m.header)
+	s.rb.write(m.burst)
+	return s
+}
+
+// resetLocked drops output of a stopped pipeline.
+func (m *mount) resetLocked() {
+	m.burst = nil
+	m.header = nil
+	m.headerDone = false
+	if m.pager != nil {
+		m.pager = &oggPager{}
+	}
+}
+
+// oggPage is one complete Ogg page.
+type oggPage []byte
+
+func (p oggPage) bos() bool {
+	return len(p) > 5 && p[5]&0x02 != 0
+}
+
+func (p oggPage) granule() uint64 {
+	if len(p) < 14 {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(p[6:14])
+}
+
+var oggCapture = []byte("OggS")
+
+// oggPager splits a byte stream into Ogg pages.
+type oggPager struct {
+	buf []byte
+}
+
+func (op *oggPager) push(p []byte) []oggPage {
+	op.buf = append(op.buf, p...)
+	var pages []oggPage
+	for {
+		if !bytes.HasPrefix(op.buf, oggCapture) {
+			// resync on the next capture pattern
+			idx := bytes.Index(op.buf, oggCapture)
+			if idx < 0 {
+				if len(op.buf) > len(oggCapture) {
+					op.buf = op.buf[len(op.buf)-len(oggCapture):]
+				}
+				break
+			}
+			op.buf = op.buf[idx:]
+		}
+		n := oggPageLen(op.buf)
+		if n == 0 {
+			break
+		}
+		page := make(oggPage, n)
+		copy(page, op.buf[:n])
+		pages = append(pages, page)
+		op.buf = op.buf[n:]
+	}
+	if len(op.buf) == 0 {
+		op.buf = nil
+	}
+	return pages
+}
+
+// oggPageBoundary returns the first page start at or after off within buf.
+func oggPageBoundary(buf []byte, off int) int {
+	pos := 0
+	for pos < off {
+		n := oggPageLen(buf[pos:])
+		if n == 0 {
+			return len(buf)
+		}
+		pos += n
+	}
+	return pos
+}
+
+// oggPageLen returns the length of the complete page at the start of b, or 0.
+func oggPageLen(b []byte) int {
+	const headerLen = 27
+	if len(b) < headerLen || !bytes.HasPrefix(b, oggCapture) {
+		return 0
+	}
+	segs := int(b[26])
+	if len(b) < headerLen+segs {
+		return 0
+	}
+	n := headerLen + segs
+	for _, l := range b[headerLen : headerLen+segs] {
+		n += int(l)
+	}
+	if len(b) < n {
+		return 0
+	}
+	return n
+}
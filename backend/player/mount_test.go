@@ -0,0 +1,115 @@
+package player
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// oggTestPage builds a minimal Ogg page with one payload segment.
+func oggTestPage(bos bool, granule uint64, payload string) []byte {
+	p := make([]byte, 27, 28+len(payload))
+	copy(p, "OggS")
+	if bos {
+		p[5] = 0x02
+	}
+	binary.LittleEndian.PutUint64(p[6:14], granule)
+	p[26] = 1
+	p = append(p, byte(len(payload)))
+	return append(p, payload...)
+}
+
+func TestOggPagerSplitsPages(t *testing.T) {
+	a := oggTestPage(true, 0, "head")
+	b := oggTestPage(false, 960, "audio")
+	stream := append(append([]byte("junk"), a...), b...)
+
+	var op oggPager
+	var pages []oggPage
+	// feed byte by byte: pages only come out once complete
+	for i := range stream {
+		pages = append(pages, op.push(stream[i:i+1])...)
+	}
+	if len(pages) != 2 {
+		t.Fatalf("pages = %d, want 2", len(pages))
+	}
+	if !bytes.Equal(pages[0], a) || !pages[0].bos() {
+		t.Fatal("first page must be the BOS page")
+	}
+	if !bytes.Equal(pages[1], b) || pages[1].granule() != 960 {
+		t.Fatal("second page mismatch")
+	}
+	if op.buf != nil {
+		t.Fatalf("pager kept %d bytes", len(op.buf))
+	}
+}
+
+func TestOggMountReplaysHeaders(t *testing.T) {
+	b := NewBroadcaster(nil, nil, "ffmpeg", nil, HLSConfig{}, []Mount{{Codec: CodecOpus, Bitrate: 96}})
+	m := b.mounts[0]
+
+	head := append(oggTestPage(true, 0, "OpusHead"), oggTestPage(false, 0, "OpusTags")...)
+	audio1 := oggTestPage(false, 960, "one")
+	audio2 := oggTestPage(false, 1920, "two")
+	_, _ = m.Write(head)
+	_, _ = m.Write(audio1)
+	_, _ = m.Write(audio2)
+
+	// a late listener gets the headers first, then the burst, never half a page
+	late := subscribe(b, m)
+	want := append(append(append([]byte{}, head...), audio1...), audio2...)
+	if got := drain(t, late, len(want)); !bytes.Equal(got, want) {
+		t.Fatalf("late listener got %q", got)
+	}
+
+	// an encoder restart begins a new logical stream with fresh headers
+	head2 := oggTestPage(true, 0, "OpusHead2")
+	_, _ = m.Write(head2)
+	if !bytes.Equal(m.header, head2) || m.burst != nil {
+		t.Fatal("BOS page must replace the headers and drop the burst")
+	}
+}
+
+func TestOggPageBoundary(t *testing.T) {
+	a := oggTestPage(false, 1, "aaaa")
+	b := oggTestPage(false, 2, "bb")
+	buf := append(append([]byte{}, a...), b...)
+	if got := oggPageBoundary(buf, 1); got != len(a) {
+		t.Fatalf("boundary = %d, want %d", got, len(a))
+	}
+	if got := oggPageBoundary(buf, 0); got != 0 {
+		t.Fatalf("boundary = %d, want 0", got)
+	}
+	if got := oggPageBoundary(buf[:len(a)+3], len(a)+1); got != len(a)+3 {
+		t.Fatalf("truncated page boundary = %d", got)
+	}
+}
+
+func TestFindMount(t *testing.T) {
+	b := NewBroadcaster(nil, nil, "ffmpeg", nil, HLSConfig{}, []Mount{
+		{Codec: CodecMP3, Bitrate: 192},
+		{Codec: CodecMP3, Bitrate: 128},
+		{Codec: CodecOpus, Bitrate: 96},
+	})
+	cases := []struct {
+		codec   string
+		bitrate int
+		want    Mount
+		ok      bool
+	}{
+		{"", 0, Mount{CodecMP3, 192}, true},
+		{CodecMP3, 128, Mount{CodecMP3, 128}, true},
+		{CodecOpus, 0, Mount{CodecOpus, 96}, true},
+		{CodecAAC, 0, Mount{}, false},
+		{CodecOpus, 192, Mount{}, false},
+	}
+	for _, tc := range cases {
+		got, ok := b.FindMount(tc.codec, tc.bitrate)
+		if ok != tc.ok || got != tc.want {
+			t.Errorf("FindMount(%q, %d) = %v, %v", tc.codec, tc.bitrate, got, ok)
+		}
+	}
+	if (Mount{Codec: CodecOpus}).SupportsICY() || !(Mount{Codec: CodecAAC}).SupportsICY() {
+		t.Fatal("ICY is only for self-synchronizing formats")
+	}
+}
@@ -0,0 +1,97 @@
+// Purpose: Saved playback state. The runtime is written to the player_runtimes row on every
+// state change (and periodically while playing, to keep the position fresh) and restored on
+// boot, so a restart resumes the same entry at the same position, paused or playing.
+
+package player
+
+import (
+	"log"
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	"radiokpowka/backend/db"
+)
+
+const (
+	runtimeRowID = 1
+	// how often the position is saved while playing
+	runtimeCheckpointEvery = 5 * time.Second
+)
+
+// persistRuntimeLocked saves the runtime. Needs at least the read lock.
+func (c *Controller) persistRuntimeLocked() {
+	row := db.PlayerRuntime{
+		ID:           runtimeRowID,
+		QueueEntryID: c.rt.currentQueueID,
+		IsPlaying:    c.rt.isPlaying,
+		IsPaused:     c.rt.isPaused,
+		Volume:       c.rt.volume,
+		PositionSec:  c.positionLocked(),
+		Shuffle:      c.rt.shuffle,
+		ShuffleSeed:  c.rt.shuffleSeed,
+		Repeat:       c.rt.repeat,
+		FairQueue:    c.rt.fairQueue,
+		UpdatedAt:    time.Now().UTC(),
+	}
+	if !c.rt.repeatSince.IsZero() {
+		since := c.rt.repeatSince
+		row.RepeatSince = &since
+	}
+	err := c.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		UpdateAll: true,
+	}).Create(&row).Error
+	if err != nil {
+		log.Printf("плеер: не удалось сохранить состояние: %v", err)
+	}
+	c.checkpointHistoryLocked()
+}
+
+// restoreRuntime loads the saved state on boot. Reports whether there was one.
+func (c *Controller) restoreRuntime() bool {
+	var saved db.PlayerRuntime
+	if err := c.db.Where("id = ?", runtimeRowID).Limit(1).Find(&saved).Error; err != nil || saved.ID == 0 {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.rt.volume = saved.Volume
+	c.rt.shuffle = saved.Shuffle
+	c.rt.shuffleSeed = saved.ShuffleSeed
+	c.rt.fairQueue = saved.FairQueue
+	if saved.Repeat != "" {
+		c.rt.repeat = saved.Repeat
+	}
+	if saved.RepeatSince != nil {
+		c.rt.repeatSince = *saved.RepeatSince
+	} else if c.rt.repeat == RepeatAll {
+		// saved before runs were tracked: start one now instead of replaying all history
+		c.rt.repeatSince = c.repeatRunStartLocked()
+	}
+
+	if err := c.refreshCurrentFromDBLocked(); err != nil {
+		return false
+	}
+	if c.rt.currentQueueID == "" || c.rt.currentQueueID != saved.QueueEntryID {
+		// the saved entry is gone: keep the mode settings, start the current entry fresh
+		return false
+	}
+
+	pos := saved.PositionSec
+	if c.rt.durationSec > 0 && pos >= c.rt.durationSec {
+		pos = c.rt.durationSec - 1
+	}
+	c.rt.basePosSec = max(pos, 0)
+	c.rt.isPlaying = saved.IsPlaying
+	c.rt.isPaused = saved.IsPaused
+	if c.rt.isPlaying && !c.rt.isPaused {
+		c.rt.startedAt = time.Now().UTC()
+	}
+	c.rt.seekSeq++
+	c.reloadStreamLocked()
+	log.Printf("плеер: состояние восстановлено pos=%d paused=%v", c.rt.basePosSec, c.rt.isPaused)
+	return true
+}
@@ -0,0 +1,93 @@
+package player
+
+import (
+	"math"
+	"testing"
+
+	"radiokpowka/backend/db"
+)
+
+// restart builds a second controller on the same database, as after a deploy.
+func restart(t *testing.T, c *Controller) *Controller {
+	t.Helper()
+	return newTestController(t, func(d *ControllerDeps) { d.DB = c.db })
+}
+
+func TestRestartResumesPausedTrack(t *testing.T) {
+	c := newTestController(t, nil)
+	request(t, c, "alice", "a")
+	request(t, c, "alice", "b")
+	c.SetVolume(0.3)
+	seed := int64(11)
+	c.SetShuffle(true, &seed)
+	c.SetFairQueue(true)
+	if err := c.SetRepeat(RepeatOne); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Seek(50, false); err != nil {
+		t.Fatal(err)
+	}
+
+	r := restart(t, c)
+	st := r.State()
+	if currentTitle(r) != "a" || st.PositionSec != 50 || !st.IsPaused || math.Abs(st.Volume-0.3) > 1e-9 {
+		t.Fatalf("restored %q at %d paused=%v volume=%v", currentTitle(r), st.PositionSec, st.IsPaused, st.Volume)
+	}
+	if !st.Shuffle || st.ShuffleSeed != 11 || !st.FairQueue || st.Repeat != RepeatOne {
+		t.Fatalf("modes not restored: %+v", st)
+	}
+	if snap := r.StreamSnapshot(); snap.PosSec != 50 || !snap.Paused {
+		t.Fatalf("stream resumes at %d paused=%v", snap.PosSec, snap.Paused)
+	}
+}
+
+func TestRestartKeepsPlaying(t *testing.T) {
+	c := newTestController(t, nil)
+	request(t, c, "alice", "a?dur=300")
+	if err := c.Seek(120, false); err != nil {
+		t.Fatal(err)
+	}
+
+	r := restart(t, c)
+	st := r.State()
+	if st.IsPaused || !st.IsPlaying {
+		t.Fatalf("restored paused=%v playing=%v, want playing", st.IsPaused, st.IsPlaying)
+	}
+	if st.PositionSec < 120 || st.PositionSec > 125 {
+		t.Fatalf("position = %d, want ~120", st.PositionSec)
+	}
+}
+
+func TestRestartWithoutSavedEntry(t *testing.T) {
+	c := newTestController(t, nil)
+	qa := request(t, c, "alice", "a")
+	request(t, c, "alice", "b")
+	c.SetVolume(0.5)
+	if err := c.Seek(60, false); err != nil {
+		t.Fatal(err)
+	}
+	// the saved entry disappears while the server is down
+	if err := c.db.Where("id = ?", qa).Delete(&db.QueueEntry{}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	r := restart(t, c)
+	st := r.State()
+	if currentTitle(r) != "b" || st.PositionSec != 0 {
+		t.Fatalf("current %q at %d, want b from the start", currentTitle(r), st.PositionSec)
+	}
+	if math.Abs(st.Volume-0.5) > 1e-9 {
+		t.Fatalf("volume = %v, want the saved 0.5", st.Volume)
+	}
+}
+
+func TestFirstBootDefaults(t *testing.T) {
+	c := newTestController(t, nil)
+	st := c.State()
+	if math.Abs(st.Volume-0.8) > 1e-9 || st.Repeat != RepeatOff || st.Shuffle {
+		t.Fatalf("defaults = %+v", st)
+	}
+}
@@ -0,0 +1,151 @@
+// Purpose: Shuffle and repeat modes (fair queue ordering lives in fairqueue.go).
+// - Shuffle reorders upcoming user entries by a seeded hash; donation and autopilot entries
+//   keep their slots. The same seed and queue give the same order.
+// - The entry picked by shuffle (or fair queue) is moved right after the current one, so played entries
+//   stay in play order and Prev works as usual.
+// - Repeat-one replays the current track on auto-advance; repeat-all requeues the entries played
+//   since it was switched on (the run) when the queue runs out. Autopilot entries are not part of
+//   the run: with nothing to repeat, autopilot refills the queue as usual.
+
+package player
+
+import (
+	"errors"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strconv"
+	"time"
+
+	"radiokpowka/backend/db"
+)
+
+const (
+	RepeatOff = "off"
+	RepeatOne = "one"
+	RepeatAll = "all"
+)
+
+var ErrInvalidRepeat = errors.New("repeat must be off, one or all")
+
+// playMode is what queue selection needs from the runtime.
+type playMode struct {
+	shuffle   bool
+	seed      int64
+	repeatAll bool
+	since     time.Time // start of the repeat-all run
+	fair      bool
+}
+
+// reorders reports whether upcoming entries play in a different order than queued.
+func (m playMode) reorders() bool {
+	return m.shuffle || m.fair
+}
+
+func (c *Controller) playModeLocked() playMode {
+	return playMode{
+		shuffle:   c.rt.shuffle,
+		seed:      c.rt.shuffleSeed,
+		repeatAll: c.rt.repeat == RepeatAll,
+		since:     c.rt.repeatSince,
+		fair:      c.rt.fairQueue,
+	}
+}
+
+// playOrder returns the play order (indexes into ids) of upcoming entries given in position
+// order: shuffled first, then interleaved by requester in fair mode.
+func playOrder(ids, keys []string, fixed []bool, mode playMode, served []string) []int {
+	order := make([]int, len(ids))
+	for i := range order {
+		order[i] = i
+	}
+	if mode.shuffle {
+		order = shuffleOrder(ids, fixed, mode.seed)
+	}
+	if mode.fair {
+		// fixed entries keep their slots under shuffle, so fixed applies to the shuffled order too
+		shuffled := make([]string, len(order))
+		for i, j := range order {
+			shuffled[i] = keys[j]
+		}
+		fair := fairOrder(shuffled, fixed, served)
+		out := make([]int, len(fair))
+		for i, j := range fair {
+			out[i] = order[j]
+		}
+		order = out
+	}
+	return order
+}
+
+// shuffleOrder returns the play order (indexes into ids) of upcoming entries given in
+// position order. Fixed entries (donations, autopilot) stay where they are.
+func shuffleOrder(ids []string, fixed []bool, seed int64) []int {
+	order := make([]int, len(ids))
+	var slots, free []int
+	for i := range ids {
+		order[i] = i
+		if !fixed[i] {
+			slots = append(slots, i)
+			free = append(free, i)
+		}
+	}
+
+	key := func(i int) uint64 {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(strconv.FormatInt(seed, 10)))
+		_, _ = h.Write([]byte(ids[i]))
+		return h.Sum64()
+	}
+	sort.SliceStable(free, func(a, b int) bool { return key(free[a]) < key(free[b]) })
+	for k, slot := range slots {
+		order[slot] = free[k]
+	}
+	return order
+}
+
+// SetShuffle turns shuffle on or off. A nil seed picks a new random one when turning on.
+func (c *Controller) SetShuffle(on bool, seed *int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.rt.shuffle = on
+	switch {
+	case seed != nil:
+		c.rt.shuffleSeed = *seed
+	case on:
+		c.rt.shuffleSeed = rand.Int63()
+	}
+	c.schedulePrefetchLocked()
+	c.broadcastQueueLocked()
+	c.broadcastStateLocked()
+}
+
+func (c *Controller) SetRepeat(mode string) error {
+	switch mode {
+	case RepeatOff, RepeatOne, RepeatAll:
+	default:
+		return ErrInvalidRepeat
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if mode == RepeatAll && c.rt.repeat != RepeatAll {
+		c.rt.repeatSince = c.repeatRunStartLocked()
+	}
+	c.rt.repeat = mode
+	c.schedulePrefetchLocked()
+	c.broadcastStateLocked()
+	return nil
+}
+
+// repeatRunStartLocked returns where a new repeat-all run starts: with the current entry,
+// or now when nothing is playing.
+func (c *Controller) repeatRunStartLocked() time.Time {
+	var cur db.QueueEntry
+	err := c.db.Where("status = ?", "current").Limit(1).Find(&cur).Error
+	if err == nil && cur.PlayedAt != nil {
+		return *cur.PlayedAt
+	}
+	return time.Now().UTC()
+}
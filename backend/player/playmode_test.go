@@ -0,0 +1,208 @@
+package player
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRepeatAllReplaysOnlyTheRun(t *testing.T) {
+	c := newTestController(t, nil)
+	request(t, c, "alice", "old")
+	request(t, c, "alice", "a")
+	request(t, c, "bob", "b")
+	if err := c.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if got := currentTitle(c); got != "a" {
+		t.Fatalf("current = %q, want a", got)
+	}
+
+	if err := c.SetRepeat(RepeatAll); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Next(); err != nil {
+		t.Fatal(err)
+	}
+	// "old" played before repeat was switched on and stays in history
+	if got := currentTitle(c); got != "a" {
+		t.Fatalf("current after wrap = %q, want a", got)
+	}
+	if got := queueTitles(t, c); !slices.Equal(got, []string{"b"}) {
+		t.Fatalf("upcoming = %v, want [b]", got)
+	}
+
+	// switching repeat off and on again starts a new run at the current entry
+	_ = c.SetRepeat(RepeatOff)
+	_ = c.SetRepeat(RepeatAll)
+	request(t, c, "carol", "c")
+	for range 3 {
+		if err := c.Next(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := currentTitle(c); got != "a" {
+		t.Fatalf("current after second wrap = %q, want a", got)
+	}
+	if got := queueTitles(t, c); !slices.Equal(got, []string{"b", "c"}) {
+		t.Fatalf("upcoming = %v, want [b c]", got)
+	}
+}
+
+func TestRepeatAllFallsBackToAutopilot(t *testing.T) {
+	c := newTestController(t, nil)
+	request(t, c, "alice", "old")
+	if err := c.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if got := currentTitle(c); got != "" {
+		t.Fatalf("current = %q, want the queue to have ended", got)
+	}
+
+	// nothing to repeat: autopilot fills the queue instead of leaving it silent
+	if err := c.SetRepeat(RepeatAll); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.AddFallbackPlaylist("http://tracks.test/list/x,y", "fb", AutopilotSequential); err != nil {
+		t.Fatal(err)
+	}
+	if got := currentTitle(c); got != "x" {
+		t.Fatalf("current = %q, want autopilot x", got)
+	}
+	// autopilot entries are not part of the run, so the next one comes from autopilot too
+	if err := c.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if got := currentTitle(c); got != "y" {
+		t.Fatalf("current = %q, want autopilot y", got)
+	}
+}
+
+func TestShuffleOrderKeepsFixedSlots(t *testing.T) {
+	ids := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	fixed := []bool{false, false, true, false, false, false, true, false}
+
+	order := shuffleOrder(ids, fixed, 42)
+	if order[2] != 2 || order[6] != 6 {
+		t.Fatalf("fixed entries moved: %v", order)
+	}
+	sorted := slices.Clone(order)
+	slices.Sort(sorted)
+	if !slices.Equal(sorted, []int{0, 1, 2, 3, 4, 5, 6, 7}) {
+		t.Fatalf("not a permutation: %v", order)
+	}
+
+	// the seed makes the order reproducible
+	if again := shuffleOrder(ids, fixed, 42); !slices.Equal(again, order) {
+		t.Fatalf("same seed gave %v and %v", order, again)
+	}
+	differs := false
+	for seed := range int64(5) {
+		if !slices.Equal(shuffleOrder(ids, fixed, seed), order) {
+			differs = true
+		}
+	}
+	if !differs {
+		t.Fatal("different seeds never changed the order")
+	}
+}
+
+func TestShufflePlaysInAnnouncedOrder(t *testing.T) {
+	c := newTestController(t, nil)
+	request(t, c, "alice", "a")
+	for _, name := range []string{"b", "c", "d", "e", "f"} {
+		request(t, c, "alice", name)
+	}
+	seed := int64(7)
+	c.SetShuffle(true, &seed)
+	if !c.State().Shuffle {
+		t.Fatal("shuffle not reported in player state")
+	}
+
+	// the upcoming list shows what will actually play
+	want := queueTitles(t, c)
+	var played []string
+	for range want {
+		if err := c.Next(); err != nil {
+			t.Fatal(err)
+		}
+		played = append(played, currentTitle(c))
+	}
+	if !slices.Equal(played, want) {
+		t.Fatalf("played %v, announced %v", played, want)
+	}
+
+	// played entries stay in play order, so Prev goes back through them
+	if err := c.Prev(); err != nil {
+		t.Fatal(err)
+	}
+	if got := currentTitle(c); got != want[len(want)-2] {
+		t.Fatalf("prev = %q, want %q", got, want[len(want)-2])
+	}
+}
+
+func TestShuffleOffRestoresQueueOrder(t *testing.T) {
+	c := newTestController(t, nil)
+	request(t, c, "alice", "a")
+	for _, name := range []string{"b", "c", "d", "e"} {
+		request(t, c, "alice", name)
+	}
+	seed := int64(3)
+	c.SetShuffle(true, &seed)
+	c.SetShuffle(false, nil)
+	if got := queueTitles(t, c); !slices.Equal(got, []string{"b", "c", "d", "e"}) {
+		t.Fatalf("upcoming = %v", got)
+	}
+}
+
+func TestRepeatOne(t *testing.T) {
+	c := newTestController(t, nil)
+	qa := request(t, c, "alice", "a")
+	request(t, c, "alice", "b")
+	if err := c.SetRepeat(RepeatOne); err != nil {
+		t.Fatal(err)
+	}
+	if c.State().Repeat != RepeatOne {
+		t.Fatalf("repeat = %q", c.State().Repeat)
+	}
+
+	// the end of the track replays it
+	c.autoAdvance(qa, 0)
+	if got := currentTitle(c); got != "a" {
+		t.Fatalf("current after auto-advance = %q, want a", got)
+	}
+	// a manual skip still moves on
+	if err := c.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if got := currentTitle(c); got != "b" {
+		t.Fatalf("current after skip = %q, want b", got)
+	}
+
+	if err := c.SetRepeat("forever"); err != ErrInvalidRepeat {
+		t.Fatalf("invalid repeat = %v", err)
+	}
+}
+
+func TestShuffleRemovingCurrentPlaysAnnouncedNext(t *testing.T) {
+	c := newTestController(t, nil)
+	cur := request(t, c, "alice", "a")
+	for _, name := range []string{"b", "c", "d", "e", "f"} {
+		request(t, c, "alice", name)
+	}
+	seed := int64(7)
+	c.SetShuffle(true, &seed)
+
+	want := queueTitles(t, c)
+	if err := c.RemoveEntry(cur); err != nil {
+		t.Fatal(err)
+	}
+	if got := currentTitle(c); got != want[0] {
+		t.Fatalf("current = %q, announced next %q", got, want[0])
+	}
+	if got := queueTitles(t, c); !slices.Equal(got, want[1:]) {
+		t.Fatalf("upcoming = %v, want %v", got, want[1:])
+	}
+}
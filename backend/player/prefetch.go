@@ -0,0 +1,139 @@
+// Purpose: Background prefetch of the upcoming track.
+// - While the current track plays, the first "next" entry is resolved ahead of time
+//   (yt-dlp direct URL; the local cache download is queued as well).
+// - On transition the resolved input goes to the decoder, so the stream does not wait for yt-dlp.
+// - Prefetch status per entry is reported in QueueEntryDTO.Prefetch.
+
+package player
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const (
+	PrefetchPending = "pending"
+	PrefetchReady   = "ready"
+	PrefetchCached  = "cached"
+	PrefetchFailed  = "failed"
+)
+
+// Direct URLs expire (YouTube: ~6h); older ones are resolved again.
+const prefetchTTL = time.Hour
+
+type prefetchEntry struct {
+	queueID string
+	url     string
+	cancel  context.CancelFunc
+
+	// guarded by Controller.pfMu
+	status     string
+	input      string
+	resolvedAt time.Time
+}
+
+func (pe *prefetchEntry) fresh() bool {
+	return pe.status == PrefetchReady && time.Since(pe.resolvedAt) < prefetchTTL
+}
+
+// schedulePrefetchLocked starts resolving the first "next" entry unless it is already in progress.
+func (c *Controller) schedulePrefetchLocked() {
+	q, t, err := firstNext(c.db, c.playModeLocked())
+
+	c.pfMu.Lock()
+	defer c.pfMu.Unlock()
+	if err != nil {
+		// nothing upcoming
+		c.dropPrefetchLocked()
+		return
+	}
+	qid := q.ID.String()
+	if pe := c.pf; pe != nil && pe.queueID == qid && (pe.status == PrefetchPending || pe.status == PrefetchCached || pe.fresh()) {
+		return
+	}
+
+	c.dropPrefetchLocked()
+	ctx, cancel := context.WithCancel(context.Background())
+	pe := &prefetchEntry{queueID: qid, url: t.SourceURL, cancel: cancel, status: PrefetchPending}
+	c.pf = pe
+	go c.runPrefetch(ctx, pe)
+}
+
+func (c *Controller) dropPrefetchLocked() {
+	if c.pf != nil {
+		c.pf.cancel()
+		c.pf = nil
+	}
+}
+
+func (c *Controller) runPrefetch(ctx context.Context, pe *prefetchEntry) {
+	defer pe.cancel()
+
+	status, input := PrefetchCached, ""
+	if !c.cache.Has(pe.url) {
+		c.cache.Prefetch(pe.url)
+		u, err := c.sources.Input(ctx, pe.url)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("предзагрузка: ошибка получения direct URL url=%s: %v", pe.url, err)
+			status = PrefetchFailed
+		} else {
+			status, input = PrefetchReady, u
+		}
+	}
+
+	c.pfMu.Lock()
+	if c.pf != pe {
+		// superseded by a queue change
+		c.pfMu.Unlock()
+		return
+	}
+	pe.status = status
+	pe.input = input
+	pe.resolvedAt = time.Now()
+	c.pfMu.Unlock()
+
+	c.broadcastQueue()
+}
+
+// takePrefetch hands the prefetched input of a queue entry over to playback ("" if none).
+func (c *Controller) takePrefetch(qid string) (string, time.Time) {
+	c.pfMu.Lock()
+	defer c.pfMu.Unlock()
+	pe := c.pf
+	if pe == nil || pe.queueID != qid {
+		return "", time.Time{}
+	}
+	c.pf = nil
+	if !pe.fresh() {
+		pe.cancel()
+		return "", time.Time{}
+	}
+	return pe.input, pe.resolvedAt
+}
+
+// annotatePrefetch fills QueueEntryDTO.Prefetch for upcoming entries.
+func (c *Controller) annotatePrefetch(items []QueueEntryDTO) {
+	c.pfMu.Lock()
+	var qid, status string
+	if c.pf != nil {
+		qid, status = c.pf.queueID, c.pf.status
+	}
+	c.pfMu.Unlock()
+
+	for i := range items {
+		it := &items[i]
+		if it.Status == "prev" {
+			continue
+		}
+		switch {
+		case c.cache.Has(it.URL):
+			it.Prefetch = PrefetchCached
+		case it.ID == qid:
+			it.Prefetch = status
+		}
+	}
+}
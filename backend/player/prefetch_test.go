@@ -0,0 +1,100 @@
+package player
+
+import (
+	"testing"
+	"time"
+)
+
+// waitPrefetch polls the queue window until the entry titled title reports status.
+func waitPrefetch(t *testing.T, c *Controller, title, status string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		w, err := c.QueueWindow()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range w.Items {
+			if e.Title == title && e.Prefetch == status {
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s never reached prefetch status %q", title, status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPrefetchHandsInputToPlayback(t *testing.T) {
+	c := newTestController(t, nil)
+	request(t, c, "alice", "a")
+	request(t, c, "alice", "b")
+	waitPrefetch(t, c, "b", PrefetchReady)
+
+	if err := c.NextWithoutFade(); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.StreamSnapshot().Input; got != "http://tracks.test/b" {
+		t.Fatalf("decoder input = %q, want the prefetched URL", got)
+	}
+
+	// the prefetch is consumed: the current entry carries no status
+	w, _ := c.QueueWindow()
+	for _, e := range w.Items {
+		if e.Status == "current" && e.Prefetch != "" {
+			t.Fatalf("current entry prefetch = %q", e.Prefetch)
+		}
+	}
+}
+
+func TestPrefetchFailureFallsBackToResolve(t *testing.T) {
+	c := newTestController(t, nil)
+	request(t, c, "alice", "a")
+	request(t, c, "alice", "b?fail=1")
+	waitPrefetch(t, c, "b", PrefetchFailed)
+
+	if err := c.NextWithoutFade(); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.StreamSnapshot().Input; got != "" {
+		t.Fatalf("decoder input = %q, want the decoder to resolve itself", got)
+	}
+}
+
+func TestPrefetchFollowsQueueOrder(t *testing.T) {
+	c := newTestController(t, nil)
+	request(t, c, "alice", "a")
+	request(t, c, "alice", "b")
+	eid := request(t, c, "alice", "e")
+	waitPrefetch(t, c, "b", PrefetchReady)
+
+	// moving another entry to the front retargets the prefetch
+	if err := c.MoveEntry(eid, 1); err != nil {
+		t.Fatal(err)
+	}
+	waitPrefetch(t, c, "e", PrefetchReady)
+	w, _ := c.QueueWindow()
+	for _, e := range w.Items {
+		if e.Title == "b" && e.Prefetch != "" {
+			t.Fatalf("b still reports %q", e.Prefetch)
+		}
+	}
+}
+
+func TestTakePrefetchIgnoresStaleInput(t *testing.T) {
+	c := newTestController(t, nil)
+	c.pf = &prefetchEntry{queueID: "q", cancel: func() {}, status: PrefetchReady, input: "old",
+		resolvedAt: time.Now().Add(-2 * prefetchTTL)}
+	if input, _ := c.takePrefetch("q"); input != "" {
+		t.Fatalf("expired direct URL %q handed to playback", input)
+	}
+	if c.pf != nil {
+		t.Fatal("taken prefetch must be cleared")
+	}
+
+	c.pf = &prefetchEntry{queueID: "q", cancel: func() {}, status: PrefetchReady, input: "new", resolvedAt: time.Now()}
+	if input, _ := c.takePrefetch("other"); input != "" || c.pf == nil {
+		t.Fatal("prefetch of another entry must be kept")
+	}
+}
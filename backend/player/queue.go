@@ -0,0 +1,82 @@
+// Purpose: Owner queue editing (delete, move, bulk clear).
+// Every change runs in one transaction, then the runtime follows the new current entry
+// and a queue_update goes out.
+
+package player
+
+import (
+	"github.com/google/uuid"
+)
+
+const (
+	ClearUpcoming = "upcoming"
+	ClearPlayed   = "played"
+)
+
+// RemoveEntry deletes a queue entry. Removing the current entry switches to the next one.
+func (c *Controller) RemoveEntry(id string) error {
+	qid, err := uuid.Parse(id)
+	if err != nil {
+		return ErrEntryNotFound
+	}
+	return c.editQueue(func() error {
+		tx := c.db.Begin()
+		defer func() { _ = tx.Rollback() }()
+		if err := deleteQueueEntry(tx, qid, c.playModeLocked()); err != nil {
+			return err
+		}
+		return tx.Commit().Error
+	})
+}
+
+// MoveEntry moves an upcoming entry to pos (1 = plays next).
+func (c *Controller) MoveEntry(id string, pos int) error {
+	qid, err := uuid.Parse(id)
+	if err != nil {
+		return ErrEntryNotFound
+	}
+	return c.editQueue(func() error {
+		tx := c.db.Begin()
+		defer func() { _ = tx.Rollback() }()
+		if err := moveQueueEntry(tx, qid, pos); err != nil {
+			return err
+		}
+		return tx.Commit().Error
+	})
+}
+
+// ClearQueue removes all upcoming or all played entries and returns how many were removed.
+func (c *Controller) ClearQueue(scope string) (int64, error) {
+	status := "next"
+	if scope == ClearPlayed {
+		status = "prev"
+	}
+	var n int64
+	err := c.editQueue(func() error {
+		tx := c.db.Begin()
+		defer func() { _ = tx.Rollback() }()
+		var err error
+		if n, err = clearQueue(tx, status); err != nil {
+			return err
+		}
+		return tx.Commit().Error
+	})
+	return n, err
+}
+
+func (c *Controller) editQueue(fn func() error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := fn(); err != nil {
+		return err
+	}
+	c.ensureAutopilotLocked()
+	if err := c.refreshCurrentFromDBLocked(); err != nil {
+		return err
+	}
+	c.schedulePrefetchLocked()
+	c.broadcastQueueLocked()
+	c.broadcastStateLocked()
+	return nil
+}
@@ -0,0 +1,167 @@
+package player
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"radiokpowka/backend/db"
+)
+
+// checkPositions fails unless queue positions are 1..N, as every edit must leave them.
+func checkPositions(t *testing.T, c *Controller) {
+	t.Helper()
+	var pos []int
+	if err := c.db.Model(&db.QueueEntry{}).Order("position asc").Pluck("position", &pos).Error; err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range pos {
+		if p != i+1 {
+			t.Fatalf("positions %v are not contiguous", pos)
+		}
+	}
+}
+
+func TestMoveEntry(t *testing.T) {
+	c := newTestController(t, nil)
+	cur := request(t, c, "alice", "a")
+	request(t, c, "alice", "b")
+	request(t, c, "alice", "c")
+	d := request(t, c, "alice", "d")
+
+	steps := []struct {
+		id   string
+		pos  int
+		want []string
+	}{
+		{d, 1, []string{"d", "b", "c"}},
+		{d, 2, []string{"b", "d", "c"}},
+		{d, 99, []string{"b", "c", "d"}}, // past the end: last
+	}
+	for _, s := range steps {
+		if err := c.MoveEntry(s.id, s.pos); err != nil {
+			t.Fatal(err)
+		}
+		if got := queueTitles(t, c); !reflect.DeepEqual(got, s.want) {
+			t.Fatalf("move to %d: %v, want %v", s.pos, got, s.want)
+		}
+		checkPositions(t, c)
+	}
+
+	if err := c.MoveEntry(cur, 1); !errors.Is(err, ErrEntryNotUpcoming) {
+		t.Fatalf("moving the current entry = %v", err)
+	}
+	if err := c.MoveEntry(uuid.NewString(), 1); !errors.Is(err, ErrEntryNotFound) {
+		t.Fatalf("moving a missing entry = %v", err)
+	}
+	if err := c.MoveEntry("garbage", 1); !errors.Is(err, ErrEntryNotFound) {
+		t.Fatalf("moving a malformed id = %v", err)
+	}
+}
+
+func TestRemoveEntry(t *testing.T) {
+	c := newTestController(t, nil)
+	cur := request(t, c, "alice", "a")
+	b := request(t, c, "alice", "b")
+	request(t, c, "alice", "c")
+	request(t, c, "alice", "d")
+
+	if err := c.RemoveEntry(b); err != nil {
+		t.Fatal(err)
+	}
+	if got := queueTitles(t, c); !reflect.DeepEqual(got, []string{"c", "d"}) {
+		t.Fatalf("queue = %v", got)
+	}
+	checkPositions(t, c)
+
+	// removing the playing entry switches to the next one
+	if err := c.RemoveEntry(cur); err != nil {
+		t.Fatal(err)
+	}
+	if got := currentTitle(c); got != "c" {
+		t.Fatalf("current = %q, want c", got)
+	}
+	if got := queueTitles(t, c); !reflect.DeepEqual(got, []string{"d"}) {
+		t.Fatalf("queue = %v", got)
+	}
+	checkPositions(t, c)
+
+	if err := c.RemoveEntry(b); !errors.Is(err, ErrEntryNotFound) {
+		t.Fatalf("removing twice = %v", err)
+	}
+}
+
+func TestClearQueue(t *testing.T) {
+	c := newTestController(t, nil)
+	request(t, c, "alice", "a")
+	request(t, c, "alice", "b")
+	request(t, c, "alice", "c")
+	request(t, c, "alice", "d")
+	if err := c.NextWithoutFade(); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := c.ClearQueue(ClearPlayed)
+	if err != nil || n != 1 {
+		t.Fatalf("clear played = %d, %v", n, err)
+	}
+	checkPositions(t, c)
+
+	n, err = c.ClearQueue(ClearUpcoming)
+	if err != nil || n != 2 {
+		t.Fatalf("clear upcoming = %d, %v", n, err)
+	}
+	if got := currentTitle(c); got != "b" {
+		t.Fatalf("current = %q, want b to keep playing", got)
+	}
+	if got := queueTitles(t, c); len(got) != 0 {
+		t.Fatalf("queue = %v", got)
+	}
+	checkPositions(t, c)
+}
+
+func TestQueueLibraryTrack(t *testing.T) {
+	c := newTestController(t, nil)
+	cat := db.Track{
+		ID:           uuid.New(),
+		Title:        "Local",
+		Artist:       "Band",
+		SourceURL:    "file:///music/local.mp3",
+		Provider:     "local",
+		DurationSec:  180,
+		Library:      true,
+		MetadataJSON: []byte(`{}`),
+	}
+	if err := c.db.Create(&cat).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.AddTrack(AddRequest{LibraryTrackID: &cat.ID, Nick: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := currentTitle(c); got != "Local" {
+		t.Fatalf("current = %q", got)
+	}
+	// the queue plays a copy: the catalog row stays untouched
+	var q db.QueueEntry
+	if err := c.db.Where("status = ?", "current").First(&q).Error; err != nil {
+		t.Fatal(err)
+	}
+	if q.TrackID == cat.ID {
+		t.Fatal("queue entry points at the catalog row")
+	}
+	var copied db.Track
+	if err := c.db.First(&copied, "id = ?", q.TrackID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if copied.Library || copied.SourceURL != cat.SourceURL || copied.AddedByNick != "alice" {
+		t.Fatalf("queued copy = %+v", copied)
+	}
+
+	missing := uuid.New()
+	if _, err := c.AddTrack(AddRequest{LibraryTrackID: &missing, Nick: "alice"}); !errors.Is(err, ErrTrackNotFound) {
+		t.Fatalf("unknown library track = %v", err)
+	}
+}
@@ -0,0 +1,262 @@
+// Purpose: Windowed queue listing and diff-based queue_update events.
+// - The queue window is the last N played entries, the current one and the next M in play
+//   order. Older played and further upcoming entries are paged with cursors.
+// - Every change of the window is published as a diff against the previous window
+//   (inserted / removed / moved / updated) with a version number; a client whose version
+//   does not match baseVersion refetches GET /api/playlist.
+
+package player
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"radiokpowka/backend/websocket"
+)
+
+const queuePageMaxLimit = 200
+
+type QueueWindowConfig struct {
+	Prev int // played entries in the window
+	Next int // upcoming entries in the window
+}
+
+// QueueWindow is an ordered slice of the queue: played entries (oldest first), the current
+// entry and upcoming entries in play order. Cursors are set while there is more to page.
+type QueueWindow struct {
+	Version    uint64          `json:"version"`
+	Items      []QueueEntryDTO `json:"items"`
+	PrevCursor string          `json:"prevCursor,omitempty"` // GET /api/playlist?before=
+	NextCursor string          `json:"nextCursor,omitempty"` // GET /api/playlist?after=
+}
+
+// QueueDiff turns the window of BaseVersion into the window of Version. Apply it as: drop
+// removed, replace updated, put inserted and moved entries at their index in the new window
+// and fill the remaining slots with the other entries in their previous order.
+type QueueDiff struct {
+	Version     uint64           `json:"version"`
+	BaseVersion uint64           `json:"baseVersion"`
+	Inserted    []QueueDiffEntry `json:"inserted,omitempty"`
+	Removed     []string         `json:"removed,omitempty"`
+	Moved       []QueueDiffMove  `json:"moved,omitempty"`
+	Updated     []QueueEntryDTO  `json:"updated,omitempty"` // status or other fields changed
+	PrevCursor  string           `json:"prevCursor,omitempty"`
+	NextCursor  string           `json:"nextCursor,omitempty"`
+}
+
+type QueueDiffEntry struct {
+	Index int           `json:"index"`
+	Entry QueueEntryDTO `json:"entry"`
+}
+
+type QueueDiffMove struct {
+	ID    string `json:"id"`
+	Index int    `json:"index"`
+}
+
+// QueueWindow returns the current window; its version matches the queue_update events.
+func (c *Controller) QueueWindow() (QueueWindow, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.publishQueueWindowLocked()
+}
+
+// QueuePageBefore pages played entries older than the cursor (oldest first).
+func (c *Controller) QueuePageBefore(cursor string, limit int) (QueueWindow, error) {
+	id, err := uuid.Parse(cursor)
+	if err != nil {
+		return QueueWindow{}, ErrInvalidCursor
+	}
+	limit = c.pageLimit(limit, c.window.Prev)
+	pos, err := entryPosition(c.db, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return QueueWindow{}, ErrInvalidCursor
+	}
+	if err != nil {
+		return QueueWindow{}, err
+	}
+	items, err := playedEntries(c.db, pos, limit+1)
+	if err != nil {
+		return QueueWindow{}, err
+	}
+
+	var page QueueWindow
+	if len(items) > limit {
+		items = items[:limit]
+		page.PrevCursor = items[limit-1].ID
+	}
+	slices.Reverse(items)
+	page.Items = items
+	return page, nil
+}
+
+// QueuePageAfter pages upcoming entries after the cursor, in play order.
+func (c *Controller) QueuePageAfter(cursor string, limit int) (QueueWindow, error) {
+	limit = c.pageLimit(limit, c.window.Next)
+
+	c.mu.RLock()
+	upcoming, err := upcomingEntries(c.db, c.playModeLocked(), 0)
+	c.mu.RUnlock()
+	if err != nil {
+		return QueueWindow{}, err
+	}
+	idx := slices.IndexFunc(upcoming, func(e QueueEntryDTO) bool { return e.ID == cursor })
+	if idx < 0 {
+		// the entry started playing or was removed: the client refetches the window
+		return QueueWindow{}, ErrInvalidCursor
+	}
+
+	var page QueueWindow
+	items := upcoming[idx+1:]
+	if len(items) > limit {
+		items = items[:limit]
+		page.NextCursor = items[limit-1].ID
+	}
+	c.annotatePrefetch(items)
+	page.Items = items
+	return page, nil
+}
+
+func (c *Controller) pageLimit(limit, def int) int {
+	if limit <= 0 {
+		limit = def
+	}
+	return min(max(limit, 1), queuePageMaxLimit)
+}
+
+// queueWindowLocked builds the window. Needs at least the read lock and queueMu.
+func (c *Controller) queueWindowLocked() (QueueWindow, error) {
+	prev, err := playedEntries(c.db, 0, c.window.Prev+1)
+	if err != nil {
+		return QueueWindow{}, err
+	}
+	cur, err := currentEntry(c.db)
+	if err != nil {
+		return QueueWindow{}, err
+	}
+	next, err := upcomingEntries(c.db, c.playModeLocked(), c.window.Next+1)
+	if err != nil {
+		return QueueWindow{}, err
+	}
+
+	var w QueueWindow
+	if len(prev) > c.window.Prev {
+		prev = prev[:c.window.Prev]
+		if len(prev) > 0 {
+			w.PrevCursor = prev[len(prev)-1].ID
+		}
+	}
+	slices.Reverse(prev)
+	if len(next) > c.window.Next {
+		next = next[:c.window.Next]
+		if len(next) > 0 {
+			w.NextCursor = next[len(next)-1].ID
+		}
+	}
+
+	w.Items = make([]QueueEntryDTO, 0, len(prev)+len(cur)+len(next))
+	w.Items = append(append(append(w.Items, prev...), cur...), next...)
+	c.annotatePrefetch(w.Items)
+	return w, nil
+}
+
+// publishQueueWindowLocked builds the window and broadcasts the diff to the last published
+// one (if anything changed). Reading and publishing happen under queueMu, so a window read
+// before a queue change can never be published after the window that includes it.
+// Needs at least the read lock.
+func (c *Controller) publishQueueWindowLocked() (QueueWindow, error) {
+	c.queueMu.Lock()
+	defer c.queueMu.Unlock()
+
+	w, err := c.queueWindowLocked()
+	if err != nil {
+		return QueueWindow{}, err
+	}
+	d := diffQueue(c.lastWindow.Items, w.Items)
+	unchanged := len(d.Inserted) == 0 && len(d.Removed) == 0 && len(d.Moved) == 0 && len(d.Updated) == 0 &&
+		w.PrevCursor == c.lastWindow.PrevCursor && w.NextCursor == c.lastWindow.NextCursor
+	if unchanged && c.queueVersion > 0 {
+		w.Version = c.queueVersion
+		return w, nil
+	}
+
+	d.BaseVersion = c.queueVersion
+	c.queueVersion++
+	d.Version = c.queueVersion
+	d.PrevCursor, d.NextCursor = w.PrevCursor, w.NextCursor
+	c.lastWindow = w
+	c.hub.Broadcast(websocket.Event{Type: websocket.EventQueueUpdate, Data: d})
+	w.Version = c.queueVersion
+	return w, nil
+}
+
+// diffQueue computes the diff from old to cur. Entries that keep their relative order (the
+// longest such run) stay in place; the rest of the surviving entries are reported as moved.
+func diffQueue(old, cur []QueueEntryDTO) QueueDiff {
+	var d QueueDiff
+	oldIdx := make(map[string]int, len(old))
+	for i, e := range old {
+		oldIdx[e.ID] = i
+	}
+	curIDs := make(map[string]bool, len(cur))
+	for _, e := range cur {
+		curIDs[e.ID] = true
+	}
+	for _, e := range old {
+		if !curIDs[e.ID] {
+			d.Removed = append(d.Removed, e.ID)
+		}
+	}
+
+	// kept entries in their new order, by old index
+	var keptNew, keptOld []int
+	for i, e := range cur {
+		j, ok := oldIdx[e.ID]
+		if !ok {
+			d.Inserted = append(d.Inserted, QueueDiffEntry{Index: i, Entry: e})
+			continue
+		}
+		keptNew = append(keptNew, i)
+		keptOld = append(keptOld, j)
+		if old[j] != e {
+			d.Updated = append(d.Updated, e)
+		}
+	}
+	stable := longestIncreasing(keptOld)
+	for k, i := range keptNew {
+		if !stable[k] {
+			d.Moved = append(d.Moved, QueueDiffMove{ID: cur[i].ID, Index: i})
+		}
+	}
+	return d
+}
+
+// longestIncreasing marks one longest strictly increasing subsequence of a.
+func longestIncreasing(a []int) []bool {
+	in := make([]bool, len(a))
+	if len(a) == 0 {
+		return in
+	}
+	tails := []int{} // indexes into a: smallest tail of each length
+	prev := make([]int, len(a))
+	for i, v := range a {
+		n, _ := slices.BinarySearchFunc(tails, v, func(t, v int) int { return a[t] - v })
+		if n > 0 {
+			prev[i] = tails[n-1]
+		} else {
+			prev[i] = -1
+		}
+		if n == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[n] = i
+		}
+	}
+	for i := tails[len(tails)-1]; i >= 0; i = prev[i] {
+		in[i] = true
+	}
+	return in
+}
@@ -0,0 +1,221 @@
+package player
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// applyDiff applies d to old the way clients do (see QueueDiff).
+func applyDiff(old []QueueEntryDTO, d QueueDiff) []QueueEntryDTO {
+	removed := map[string]bool{}
+	for _, id := range d.Removed {
+		removed[id] = true
+	}
+	updated := map[string]QueueEntryDTO{}
+	for _, e := range d.Updated {
+		updated[e.ID] = e
+	}
+	placed := map[int]QueueEntryDTO{}
+	moved := map[string]bool{}
+	for _, ins := range d.Inserted {
+		placed[ins.Index] = ins.Entry
+	}
+	var rest []QueueEntryDTO
+	for _, e := range old {
+		if removed[e.ID] {
+			continue
+		}
+		if u, ok := updated[e.ID]; ok {
+			e = u
+		}
+		for _, m := range d.Moved {
+			if m.ID == e.ID {
+				placed[m.Index] = e
+				moved[e.ID] = true
+			}
+		}
+		if !moved[e.ID] {
+			rest = append(rest, e)
+		}
+	}
+	out := make([]QueueEntryDTO, 0, len(placed)+len(rest))
+	for i := 0; len(placed) > 0 || len(rest) > 0; i++ {
+		if e, ok := placed[i]; ok {
+			out = append(out, e)
+			delete(placed, i)
+			continue
+		}
+		out = append(out, rest[0])
+		rest = rest[1:]
+	}
+	return out
+}
+
+func entries(ids ...string) []QueueEntryDTO {
+	out := make([]QueueEntryDTO, len(ids))
+	for i, id := range ids {
+		out[i] = QueueEntryDTO{ID: id, Title: id, Status: "next"}
+	}
+	return out
+}
+
+func TestDiffQueue(t *testing.T) {
+	old := entries("a", "b", "c", "d")
+	cur := entries("b", "x", "d", "c")
+	cur[0].Status = "current"
+
+	d := diffQueue(old, cur)
+	if !slices.Equal(d.Removed, []string{"a"}) {
+		t.Fatalf("removed = %v, want [a]", d.Removed)
+	}
+	if len(d.Inserted) != 1 || d.Inserted[0].Index != 1 || d.Inserted[0].Entry.ID != "x" {
+		t.Fatalf("inserted = %+v, want x at 1", d.Inserted)
+	}
+	// b, c stay in order; only d is reported as moved
+	if len(d.Moved) != 1 || d.Moved[0] != (QueueDiffMove{ID: "d", Index: 2}) {
+		t.Fatalf("moved = %+v, want d to 2", d.Moved)
+	}
+	if len(d.Updated) != 1 || d.Updated[0].ID != "b" {
+		t.Fatalf("updated = %+v, want b", d.Updated)
+	}
+	if got := applyDiff(old, d); !slices.Equal(got, cur) {
+		t.Fatalf("applied = %v, want %v", got, cur)
+	}
+}
+
+func TestDiffQueueRoundTripRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	next := 0
+	old := entries()
+	for range 500 {
+		cur := slices.Clone(old)
+		rng.Shuffle(len(cur), func(i, j int) { cur[i], cur[j] = cur[j], cur[i] })
+		if len(cur) > 0 {
+			cur = cur[:rng.Intn(len(cur)+1)]
+		}
+		for range rng.Intn(4) {
+			next++
+			e := entries(fmt.Sprint("n", next))[0]
+			i := rng.Intn(len(cur) + 1)
+			cur = slices.Insert(cur, i, e)
+		}
+		if len(cur) > 0 && rng.Intn(3) == 0 {
+			cur[rng.Intn(len(cur))].RequestCount++
+		}
+
+		d := diffQueue(old, cur)
+		if got := applyDiff(old, d); !slices.Equal(got, cur) {
+			t.Fatalf("old %v -> %v: applied %v", ids(old), ids(cur), ids(got))
+		}
+		old = cur
+	}
+}
+
+func ids(es []QueueEntryDTO) []string {
+	out := make([]string, len(es))
+	for i, e := range es {
+		out[i] = e.ID
+	}
+	return out
+}
+
+func TestLongestIncreasing(t *testing.T) {
+	for _, tc := range []struct {
+		in   []int
+		want int
+	}{
+		{nil, 0},
+		{[]int{0, 1, 2}, 3},
+		{[]int{2, 1, 0}, 1},
+		{[]int{3, 0, 1, 4, 2}, 3},
+	} {
+		in := longestIncreasing(tc.in)
+		var picked []int
+		for i, ok := range in {
+			if ok {
+				picked = append(picked, tc.in[i])
+			}
+		}
+		if len(picked) != tc.want || !slices.IsSorted(picked) {
+			t.Errorf("longestIncreasing(%v) picked %v, want %d increasing", tc.in, picked, tc.want)
+		}
+	}
+}
+
+func TestQueueWindowVersions(t *testing.T) {
+	c := newTestController(t, nil)
+	request(t, c, "alice", "a")
+	request(t, c, "alice", "b")
+	w1, err := c.QueueWindow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range w1.Items {
+		if e.ID == "" {
+			t.Fatalf("entry %q has no ID", e.Title)
+		}
+	}
+	w2, _ := c.QueueWindow()
+	if w2.Version != w1.Version {
+		t.Fatalf("unchanged window: version %d -> %d", w1.Version, w2.Version)
+	}
+
+	c.queueMu.Lock()
+	last := c.lastWindow.Items
+	c.queueMu.Unlock()
+	request(t, c, "bob", "c")
+	w3, _ := c.QueueWindow()
+	if w3.Version <= w2.Version {
+		t.Fatalf("changed window kept version %d", w3.Version)
+	}
+	if got := applyDiff(last, diffQueue(last, w3.Items)); !slices.Equal(ids(got), ids(w3.Items)) {
+		t.Fatalf("applied %v, want %v", ids(got), ids(w3.Items))
+	}
+}
+
+func TestQueueWindowPaging(t *testing.T) {
+	for _, shuffle := range []bool{false, true} {
+		c := newTestController(t, func(d *ControllerDeps) {
+			d.QueueWindow = QueueWindowConfig{Prev: 10, Next: 2}
+		})
+		for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+			request(t, c, "alice", name)
+		}
+		if shuffle {
+			seed := int64(1)
+			c.SetShuffle(true, &seed)
+		}
+		order := queueTitles(t, c)
+		w, err := c.QueueWindow()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// window and pages together list every upcoming entry once
+		var got []string
+		cursor := w.NextCursor
+		for cursor != "" {
+			page, err := c.QueuePageAfter(cursor, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range page.Items {
+				got = append(got, e.Title)
+			}
+			cursor = page.NextCursor
+		}
+		got = append(slices.Clone(order), got...)
+		if len(order) != 2 {
+			t.Fatalf("shuffle=%v: window lists %v", shuffle, order)
+		}
+		want := []string{"b", "c", "d", "e", "f"}
+		if shuffle {
+			got = slices.Sorted(slices.Values(got))
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("shuffle=%v: window and pages list %v", shuffle, got)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Purpose: Song request quotas enforced in AddTrack.
+// - Max pending (current + upcoming) entries per requester (user ID, or nick for guests).
+// - Max tracks taken from one playlist link.
+// - Cooldown between requests of the same requester. It starts when a request passes the
+//   quota checks, before the track is resolved, so failed or slow lookups count too.
+// Donations and owner requests are exempt. A zero limit disables that check.
+
+package player
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type QuotaConfig struct {
+	MaxPending        int
+	MaxPlaylistTracks int
+	Cooldown          time.Duration
+}
+
+// Request rejection codes returned to API clients.
+const (
+	CodeQuotaExceeded   = "quota_exceeded"
+	CodePlaylistTooLong = "playlist_too_long"
+	CodeCooldown        = "cooldown"
+)
+
+// RequestError is a rejected song request with a machine-readable code.
+type RequestError struct {
+	Code       string
+	Message    string
+	RetryAfter time.Duration // set for cooldown rejections
+}
+
+func (e *RequestError) Error() string {
+	return e.Message
+}
+
+// requesterKey identifies who a request counts against.
+func requesterKey(userID *uuid.UUID, nick string) string {
+	if userID != nil {
+		return "user:" + userID.String()
+	}
+	return "nick:" + strings.ToLower(strings.TrimSpace(nick))
+}
+
+// claimCooldown rejects a request made too soon after the previous one, otherwise starts the
+// cooldown. Check and record happen under one lock, so parallel requests cannot both pass.
+func (c *Controller) claimCooldown(key string) error {
+	if c.quota.Cooldown <= 0 {
+		return nil
+	}
+	c.quotaMu.Lock()
+	defer c.quotaMu.Unlock()
+	now := time.Now()
+	if wait := c.quota.Cooldown - now.Sub(c.lastRequest[key]); wait > 0 {
+		return &RequestError{
+			Code:       CodeCooldown,
+			Message:    fmt.Sprintf("слишком часто: следующий трек можно заказать через %d с", int(wait.Seconds())+1),
+			RetryAfter: wait,
+		}
+	}
+	c.lastRequest[key] = now
+	// forget requesters whose cooldown is long over
+	for k, t := range c.lastRequest {
+		if now.Sub(t) > c.quota.Cooldown {
+			delete(c.lastRequest, k)
+		}
+	}
+	return nil
+}
+
+func (c *Controller) checkPlaylistSize(n int) error {
+	if c.quota.MaxPlaylistTracks > 0 && n > c.quota.MaxPlaylistTracks {
+		return &RequestError{
+			Code:    CodePlaylistTooLong,
+			Message: fmt.Sprintf("в плейлисте %d треков, максимум %d", n, c.quota.MaxPlaylistTracks),
+		}
+	}
+	return nil
+}
+
+// checkPending rejects a request that would push the requester over MaxPending.
+func (c *Controller) checkPending(pending int64, adding int) error {
+	if c.quota.MaxPending > 0 && int(pending)+adding > c.quota.MaxPending {
+		return &RequestError{
+			Code:    CodeQuotaExceeded,
+			Message: fmt.Sprintf("лимит заказов: в очереди уже %d из %d ваших треков", pending, c.quota.MaxPending),
+		}
+	}
+	return nil
+}
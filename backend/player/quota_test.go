@@ -0,0 +1,89 @@
+package player
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQuotaMaxPending(t *testing.T) {
+	c := newTestController(t, func(d *ControllerDeps) {
+		d.Quota = QuotaConfig{MaxPending: 2}
+	})
+	request(t, c, "alice", "a")
+	request(t, c, "Alice", "b") // nick match is case-insensitive
+	if _, err := c.AddTrack(AddRequest{URL: "http://tracks.test/c", Nick: "alice"}); requestCode(err) != CodeQuotaExceeded {
+		t.Fatalf("third request: err = %v, want %s", err, CodeQuotaExceeded)
+	}
+	// other requesters, donations and the owner are not limited by alice's quota
+	request(t, c, "bob", "d")
+	if _, err := c.AddTrack(AddRequest{URL: "http://tracks.test/e", Nick: "alice", IsDonation: true}); err != nil {
+		t.Fatalf("donation: %v", err)
+	}
+	if _, err := c.AddTrack(AddRequest{URL: "http://tracks.test/f", Nick: "alice", Privileged: true}); err != nil {
+		t.Fatalf("owner request: %v", err)
+	}
+}
+
+func TestQuotaPlaylistSize(t *testing.T) {
+	c := newTestController(t, func(d *ControllerDeps) {
+		d.Quota = QuotaConfig{MaxPlaylistTracks: 2, MaxPending: 10}
+	})
+	if _, err := c.AddTrack(AddRequest{URL: "http://tracks.test/list/a,b,c", Nick: "alice"}); requestCode(err) != CodePlaylistTooLong {
+		t.Fatalf("3-track playlist: err = %v, want %s", err, CodePlaylistTooLong)
+	}
+	if _, err := c.AddTrack(AddRequest{URL: "http://tracks.test/list/a,b", Nick: "alice"}); err != nil {
+		t.Fatalf("2-track playlist: %v", err)
+	}
+}
+
+func TestQuotaCooldown(t *testing.T) {
+	c := newTestController(t, func(d *ControllerDeps) {
+		d.Quota = QuotaConfig{Cooldown: time.Minute}
+	})
+	request(t, c, "alice", "a")
+	_, err := c.AddTrack(AddRequest{URL: "http://tracks.test/b", Nick: "alice"})
+	if requestCode(err) != CodeCooldown {
+		t.Fatalf("err = %v, want %s", err, CodeCooldown)
+	}
+	if rerr := err.(*RequestError); rerr.RetryAfter <= 0 || rerr.RetryAfter > time.Minute {
+		t.Fatalf("RetryAfter = %v", rerr.RetryAfter)
+	}
+	request(t, c, "bob", "c")
+}
+
+func TestQuotaCooldownStartsBeforeResolving(t *testing.T) {
+	c := newTestController(t, func(d *ControllerDeps) {
+		d.Quota = QuotaConfig{Cooldown: time.Minute}
+	})
+	// a lookup that finds nothing still costs a search, so it starts the cooldown
+	if _, err := c.AddTrack(AddRequest{URL: "nothing", Nick: "alice"}); requestCode(err) != CodeNoResults {
+		t.Fatalf("err = %v, want %s", err, CodeNoResults)
+	}
+	if _, err := c.AddTrack(AddRequest{URL: "http://tracks.test/a", Nick: "alice"}); requestCode(err) != CodeCooldown {
+		t.Fatalf("err = %v, want %s", err, CodeCooldown)
+	}
+}
+
+func TestQuotaCooldownParallelRequests(t *testing.T) {
+	c := newTestController(t, func(d *ControllerDeps) {
+		d.Quota = QuotaConfig{Cooldown: time.Minute}
+	})
+	var wg sync.WaitGroup
+	var ok atomic.Int32
+	for i := range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.AddTrack(AddRequest{URL: fmt.Sprintf("http://tracks.test/t%d", i), Nick: "alice"}); err == nil {
+				ok.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := ok.Load(); got != 1 {
+		t.Fatalf("accepted = %d, want 1", got)
+	}
+}
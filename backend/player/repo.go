@@ -3,16 +3,22 @@
 package player
 
 import (
+	"encoding/json"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"radiokpowka/backend/db"
+	"radiokpowka/backend/youtube"
 )
 
-func ensureQueueHasCurrent(tx *gorm.DB) error {
+// ensureQueueHasCurrent promotes the entry that plays next under mode when nothing is current.
+func ensureQueueHasCurrent(tx *gorm.DB, mode playMode) error {
 	var cur db.QueueEntry
 	err := tx.Where("status = ?", "current").First(&cur).Error
 	if err == nil {
@@ -22,56 +28,177 @@ func ensureQueueHasCurrent(tx *gorm.DB) error {
 		return err
 	}
 
-	// set first next as current if exists
-	var first db.QueueEntry
-	if err := tx.Where("status = ?", "next").Order("position asc").First(&first).Error; err != nil {
-		return nil // empty queue is OK
+	first, err := pickNext(tx, mode)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil // empty queue is OK
+		}
+		return err
 	}
-	return tx.Model(&db.QueueEntry{}).Where("id = ?", first.ID).Update("status", "current").Error
+	if mode.reorders() {
+		// keep played entries in play order
+		if err := moveQueueEntry(tx, first.ID, 1); err != nil {
+			return err
+		}
+	}
+	return setCurrent(tx, first.ID)
 }
 
-func listQueue(tx *gorm.DB) ([]QueueEntryDTO, error) {
-	// join queue_entries + tracks
+// setCurrent makes an entry the current one and records when it started playing.
+func setCurrent(tx *gorm.DB, id uuid.UUID) error {
+	return tx.Model(&db.QueueEntry{}).Where("id = ?", id).Updates(map[string]any{
+		"status":    "current",
+		"played_at": time.Now().UTC(),
+	}).Error
+}
+
+// queueEntries loads queue entries joined with their tracks plus their requester keys;
+// scope adds filters, ordering and limits.
+func queueEntries(tx *gorm.DB, scope func(*gorm.DB) *gorm.DB) ([]QueueEntryDTO, []string, error) {
 	type row struct {
-		QID         string
-		Status      string
-		Position    int
-		AddedAt     time.Time
-		IsDonation  bool
-		Title       string
-		URL         string
-		AddedByNick string
+		QID            string `gorm:"column:qid"`
+		Status         string
+		AddedAt        time.Time
+		IsDonation     bool
+		IsFallback     bool
+		DonationAmount int64
+		RequestCount   int
+		Title          string
+		URL            string
+		Provider       string
+		AddedByUserID  *uuid.UUID
+		AddedByNick    string
 	}
 	var rows []row
-	err := tx.Table("queue_entries").
-		Select("queue_entries.id as qid, queue_entries.status, queue_entries.position, queue_entries.added_at, queue_entries.is_donation, tracks.title, tracks.source_url as url, tracks.added_by_nick").
-		Joins("join tracks on tracks.id = queue_entries.track_id").
-		Order("queue_entries.position asc").
-		Scan(&rows).Error
-	if err != nil {
-		return nil, err
+	q := tx.Table("queue_entries").
+		Select("queue_entries.id as qid, queue_entries.status, queue_entries.added_at, queue_entries.is_donation, queue_entries.is_fallback, queue_entries.donation_amount, queue_entries.request_count, tracks.title, tracks.source_url as url, tracks.provider, tracks.added_by_user_id, tracks.added_by_nick").
+		Joins("join tracks on tracks.id = queue_entries.track_id")
+	if err := scope(q).Scan(&rows).Error; err != nil {
+		return nil, nil, err
 	}
 
 	out := make([]QueueEntryDTO, 0, len(rows))
+	keys := make([]string, 0, len(rows))
 	for _, r := range rows {
+		keys = append(keys, requesterKey(r.AddedByUserID, r.AddedByNick))
 		out = append(out, QueueEntryDTO{
-			ID:          r.QID,
-			Title:       r.Title,
-			URL:         r.URL,
-			AddedByNick: r.AddedByNick,
-			AddedAt:     r.AddedAt.UTC().Format(time.RFC3339),
-			Status:      r.Status,
-			IsDonation:  r.IsDonation,
+			ID:             r.QID,
+			Title:          r.Title,
+			URL:            r.URL,
+			Provider:       r.Provider,
+			AddedByNick:    r.AddedByNick,
+			AddedAt:        r.AddedAt.UTC().Format(time.RFC3339),
+			Status:         r.Status,
+			IsDonation:     r.IsDonation,
+			IsFallback:     r.IsFallback,
+			DonationAmount: r.DonationAmount,
+			RequestCount:   r.RequestCount,
 		})
 	}
-	return out, nil
+	return out, keys, nil
+}
+
+// playedEntries returns up to limit played entries before the given position (0 = latest),
+// newest first.
+func playedEntries(tx *gorm.DB, beforePos, limit int) ([]QueueEntryDTO, error) {
+	items, _, err := queueEntries(tx, func(q *gorm.DB) *gorm.DB {
+		q = q.Where("queue_entries.status = ?", "prev")
+		if beforePos > 0 {
+			q = q.Where("queue_entries.position < ?", beforePos)
+		}
+		return q.Order("queue_entries.position desc").Limit(limit)
+	})
+	return items, err
 }
 
-func addTrack(tx *gorm.DB, url, title string, duration int, addedByUser *uuid.UUID, addedByNick string) (db.Track, error) {
+func currentEntry(tx *gorm.DB) ([]QueueEntryDTO, error) {
+	items, _, err := queueEntries(tx, func(q *gorm.DB) *gorm.DB {
+		return q.Where("queue_entries.status = ?", "current").Limit(1)
+	})
+	return items, err
+}
+
+// upcomingEntries returns upcoming entries in play order (shuffle / fair queue applied); limit > 0
+// loads at most that many when the queue order is the play order.
+func upcomingEntries(tx *gorm.DB, mode playMode, limit int) ([]QueueEntryDTO, error) {
+	items, keys, err := queueEntries(tx, func(q *gorm.DB) *gorm.DB {
+		q = q.Where("queue_entries.status = ?", "next").Order("queue_entries.position asc")
+		if limit > 0 && !mode.reorders() {
+			// queue order is play order: the rest cannot move into the first limit
+			q = q.Limit(limit)
+		}
+		return q
+	})
+	if err != nil || !mode.reorders() {
+		return items, err
+	}
+	var served []string
+	if mode.fair {
+		if served, err = servedRequesters(tx); err != nil {
+			return nil, err
+		}
+	}
+	reorderUpcoming(items, keys, mode, served)
+	return items, nil
+}
+
+// entryPosition returns the position of a queue entry.
+func entryPosition(tx *gorm.DB, id uuid.UUID) (int, error) {
+	var q db.QueueEntry
+	if err := tx.Select("position").Where("id = ?", id).First(&q).Error; err != nil {
+		return 0, err
+	}
+	return q.Position, nil
+}
+
+// reorderUpcoming puts the trailing "next" entries of a position-ordered queue in play order
+// in place. keys are the requester keys of items.
+func reorderUpcoming(items []QueueEntryDTO, keys []string, mode playMode, served []string) {
+	start := len(items)
+	for start > 0 && items[start-1].Status == "next" {
+		start--
+	}
+	upcoming := items[start:]
+	ids := make([]string, len(upcoming))
+	fixed := make([]bool, len(upcoming))
+	for i, it := range upcoming {
+		ids[i], fixed[i] = it.ID, it.IsDonation || it.IsFallback
+	}
+	ordered := make([]QueueEntryDTO, len(upcoming))
+	for i, j := range playOrder(ids, keys[start:], fixed, mode, served) {
+		ordered[i] = upcoming[j]
+	}
+	copy(upcoming, ordered)
+}
+
+// upcomingTracks returns tracks of the current and next queue entries in play order.
+func upcomingTracks(tx *gorm.DB) ([]db.Track, error) {
+	var tracks []db.Track
+	err := tx.Table("tracks").
+		Joins("join queue_entries on queue_entries.track_id = tracks.id").
+		Where("queue_entries.status in ?", []string{"current", "next"}).
+		Order("queue_entries.position asc").
+		Find(&tracks).Error
+	return tracks, err
+}
+
+// libraryTrack loads a catalog row of the local library.
+func libraryTrack(tx *gorm.DB, id uuid.UUID) (db.Track, error) {
+	var t db.Track
+	err := tx.Where("id = ? AND library = ?", id, true).First(&t).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return db.Track{}, ErrTrackNotFound
+	}
+	return t, err
+}
+
+func addTrack(tx *gorm.DB, url, provider, title string, duration int, addedByUser *uuid.UUID, addedByNick string) (db.Track, error) {
 	t := db.Track{
 		ID:            uuid.New(),
 		Title:         title,
 		SourceURL:     url,
+		Provider:      provider,
+		CanonicalID:   youtube.CanonicalID(url),
 		DurationSec:   duration,
 		AddedByUserID: addedByUser,
 		AddedByNick:   addedByNick,
@@ -92,14 +219,71 @@ func maxPosition(tx *gorm.DB) (int, error) {
 	return max, nil
 }
 
-func insertQueueEntry(tx *gorm.DB, trackID uuid.UUID, pos int, status string, isDonation bool) (db.QueueEntry, error) {
+// insertQueueEntry creates q with a new ID and the current time.
+func insertQueueEntry(tx *gorm.DB, q db.QueueEntry) (db.QueueEntry, error) {
+	q.ID = uuid.New()
+	q.AddedAt = time.Now().UTC()
+	if err := tx.Create(&q).Error; err != nil {
+		return db.QueueEntry{}, err
+	}
+	return q, nil
+}
+
+// insertNextPosition returns the position right after the current entry, skipping upcoming
+// donations of at least the given priority when afterDonations is set (0 = nothing is current).
+func insertNextPosition(tx *gorm.DB, afterDonations bool, priority int) (int, error) {
+	var cur db.QueueEntry
+	err := tx.Where("status = ?", "current").First(&cur).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	pos := cur.Position + 1
+	if !afterDonations {
+		return pos, nil
+	}
+
+	var upcoming []db.QueueEntry
+	if err := tx.Where("status = ? AND position > ?", "next", cur.Position).Order("position asc").Find(&upcoming).Error; err != nil {
+		return 0, err
+	}
+	for _, q := range upcoming {
+		if !q.IsDonation || q.DonationPriority < priority {
+			break
+		}
+		pos = q.Position + 1
+	}
+	return pos, nil
+}
+
+// countPending returns current and upcoming entries requested by a user (or by nick for guests).
+func countPending(tx *gorm.DB, userID *uuid.UUID, nick string) (int64, error) {
+	q := tx.Table("queue_entries").
+		Joins("join tracks on tracks.id = queue_entries.track_id").
+		Where("queue_entries.status in ?", []string{"current", "next"}).
+		Where("queue_entries.is_donation = ? AND queue_entries.is_fallback = ?", false, false)
+	if userID != nil {
+		q = q.Where("tracks.added_by_user_id = ?", *userID)
+	} else {
+		q = q.Where("LOWER(tracks.added_by_nick) = ?", strings.ToLower(strings.TrimSpace(nick)))
+	}
+	var n int64
+	err := q.Count(&n).Error
+	return n, err
+}
+
+// insertFallbackEntry queues an autopilot track from playlistID as "next".
+func insertFallbackEntry(tx *gorm.DB, trackID, playlistID uuid.UUID, pos int) (db.QueueEntry, error) {
 	q := db.QueueEntry{
-		ID:         uuid.New(),
-		TrackID:    trackID,
-		Position:   pos,
-		Status:     status,
-		AddedAt:    time.Now().UTC(),
-		IsDonation: isDonation,
+		ID:                 uuid.New(),
+		TrackID:            trackID,
+		Position:           pos,
+		Status:             "next",
+		AddedAt:            time.Now().UTC(),
+		IsFallback:         true,
+		FallbackPlaylistID: &playlistID,
 	}
 	if err := tx.Create(&q).Error; err != nil {
 		return db.QueueEntry{}, err
@@ -107,6 +291,177 @@ func insertQueueEntry(tx *gorm.DB, trackID uuid.UUID, pos int, status string, is
 	return q, nil
 }
 
+// firstFallbackPosition returns the position of the first upcoming autopilot entry (0 = none).
+func firstFallbackPosition(tx *gorm.DB) (int, error) {
+	var pos int
+	err := tx.Model(&db.QueueEntry{}).
+		Select("COALESCE(MIN(position), 0)").
+		Where("status = ? AND is_fallback = ?", "next", true).
+		Scan(&pos).Error
+	return pos, err
+}
+
+// upcomingByCanonicalID finds a queued user request for the same track.
+func upcomingByCanonicalID(tx *gorm.DB, canonicalID string) (db.QueueEntry, error) {
+	var q db.QueueEntry
+	err := tx.Model(&db.QueueEntry{}).
+		Select("queue_entries.*").
+		Joins("join tracks on tracks.id = queue_entries.track_id").
+		Where("queue_entries.status = ? AND queue_entries.is_fallback = ?", "next", false).
+		Where("tracks.canonical_id = ?", canonicalID).
+		Order("queue_entries.position asc").
+		First(&q).Error
+	return q, err
+}
+
+// lastPlayedAt returns when the track last started playing (nil = never).
+func lastPlayedAt(tx *gorm.DB, canonicalID string) (*time.Time, error) {
+	var q db.QueueEntry
+	err := tx.Model(&db.QueueEntry{}).
+		Select("queue_entries.played_at").
+		Joins("join tracks on tracks.id = queue_entries.track_id").
+		Where("queue_entries.played_at IS NOT NULL AND tracks.canonical_id = ?", canonicalID).
+		Order("queue_entries.played_at desc").
+		First(&q).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return q.PlayedAt, nil
+}
+
+// recentSourceURLs returns source URLs of the last n played entries.
+func recentSourceURLs(tx *gorm.DB, n int) (map[string]bool, error) {
+	out := map[string]bool{}
+	if n <= 0 {
+		return out, nil
+	}
+	var urls []string
+	err := tx.Table("queue_entries").
+		Joins("join tracks on tracks.id = queue_entries.track_id").
+		Where("queue_entries.status in ?", []string{"prev", "current"}).
+		Order("queue_entries.position desc").
+		Limit(n).
+		Pluck("tracks.source_url", &urls).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range urls {
+		out[u] = true
+	}
+	return out, nil
+}
+
+func createPlaylist(tx *gorm.DB, p db.Playlist, items []db.PlaylistItem) error {
+	return tx.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&p).Error; err != nil {
+			return err
+		}
+		return tx.CreateInBatches(items, 100).Error
+	})
+}
+
+func listPlaylists(tx *gorm.DB) ([]PlaylistDTO, error) {
+	var playlists []db.Playlist
+	if err := tx.Order("created_at asc").Find(&playlists).Error; err != nil {
+		return nil, err
+	}
+	type count struct {
+		PlaylistID uuid.UUID
+		N          int
+	}
+	var counts []count
+	if err := tx.Model(&db.PlaylistItem{}).Select("playlist_id, COUNT(*) as n").Group("playlist_id").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]int, len(counts))
+	for _, c := range counts {
+		byID[c.PlaylistID] = c.N
+	}
+
+	out := make([]PlaylistDTO, 0, len(playlists))
+	for _, p := range playlists {
+		out = append(out, playlistDTO(p, byID[p.ID]))
+	}
+	return out, nil
+}
+
+func updatePlaylist(tx *gorm.DB, id uuid.UUID, mode *string, enabled *bool) error {
+	updates := map[string]any{}
+	if mode != nil {
+		updates["mode"] = *mode
+	}
+	if enabled != nil {
+		updates["enabled"] = *enabled
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	return tx.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&db.Playlist{}).Where("id = ?", id).Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrPlaylistNotFound
+		}
+		if enabled != nil && !*enabled {
+			return dropFallbackEntries(tx, id)
+		}
+		return nil
+	})
+}
+
+func deletePlaylist(tx *gorm.DB, id uuid.UUID) error {
+	return tx.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("id = ?", id).Delete(&db.Playlist{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrPlaylistNotFound
+		}
+		if err := tx.Where("playlist_id = ?", id).Delete(&db.PlaylistItem{}).Error; err != nil {
+			return err
+		}
+		return dropFallbackEntries(tx, id)
+	})
+}
+
+// dropFallbackEntries removes the upcoming autopilot entries of a playlist; one already playing finishes.
+func dropFallbackEntries(tx *gorm.DB, playlistID uuid.UUID) error {
+	res := tx.Where("status = ? AND is_fallback = ? AND fallback_playlist_id = ?", "next", true, playlistID).Delete(&db.QueueEntry{})
+	if res.Error != nil || res.RowsAffected == 0 {
+		return res.Error
+	}
+	return renumberQueue(tx, nil)
+}
+
+// fallbackTrack returns the track row of a playlist item, creating it the first time the
+// item is queued, so replays do not add a row each time.
+func fallbackTrack(tx *gorm.DB, item db.PlaylistItem, provider string) (db.Track, error) {
+	if item.TrackID != nil {
+		var t db.Track
+		err := tx.Where("id = ?", *item.TrackID).First(&t).Error
+		if err == nil {
+			return t, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return db.Track{}, err
+		}
+	}
+	t, err := addTrack(tx, item.SourceURL, provider, item.Title, item.DurationSec, nil, autopilotNick)
+	if err != nil {
+		return db.Track{}, err
+	}
+	if err := tx.Model(&db.PlaylistItem{}).Where("id = ?", item.ID).Update("track_id", t.ID).Error; err != nil {
+		return db.Track{}, err
+	}
+	return t, nil
+}
+
 func shiftPositionsFrom(tx *gorm.DB, fromPos int, delta int) error {
 	// shift all >= fromPos by delta
 	if delta <= 0 {
@@ -117,7 +472,7 @@ func shiftPositionsFrom(tx *gorm.DB, fromPos int, delta int) error {
 		Update("position", gorm.Expr("position + ?", delta)).Error
 }
 
-func nextTrack(tx *gorm.DB) (db.QueueEntry, db.Track, error) {
+func nextTrack(tx *gorm.DB, mode playMode) (db.QueueEntry, db.Track, error) {
 	var cur db.QueueEntry
 	if err := tx.Where("status = ?", "current").First(&cur).Error; err != nil {
 		return db.QueueEntry{}, db.Track{}, err
@@ -128,14 +483,26 @@ func nextTrack(tx *gorm.DB) (db.QueueEntry, db.Track, error) {
 		return db.QueueEntry{}, db.Track{}, err
 	}
 
-	// pick first next
-	var nx db.QueueEntry
-	if err := tx.Where("status = ?", "next").Order("position asc").First(&nx).Error; err != nil {
+	nx, err := pickNext(tx, mode)
+	if errors.Is(err, gorm.ErrRecordNotFound) && mode.repeatAll {
+		// queue ended: play the run again from the top
+		if err := repeatRun(tx, mode.since).Update("status", "next").Error; err != nil {
+			return db.QueueEntry{}, db.Track{}, err
+		}
+		nx, err = pickNext(tx, mode)
+	}
+	if err != nil {
 		// no next: stop playback (queue ended)
 		return db.QueueEntry{}, db.Track{}, gorm.ErrRecordNotFound
 	}
+	if mode.reorders() {
+		// keep played entries in play order
+		if err := moveQueueEntry(tx, nx.ID, 1); err != nil {
+			return db.QueueEntry{}, db.Track{}, err
+		}
+	}
 
-	if err := tx.Model(&db.QueueEntry{}).Where("id = ?", nx.ID).Update("status", "current").Error; err != nil {
+	if err := setCurrent(tx, nx.ID); err != nil {
 		return db.QueueEntry{}, db.Track{}, err
 	}
 
@@ -146,6 +513,102 @@ func nextTrack(tx *gorm.DB) (db.QueueEntry, db.Track, error) {
 	return nx, t, nil
 }
 
+// repeatRun selects the played entries of the repeat-all run that began at since.
+// Autopilot entries are left out: they are filler, not part of what was asked to repeat.
+func repeatRun(tx *gorm.DB, since time.Time) *gorm.DB {
+	return tx.Model(&db.QueueEntry{}).
+		Where("status = ? AND is_fallback = ? AND played_at >= ?", "prev", false, since)
+}
+
+// pickNext returns the upcoming entry that plays next under mode.
+func pickNext(tx *gorm.DB, mode playMode) (db.QueueEntry, error) {
+	if !mode.reorders() {
+		var nx db.QueueEntry
+		err := tx.Where("status = ?", "next").Order("position asc").First(&nx).Error
+		return nx, err
+	}
+
+	var upcoming []db.QueueEntry
+	if err := tx.Where("status = ?", "next").Order("position asc").Find(&upcoming).Error; err != nil {
+		return db.QueueEntry{}, err
+	}
+	if len(upcoming) == 0 {
+		return db.QueueEntry{}, gorm.ErrRecordNotFound
+	}
+	ids := make([]string, len(upcoming))
+	fixed := make([]bool, len(upcoming))
+	for i, q := range upcoming {
+		ids[i], fixed[i] = q.ID.String(), q.IsDonation || q.IsFallback
+	}
+	var keys []string
+	var served []string
+	if mode.fair {
+		var err error
+		if keys, err = requesterKeys(tx, upcoming); err != nil {
+			return db.QueueEntry{}, err
+		}
+		if served, err = servedRequesters(tx); err != nil {
+			return db.QueueEntry{}, err
+		}
+	}
+	return upcoming[playOrder(ids, keys, fixed, mode, served)[0]], nil
+}
+
+// requesterKeys returns the requester key of each entry.
+func requesterKeys(tx *gorm.DB, entries []db.QueueEntry) ([]string, error) {
+	trackIDs := make([]uuid.UUID, len(entries))
+	for i, q := range entries {
+		trackIDs[i] = q.TrackID
+	}
+	var tracks []db.Track
+	if err := tx.Select("id", "added_by_user_id", "added_by_nick").Where("id in ?", trackIDs).Find(&tracks).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]string, len(tracks))
+	for _, t := range tracks {
+		byID[t.ID] = requesterKey(t.AddedByUserID, t.AddedByNick)
+	}
+	keys := make([]string, len(entries))
+	for i, q := range entries {
+		keys[i] = byID[q.TrackID]
+	}
+	return keys, nil
+}
+
+// servedRequesters returns the requesters of recently played user entries, most recent first
+// (one key per played entry).
+func servedRequesters(tx *gorm.DB) ([]string, error) {
+	var tracks []db.Track
+	err := tx.Model(&db.Track{}).
+		Select("tracks.added_by_user_id, tracks.added_by_nick").
+		Joins("join queue_entries on queue_entries.track_id = tracks.id").
+		Where("queue_entries.status in ? AND queue_entries.played_at IS NOT NULL AND queue_entries.is_fallback = ?", []string{"prev", "current"}, false).
+		Order("queue_entries.played_at desc").
+		Limit(fairHistoryRows).
+		Find(&tracks).Error
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(tracks))
+	for i, t := range tracks {
+		keys[i] = requesterKey(t.AddedByUserID, t.AddedByNick)
+	}
+	return keys, nil
+}
+
+// firstNext returns the entry that plays after the current one.
+func firstNext(tx *gorm.DB, mode playMode) (db.QueueEntry, db.Track, error) {
+	nx, err := pickNext(tx, mode)
+	if err != nil {
+		return db.QueueEntry{}, db.Track{}, err
+	}
+	var t db.Track
+	if err := tx.Where("id = ?", nx.TrackID).First(&t).Error; err != nil {
+		return db.QueueEntry{}, db.Track{}, err
+	}
+	return nx, t, nil
+}
+
 func prevTrack(tx *gorm.DB) (db.QueueEntry, db.Track, error) {
 	// current -> next, last prev -> current
 	var cur db.QueueEntry
@@ -161,7 +624,7 @@ func prevTrack(tx *gorm.DB) (db.QueueEntry, db.Track, error) {
 	if err := tx.Model(&db.QueueEntry{}).Where("id = ?", cur.ID).Update("status", "next").Error; err != nil {
 		return db.QueueEntry{}, db.Track{}, err
 	}
-	if err := tx.Model(&db.QueueEntry{}).Where("id = ?", pv.ID).Update("status", "current").Error; err != nil {
+	if err := setCurrent(tx, pv.ID); err != nil {
 		return db.QueueEntry{}, db.Track{}, err
 	}
 
@@ -172,8 +635,8 @@ func prevTrack(tx *gorm.DB) (db.QueueEntry, db.Track, error) {
 	return pv, t, nil
 }
 
-func getCurrent(tx *gorm.DB) (db.QueueEntry, db.Track, error) {
-	if err := ensureQueueHasCurrent(tx); err != nil {
+func getCurrent(tx *gorm.DB, mode playMode) (db.QueueEntry, db.Track, error) {
+	if err := ensureQueueHasCurrent(tx, mode); err != nil {
 		return db.QueueEntry{}, db.Track{}, err
 	}
 	var cur db.QueueEntry
@@ -186,3 +649,136 @@ func getCurrent(tx *gorm.DB) (db.QueueEntry, db.Track, error) {
 	}
 	return cur, t, nil
 }
+
+var (
+	ErrEntryNotFound    = errors.New("queue entry not found")
+	ErrEntryNotUpcoming = errors.New("only upcoming entries can be moved")
+	ErrPlaylistNotFound = errors.New("playlist not found")
+	ErrTrackNotFound    = errors.New("library track not found")
+)
+
+// deleteQueueEntry removes one entry and closes the gap in positions; removing the current
+// entry hands over to the one that plays next under mode.
+func deleteQueueEntry(tx *gorm.DB, id uuid.UUID, mode playMode) error {
+	res := tx.Where("id = ?", id).Delete(&db.QueueEntry{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrEntryNotFound
+	}
+	if err := renumberQueue(tx, nil); err != nil {
+		return err
+	}
+	return ensureQueueHasCurrent(tx, mode)
+}
+
+// moveQueueEntry moves an upcoming entry to pos (1-based among upcoming entries, 1 = plays next).
+func moveQueueEntry(tx *gorm.DB, id uuid.UUID, pos int) error {
+	var q db.QueueEntry
+	if err := tx.Where("id = ?", id).First(&q).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrEntryNotFound
+		}
+		return err
+	}
+	if q.Status != "next" {
+		return ErrEntryNotUpcoming
+	}
+
+	var upcoming []uuid.UUID
+	if err := tx.Model(&db.QueueEntry{}).Where("status = ? AND id <> ?", "next", id).Order("position asc").Pluck("id", &upcoming).Error; err != nil {
+		return err
+	}
+	idx := min(max(pos-1, 0), len(upcoming))
+	upcoming = append(upcoming[:idx], append([]uuid.UUID{id}, upcoming[idx:]...)...)
+	return renumberQueue(tx, upcoming)
+}
+
+// clearQueue deletes all entries with the given status (prev|next) and returns how many were removed.
+func clearQueue(tx *gorm.DB, status string) (int64, error) {
+	res := tx.Where("status = ?", status).Delete(&db.QueueEntry{})
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	if err := renumberQueue(tx, nil); err != nil {
+		return 0, err
+	}
+	return res.RowsAffected, nil
+}
+
+// renumberQueue rewrites positions as 1..N: played and current entries keep their order,
+// followed by upcoming entries in the given order (nil = current order).
+func renumberQueue(tx *gorm.DB, upcoming []uuid.UUID) error {
+	type row struct {
+		ID       uuid.UUID
+		Position int
+		Status   string
+	}
+	var rows []row
+	if err := tx.Model(&db.QueueEntry{}).Select("id, position, status").Order("position asc").Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	order := make([]uuid.UUID, 0, len(rows))
+	current := make(map[uuid.UUID]int, len(rows))
+	for _, r := range rows {
+		current[r.ID] = r.Position
+		if upcoming == nil || r.Status != "next" {
+			order = append(order, r.ID)
+		}
+	}
+	order = append(order, upcoming...)
+
+	for i, id := range order {
+		if current[id] == i+1 {
+			continue
+		}
+		if err := tx.Model(&db.QueueEntry{}).Where("id = ?", id).Update("position", i+1).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadSetting decodes a JSON setting into out. Reports false if it was never saved.
+func loadSetting(tx *gorm.DB, key string, out any) (bool, error) {
+	var s db.Setting
+	if err := tx.Where(map[string]any{"key": key}).Limit(1).Find(&s).Error; err != nil {
+		return false, err
+	}
+	if s.Key == "" {
+		return false, nil
+	}
+	return true, json.Unmarshal(s.ValueJSON, out)
+}
+
+func saveSetting(tx *gorm.DB, key string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s := db.Setting{Key: key, ValueJSON: datatypes.JSON(b), UpdatedAt: time.Now().UTC()}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value_json", "updated_at"}),
+	}).Create(&s).Error
+}
+
+// saveTrackLoudness merges the measurement into tracks.metadata_json under "loudness".
+func saveTrackLoudness(tx *gorm.DB, trackID uuid.UUID, l Loudness) error {
+	var t db.Track
+	if err := tx.Where("id = ?", trackID).First(&t).Error; err != nil {
+		return err
+	}
+	meta := map[string]any{}
+	if len(t.MetadataJSON) > 0 {
+		_ = json.Unmarshal(t.MetadataJSON, &meta)
+	}
+	meta["loudness"] = l
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return tx.Model(&db.Track{}).Where("id = ?", trackID).Update("metadata_json", datatypes.JSON(b)).Error
+}
@@ -0,0 +1,180 @@
+// Purpose: Request rules evaluated on resolved source.Meta before insertion.
+// - Duration limits, livestream ban, title/channel keyword blocklists, URL domain allowlist.
+// - Owner-editable; stored in the settings table under "request_rules".
+// - Each rejection carries a human-readable reason. Owner requests bypass the rules.
+
+package player
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"radiokpowka/backend/source"
+)
+
+const (
+	CodeRuleRejected = "rule_rejected"
+
+	rulesSettingKey = "request_rules"
+)
+
+type Rules struct {
+	MinDurationSec   int      `json:"minDurationSec"`   // 0 = no minimum
+	MaxDurationSec   int      `json:"maxDurationSec"`   // 0 = no maximum
+	BanLivestreams   bool     `json:"banLivestreams"`   // also rejects unknown (zero) duration
+	TitleBlocklist   []string `json:"titleBlocklist"`   // case-insensitive substrings
+	ChannelBlocklist []string `json:"channelBlocklist"` // case-insensitive substrings
+	AllowedDomains   []string `json:"allowedDomains"`   // empty = any; subdomains match
+}
+
+func DefaultRules() Rules {
+	return Rules{
+		MaxDurationSec: 20 * 60,
+		BanLivestreams: true,
+	}
+}
+
+// normalize cleans up owner input: trims and lowercases lists, drops empty entries.
+func (r Rules) normalize() (Rules, error) {
+	if r.MinDurationSec < 0 || r.MaxDurationSec < 0 {
+		return Rules{}, errors.New("durations must not be negative")
+	}
+	if r.MaxDurationSec > 0 && r.MinDurationSec > r.MaxDurationSec {
+		return Rules{}, errors.New("minDurationSec is greater than maxDurationSec")
+	}
+	clean := func(list []string) []string {
+		out := make([]string, 0, len(list))
+		for _, s := range list {
+			if s = strings.ToLower(strings.TrimSpace(s)); s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	r.TitleBlocklist = clean(r.TitleBlocklist)
+	r.ChannelBlocklist = clean(r.ChannelBlocklist)
+	r.AllowedDomains = clean(r.AllowedDomains)
+	for i, d := range r.AllowedDomains {
+		r.AllowedDomains[i] = strings.TrimPrefix(d, "www.")
+	}
+	return r, nil
+}
+
+// checkURL rejects links outside the domain allowlist (before anything is resolved).
+// Local files are exempt: the local source only serves LOCAL_MEDIA_DIRS.
+func (r Rules) checkURL(raw string) error {
+	if len(r.AllowedDomains) == 0 {
+		return nil
+	}
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err == nil && u.Scheme == "file" {
+		return nil
+	}
+	if err != nil || u.Hostname() == "" {
+		return ruleRejected("ссылка не распознана")
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, d := range r.AllowedDomains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return nil
+		}
+	}
+	return ruleRejected(fmt.Sprintf("домен %s не разрешён (разрешены: %s)", host, strings.Join(r.AllowedDomains, ", ")))
+}
+
+// checkMeta evaluates the rules on one resolved track.
+func (r Rules) checkMeta(m source.Meta) error {
+	if r.BanLivestreams && (m.IsLive || m.DurationSec == 0) {
+		return ruleRejected(fmt.Sprintf("«%s»: трансляции и треки без длительности запрещены", m.Title))
+	}
+	if r.MinDurationSec > 0 && m.DurationSec > 0 && m.DurationSec < r.MinDurationSec {
+		return ruleRejected(fmt.Sprintf("«%s»: слишком короткий трек (%s, минимум %s)", m.Title, fmtDuration(m.DurationSec), fmtDuration(r.MinDurationSec)))
+	}
+	if r.MaxDurationSec > 0 && m.DurationSec > r.MaxDurationSec {
+		return ruleRejected(fmt.Sprintf("«%s»: слишком длинный трек (%s, максимум %s)", m.Title, fmtDuration(m.DurationSec), fmtDuration(r.MaxDurationSec)))
+	}
+	if w := containsAny(m.Title, r.TitleBlocklist); w != "" {
+		return ruleRejected(fmt.Sprintf("«%s»: запрещённое слово в названии (%q)", m.Title, w))
+	}
+	if w := containsAny(m.Channel, r.ChannelBlocklist); w != "" {
+		return ruleRejected(fmt.Sprintf("«%s»: канал %s в чёрном списке (%q)", m.Title, m.Channel, w))
+	}
+	if m.WebpageURL != "" {
+		if err := r.checkURL(m.WebpageURL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filterMetas keeps the tracks that pass. A request is rejected only if nothing passes;
+// the first reason is reported then.
+func (r Rules) filterMetas(metas []source.Meta) ([]source.Meta, error) {
+	out := make([]source.Meta, 0, len(metas))
+	var first error
+	for _, m := range metas {
+		if err := r.checkMeta(m); err != nil {
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		out = append(out, m)
+	}
+	if len(out) == 0 && first != nil {
+		return nil, first
+	}
+	return out, nil
+}
+
+func ruleRejected(reason string) error {
+	return &RequestError{Code: CodeRuleRejected, Message: reason}
+}
+
+func containsAny(s string, words []string) string {
+	s = strings.ToLower(s)
+	for _, w := range words {
+		if strings.Contains(s, w) {
+			return w
+		}
+	}
+	return ""
+}
+
+func fmtDuration(sec int) string {
+	return fmt.Sprintf("%d:%02d", sec/60, sec%60)
+}
+
+func (c *Controller) Rules() Rules {
+	c.rulesMu.RLock()
+	defer c.rulesMu.RUnlock()
+	return c.rules
+}
+
+// SetRules validates and stores new rules.
+func (c *Controller) SetRules(r Rules) (Rules, error) {
+	r, err := r.normalize()
+	if err != nil {
+		return Rules{}, err
+	}
+	if err := saveSetting(c.db, rulesSettingKey, r); err != nil {
+		return Rules{}, err
+	}
+	c.rulesMu.Lock()
+	c.rules = r
+	c.rulesMu.Unlock()
+	return r, nil
+}
+
+func (c *Controller) loadRules() {
+	r := DefaultRules()
+	if _, err := loadSetting(c.db, rulesSettingKey, &r); err != nil {
+		log.Printf("правила заказов: не удалось загрузить, используются значения по умолчанию: %v", err)
+	}
+	c.rulesMu.Lock()
+	c.rules = r
+	c.rulesMu.Unlock()
+}
@@ -0,0 +1,131 @@
+package player
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"radiokpowka/backend/source"
+)
+
+func TestRulesCheckMeta(t *testing.T) {
+	r, err := Rules{
+		MinDurationSec:   30,
+		MaxDurationSec:   600,
+		BanLivestreams:   true,
+		TitleBlocklist:   []string{" Earrape ", ""},
+		ChannelBlocklist: []string{"spam"},
+		AllowedDomains:   []string{"www.YouTube.com"},
+	}.normalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok := source.Meta{Title: "Song", DurationSec: 200, Channel: "Band", WebpageURL: "https://music.youtube.com/watch?v=x"}
+	cases := []struct {
+		name   string
+		patch  func(*source.Meta)
+		reason string // "" = accepted
+	}{
+		{"accepted", func(*source.Meta) {}, ""},
+		{"livestream", func(m *source.Meta) { m.IsLive = true }, "трансляции"},
+		{"unknown duration", func(m *source.Meta) { m.DurationSec = 0 }, "трансляции"},
+		{"too short", func(m *source.Meta) { m.DurationSec = 10 }, "слишком короткий трек (0:10, минимум 0:30)"},
+		{"too long", func(m *source.Meta) { m.DurationSec = 36000 }, "слишком длинный трек (600:00, максимум 10:00)"},
+		{"title", func(m *source.Meta) { m.Title = "EARRAPE remix" }, `запрещённое слово в названии ("earrape")`},
+		{"channel", func(m *source.Meta) { m.Channel = "SpamChannel" }, "в чёрном списке"},
+		{"domain", func(m *source.Meta) { m.WebpageURL = "https://evil.example/x" }, "домен evil.example не разрешён"},
+		{"lookalike domain", func(m *source.Meta) { m.WebpageURL = "https://notyoutube.com/x" }, "не разрешён"},
+	}
+	for _, tc := range cases {
+		m := ok
+		tc.patch(&m)
+		err := r.checkMeta(m)
+		switch {
+		case tc.reason == "" && err != nil:
+			t.Errorf("%s: rejected: %v", tc.name, err)
+		case tc.reason != "" && (err == nil || !strings.Contains(err.Error(), tc.reason)):
+			t.Errorf("%s: got %v, want reason containing %q", tc.name, err, tc.reason)
+		case err != nil && requestCode(err) != CodeRuleRejected:
+			t.Errorf("%s: code %q", tc.name, requestCode(err))
+		}
+	}
+}
+
+func TestRulesNormalize(t *testing.T) {
+	if _, err := (Rules{MinDurationSec: -1}).normalize(); err == nil {
+		t.Fatal("negative duration accepted")
+	}
+	if _, err := (Rules{MinDurationSec: 300, MaxDurationSec: 60}).normalize(); err == nil {
+		t.Fatal("min above max accepted")
+	}
+	r, err := (Rules{MinDurationSec: 300}).normalize()
+	if err != nil || r.MinDurationSec != 300 {
+		t.Fatalf("min without max: %v", err)
+	}
+	r, _ = (Rules{AllowedDomains: []string{" www.Twitch.tv", " "}}).normalize()
+	if !slices.Equal(r.AllowedDomains, []string{"twitch.tv"}) {
+		t.Fatalf("domains = %q", r.AllowedDomains)
+	}
+}
+
+func TestRulesFilterPlaylist(t *testing.T) {
+	r := DefaultRules()
+	metas := []source.Meta{
+		{Title: "loop", DurationSec: 36000},
+		{Title: "song", DurationSec: 200},
+	}
+	out, err := r.filterMetas(metas)
+	if err != nil || len(out) != 1 || out[0].Title != "song" {
+		t.Fatalf("filter = %v, %v", out, err)
+	}
+	// nothing passes: the first reason is reported
+	if _, err := r.filterMetas(metas[:1]); err == nil || !strings.Contains(err.Error(), "«loop»") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestRulesApplyToRequests(t *testing.T) {
+	c := newTestController(t, nil)
+	if _, err := c.SetRules(Rules{MaxDurationSec: 300, AllowedDomains: []string{"tracks.test"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := c.AddTrack(AddRequest{URL: "http://tracks.test/long?dur=900", Nick: "alice"})
+	if requestCode(err) != CodeRuleRejected {
+		t.Fatalf("long track: %v", err)
+	}
+	_, err = c.AddTrack(AddRequest{URL: "https://other.test/a", Nick: "alice"})
+	if requestCode(err) != CodeRuleRejected {
+		t.Fatalf("foreign domain: %v", err)
+	}
+	request(t, c, "alice", "a")
+
+	// owner requests bypass the rules
+	if _, err := c.AddTrack(AddRequest{URL: "http://tracks.test/long?dur=900", Nick: "owner", Privileged: true}); err != nil {
+		t.Fatalf("owner request: %v", err)
+	}
+	if got := queueTitles(t, c); !slices.Equal(got, []string{"long"}) {
+		t.Fatalf("upcoming = %v", got)
+	}
+
+	// the rules survive a restart
+	if got := restart(t, c).Rules(); got.MaxDurationSec != 300 || !slices.Equal(got.AllowedDomains, []string{"tracks.test"}) {
+		t.Fatalf("rules after restart = %+v", got)
+	}
+}
+
+func TestRulesRejectedLinkKeepsCooldown(t *testing.T) {
+	c := newTestController(t, func(d *ControllerDeps) {
+		d.Quota = QuotaConfig{Cooldown: time.Minute}
+	})
+	if _, err := c.SetRules(Rules{AllowedDomains: []string{"tracks.test"}}); err != nil {
+		t.Fatal(err)
+	}
+	// a link the rules refuse is never looked up, so it does not start the cooldown
+	if _, err := c.AddTrack(AddRequest{URL: "https://other.test/a", Nick: "alice"}); requestCode(err) != CodeRuleRejected {
+		t.Fatalf("foreign domain: %v", err)
+	}
+	request(t, c, "alice", "a")
+}
@@ -0,0 +1,25 @@
+// Purpose: Text-query song requests ("!sr artist - song"). The query resolves to the top
+// search result, which then goes through the same rules, quotas and duplicate checks as a link.
+
+package player
+
+import (
+	"context"
+	"fmt"
+
+	"radiokpowka/backend/source"
+)
+
+const CodeNoResults = "no_results"
+
+// searchTop resolves a text query to its first search result.
+func (c *Controller) searchTop(query string) ([]source.Meta, error) {
+	metas, err := c.sources.Search(context.Background(), query, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(metas) == 0 {
+		return nil, &RequestError{Code: CodeNoResults, Message: fmt.Sprintf("по запросу «%s» ничего не найдено", query)}
+	}
+	return metas[:1], nil
+}
@@ -0,0 +1,59 @@
+package player
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTextQueryQueuesTopResult(t *testing.T) {
+	c := newTestController(t, nil)
+	request(t, c, "alice", "now")
+	if _, err := c.AddTrack(AddRequest{URL: "  Artist - Song ", Nick: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := queueTitles(t, c); !slices.Equal(got, []string{"Artist - Song"}) {
+		t.Fatalf("upcoming = %v", got)
+	}
+	w, _ := c.QueueWindow()
+	if last := w.Items[len(w.Items)-1]; last.URL != "http://tracks.test/artist---song" {
+		t.Fatalf("queued %q, want the search result URL", last.URL)
+	}
+
+	_, err := c.AddTrack(AddRequest{URL: "nothing", Nick: "bob"})
+	if requestCode(err) != CodeNoResults {
+		t.Fatalf("empty search = %v", err)
+	}
+	if _, err := c.AddTrack(AddRequest{URL: "   ", Nick: "bob"}); err == nil {
+		t.Fatal("blank request accepted")
+	}
+}
+
+func TestTextQueryObeysRules(t *testing.T) {
+	c := newTestController(t, nil)
+	// the result URL is checked against the allowlist even though the query has no domain
+	if _, err := c.SetRules(Rules{AllowedDomains: []string{"youtube.com"}}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := c.AddTrack(AddRequest{URL: "artist - song", Nick: "bob"})
+	if requestCode(err) != CodeRuleRejected {
+		t.Fatalf("query with a disallowed result = %v", err)
+	}
+
+	if _, err := c.SetRules(Rules{TitleBlocklist: []string{"earrape"}}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.AddTrack(AddRequest{URL: "earrape compilation", Nick: "bob"})
+	if requestCode(err) != CodeRuleRejected {
+		t.Fatalf("query with a blocked title = %v", err)
+	}
+}
+
+func TestTextQueryCountsAsDuplicate(t *testing.T) {
+	c := newTestController(t, func(d *ControllerDeps) { d.Duplicates = DuplicateConfig{Policy: DuplicateReject} })
+	request(t, c, "alice", "now")
+	request(t, c, "alice", "artist---song")
+	_, err := c.AddTrack(AddRequest{URL: "Artist - Song", Nick: "bob"})
+	if requestCode(err) != CodeDuplicate {
+		t.Fatalf("query for a queued track = %v", err)
+	}
+}
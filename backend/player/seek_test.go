@@ -0,0 +1,47 @@
+package player
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSeekClampsAndMovesListeners(t *testing.T) {
+	c := newTestController(t, nil)
+	if err := c.Seek(10, false); !errors.Is(err, ErrNothingPlaying) {
+		t.Fatalf("seek without a track = %v", err)
+	}
+
+	request(t, c, "alice", "a?dur=180")
+	if err := c.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	before := c.StreamSnapshot().SeekSeq
+
+	cases := []struct {
+		sec      int
+		relative bool
+		want     int
+	}{
+		{60, false, 60},
+		{30, true, 90},     // skip intro
+		{-15, true, 75},    // back a bit
+		{-500, true, 0},    // not before the start
+		{1000, false, 179}, // not past the end
+	}
+	for _, tc := range cases {
+		if err := c.Seek(tc.sec, tc.relative); err != nil {
+			t.Fatal(err)
+		}
+		if got := c.State().PositionSec; got != tc.want {
+			t.Fatalf("Seek(%d, %v): position %d, want %d", tc.sec, tc.relative, got, tc.want)
+		}
+		if snap := c.StreamSnapshot(); snap.PosSec != tc.want {
+			t.Fatalf("stream snapshot at %d, want %d", snap.PosSec, tc.want)
+		}
+	}
+
+	// every seek restarts the decoder at the new offset
+	if got := c.StreamSnapshot().SeekSeq - before; got != uint64(len(cases)) {
+		t.Fatalf("seek sequence advanced by %d, want %d", got, len(cases))
+	}
+}
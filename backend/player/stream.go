@@ -1,6 +1,7 @@
 // Purpose: Streaming implementation for /stream.
-// - If paused: stream silence from ffmpeg (anullsrc) to avoid client error loops.
-// - If playing: get yt-dlp direct audio URL and run ffmpeg -> mp3, seek to position.
+// - One long-running encoder (raw PCM on stdin -> mp3/opus/aac on stdout) per mount.
+// - Each track is decoded to raw PCM by its own ffmpeg and fed into the encoder in real time.
+// - If paused or the queue is empty the encoder is fed silence, so the stream never ends.
 
 package player
 
@@ -15,38 +16,46 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+)
 
-	"github.com/google/uuid"
-
-	"radiokpowka/backend/youtube"
+// Raw PCM format shared by decoders and the encoder: s16le, 48 kHz, stereo.
+const (
+	pcmSampleRate    = 48000
+	pcmChannels      = 2
+	pcmFrameDuration = 20 * time.Millisecond
+	pcmFrameBytes    = pcmSampleRate * pcmChannels * 2 * int(pcmFrameDuration/time.Millisecond) / 1000
 )
 
-func NewSilenceFFMPEG(ctx context.Context, ffmpegPath string, w io.Writer) *exec.Cmd {
-	// Generates infinite silent MP3 stream
-	// ffmpeg -f lavfi -i anullsrc=r=48000:cl=stereo -acodec libmp3lame -b:a 192k -f mp3 pipe:1
-	cmd := exec.CommandContext(ctx,
-		ffmpegPath,
+var pcmInputArgs = []string{
+	"-f", "s16le",
+	"-ar", strconv.Itoa(pcmSampleRate),
+	"-ac", strconv.Itoa(pcmChannels),
+}
+
+func NewEncoderFFMPEG(ctx context.Context, ffmpegPath string, m Mount, w io.Writer) *exec.Cmd {
+	// ffmpeg -f s16le -ar 48000 -ac 2 -i pipe:0 <codec args, e.g. -acodec libmp3lame -b:a 192k -f mp3> pipe:1
+	args := []string{
 		"-hide_banner",
 		"-loglevel", "warning",
 		"-fflags", "+flush_packets",
-		"-f", "lavfi",
-		"-i", "anullsrc=r=48000:cl=stereo",
-		"-acodec", "libmp3lame",
-		"-b:a", "192k",
+	}
+	args = append(args, pcmInputArgs...)
+	args = append(args, "-i", "pipe:0")
+	args = append(args, m.codecArgs()...)
+	args = append(args,
 		"-flush_packets", "1",
-		"-f", "mp3",
 		"pipe:1",
 	)
+	cmd := exec.CommandContext(ctx, ffmpegPath, args...)
 	cmd.Stdout = w
 	return cmd
 }
 
-func NewTrackFFMPEG(ctx context.Context, ffmpegPath string, directURL string, seekSec int, volume float64, w io.Writer) *exec.Cmd {
-	// ffmpeg -ss <seek> -i <directURL> -vn -filter:a volume=<v> -acodec libmp3lame -b:a 192k -f mp3 pipe:1
+func NewDecoderFFMPEG(ctx context.Context, ffmpegPath string, directURL string, seekSec int, filter string, w io.Writer) *exec.Cmd {
+	// ffmpeg -ss <seek> -i <directURL> -vn [-af <filter>] -f s16le -ar 48000 -ac 2 pipe:1
 	args := []string{
 		"-hide_banner",
 		"-loglevel", "warning",
-		"-fflags", "+flush_packets",
 	}
 	if seekSec > 0 {
 		args = append(args, "-ss", strconv.Itoa(seekSec))
@@ -54,31 +63,19 @@ func NewTrackFFMPEG(ctx context.Context, ffmpegPath string, directURL string, se
 	args = append(args,
 		"-i", directURL,
 		"-vn",
-		"-filter:a", "volume="+formatVol(volume),
-		"-acodec", "libmp3lame",
-		"-b:a", "192k",
-		"-flush_packets", "1",
-		"-f", "mp3",
-		"pipe:1",
 	)
+	if filter != "" {
+		args = append(args, "-af", filter)
+	}
+	args = append(args, pcmInputArgs...)
+	args = append(args, "pipe:1")
 	cmd := exec.CommandContext(ctx, ffmpegPath, args...)
 	cmd.Stdout = w
 	return cmd
 }
 
-func formatVol(v float64) string {
-	if v < 0 {
-		v = 0
-	}
-	if v > 2 {
-		v = 2
-	}
-	// ffmpeg accepts float in string form
-	return strconv.FormatFloat(v, 'f', 3, 64)
-}
-
 type ControllerStreamer interface {
-	CurrentForStreaming() (url string, posSec int, vol float64, paused bool, ok bool)
+	StreamSnapshot() StreamSnapshot
 }
 
 type StreamWriter interface {
@@ -89,40 +86,36 @@ type Flusher interface {
 	Flush()
 }
 
-func (c *Controller) StreamTo(ctx context.Context, w io.Writer, flusher Flusher) error {
-	url, pos, vol, paused, ok := c.CurrentForStreaming()
-	ffmpegPath := c.yt.FFMPEGPath()
-	fw := &flushWriter{w: w, f: flusher}
-
-	// no track -> silence for a short time then exit
-	if !ok || url == "" {
-		shortCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
-		defer cancel()
-		log.Printf("стрим: нет трека, отправляем короткую тишину")
-		counter := &countWriter{w: fw}
-		cmd := NewSilenceFFMPEG(shortCtx, ffmpegPath, counter)
-		return runFFMPEG(shortCtx, cmd, "silence", counter)
-	}
+type StreamOptions struct {
+	Mount Mount
+	// IcyMetaInt > 0 interleaves ICY metadata every IcyMetaInt audio bytes.
+	IcyMetaInt int
+}
 
-	// paused => stream silence (keeps clients stable)
-	if paused {
-		log.Printf("стрим: пауза, отправляем тишину")
-		counter := &countWriter{w: fw}
-		cmd := NewSilenceFFMPEG(ctx, ffmpegPath, counter)
-		return runFFMPEG(ctx, cmd, "silence", counter)
+// StreamTo attaches the client to the shared station encoder and copies audio until
+// the client goes away or is dropped for falling behind.
+func (c *Controller) StreamTo(ctx context.Context, w io.Writer, flusher Flusher, opts StreamOptions) error {
+	var fw io.Writer = &flushWriter{w: w, f: flusher}
+	if opts.IcyMetaInt > 0 {
+		fw = newIcyWriter(fw, opts.IcyMetaInt, c.StreamTitle)
 	}
 
-	// playing => direct URL via yt-dlp, then ffmpeg to mp3
-	direct, err := c.yt.DirectAudioURL(ctx, url)
+	sub, err := c.bc.Subscribe(opts.Mount)
 	if err != nil {
-		log.Printf("стрим: ошибка получения direct URL: %v", err)
 		return err
 	}
+	defer c.bc.Unsubscribe(sub)
+
+	stop := context.AfterFunc(ctx, func() { c.bc.Unsubscribe(sub) })
+	defer stop()
 
-	log.Printf("стрим: старт трека url=%s pos=%d vol=%.3f", url, pos, vol)
 	counter := &countWriter{w: fw}
-	cmd := NewTrackFFMPEG(ctx, ffmpegPath, direct, pos, vol, counter)
-	return runFFMPEG(ctx, cmd, "track", counter)
+	_, err = io.Copy(counter, sub)
+	log.Printf("стрим: слушатель отключён, отправлено байт=%d", counter.Count())
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
 }
 
 type flushWriter struct {
@@ -199,6 +192,7 @@ func runFFMPEG(ctx context.Context, cmd *exec.Cmd, label string, counter *countW
 	return nil
 }
 
-// ensure we use uuid import (kept for future extensions, avoids IDE confusion)
-var _ = uuid.Nil
-var _ = youtube.Meta{}
+// FindMount resolves a mount by codec ("" = default) and bitrate (0 = any).
+func (c *Controller) FindMount(codec string, bitrate int) (Mount, bool) {
+	return c.bc.FindMount(codec, bitrate)
+}
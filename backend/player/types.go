@@ -2,7 +2,11 @@
 
 package player
 
-import "time"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type TrackDTO struct {
 	ID          string `json:"id"`
@@ -18,22 +22,36 @@ type PlayerState struct {
 	PositionSec int      `json:"positionSec"`
 	DurationSec int      `json:"durationSec"`
 	Current     *TrackDTO `json:"current,omitempty"`
+	Shuffle     bool     `json:"shuffle"`
+	ShuffleSeed int64    `json:"shuffleSeed,omitempty"`
+	Repeat      string   `json:"repeat"` // off|one|all
+	FairQueue   bool     `json:"fairQueue"`
 }
 
 type QueueEntryDTO struct {
 	ID         string `json:"id"`
 	Title      string `json:"title"`
 	URL        string `json:"url"`
+	Provider   string `json:"provider,omitempty"` // audio source (youtube, soundcloud, local, ...)
 	AddedByNick string `json:"addedByNick"`
 	AddedAt    string `json:"addedAt"`
 	Status     string `json:"status"` // prev|current|next
 	IsDonation bool   `json:"isDonation,omitempty"`
+	DonationAmount int64 `json:"donationAmount,omitempty"`
+	IsFallback bool   `json:"isFallback,omitempty"` // queued by autopilot
+	RequestCount int  `json:"requestCount,omitempty"` // people who requested it (merged duplicates)
+	Prefetch   string `json:"prefetch,omitempty"` // pending|ready|cached|failed (upcoming entries only)
 }
 
 type runtime struct {
 	isPlaying   bool
 	isPaused    bool
 	volume      float64
+	shuffle     bool
+	shuffleSeed int64
+	repeat      string // off|one|all
+	repeatSince time.Time // repeat-all replays entries played since then
+	fairQueue   bool
 
 	currentQueueID string
 	currentTrackID string
@@ -41,7 +59,29 @@ type runtime struct {
 	currentURL     string
 	currentAddedBy string
 	durationSec    int
+	currentLoudness *Loudness
+	currentInput    string // prefetched direct URL, "" = resolve at start
+	currentInputAt  time.Time
+	crossfade       time.Duration // set only while switching to the next track
+	seekSeq         uint64        // bumped on seek: the decoder restarts at the new position
+	historyID       uuid.UUID     // open play_histories row, uuid.Nil = none
 
 	startedAt     time.Time
 	basePosSec    int // position at startedAt
+
+	streamSeq uint64 // bumped on every change the audio stream must follow
+}
+
+// StreamSnapshot is what the station encoder needs to know about playback.
+type StreamSnapshot struct {
+	Seq     uint64
+	QueueID string
+	URL     string
+	PosSec  int
+	Volume  float64
+	Paused  bool   // paused, stopped or nothing to play
+	Filter  string // decoder audio filter (loudness normalization)
+	Input   string // prefetched direct URL, "" = resolve
+	Fade    time.Duration // crossfade from the previous track (0 = hard cut)
+	SeekSeq uint64        // changes when the decoder must restart at PosSec
 }
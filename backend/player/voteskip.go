@@ -0,0 +1,140 @@
+// Purpose: Listener skip voting. Authenticated listeners and Twitch chat vote on the current
+// queue entry; when the votes reach the threshold the track is skipped. The threshold is a
+// fixed number of votes or a percentage of active WebSocket listeners, never below Min: chat
+// voters are not WebSocket listeners, so with no dashboard open a percentage alone would let
+// one !skip through. Votes are per entry: they reset as soon as another entry becomes current.
+
+package player
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"radiokpowka/backend/websocket"
+)
+
+type VoteSkipConfig struct {
+	Enabled bool
+	Votes   int // fixed threshold, used when Percent is 0
+	Percent int // percentage of active WebSocket listeners
+	Min     int // votes a percentage threshold never goes below (at least one)
+}
+
+var (
+	ErrVoteSkipDisabled = errors.New("vote skip is disabled")
+	ErrAlreadyVoted     = errors.New("already voted for this track")
+	ErrAlreadySkipped   = errors.New("track is already being skipped")
+)
+
+// VoteProgress is the vote state of the current entry.
+type VoteProgress struct {
+	QueueID string `json:"queueId"`
+	Votes   int    `json:"votes"`
+	Needed  int    `json:"needed"`
+	Skipped bool   `json:"skipped"`
+}
+
+type skipVotes struct {
+	queueID string
+	voters  map[string]bool
+	skipped bool // threshold reached: the skip fires once per entry
+}
+
+// ListenerVoter and TwitchVoter build voter keys, so one person votes once per track.
+func ListenerVoter(userID uuid.UUID) string {
+	return "user:" + userID.String()
+}
+
+func TwitchVoter(nick string) string {
+	return "twitch:" + strings.ToLower(strings.TrimSpace(nick))
+}
+
+// VoteSkip records a vote against the current entry and skips it once the threshold is reached.
+func (c *Controller) VoteSkip(voter string) (VoteProgress, error) {
+	if !c.voteSkip.Enabled {
+		return VoteProgress{}, ErrVoteSkipDisabled
+	}
+	c.mu.RLock()
+	qid := c.rt.currentQueueID
+	c.mu.RUnlock()
+	if qid == "" {
+		return VoteProgress{}, ErrNothingPlaying
+	}
+
+	c.votesMu.Lock()
+	if c.votes.queueID != qid {
+		c.votes = skipVotes{queueID: qid, voters: map[string]bool{}}
+	}
+	if c.votes.skipped {
+		p := c.voteProgressLocked()
+		c.votesMu.Unlock()
+		return p, ErrAlreadySkipped
+	}
+	if c.votes.voters[voter] {
+		p := c.voteProgressLocked()
+		c.votesMu.Unlock()
+		return p, ErrAlreadyVoted
+	}
+	c.votes.voters[voter] = true
+	p := c.voteProgressLocked()
+	if p.Votes >= p.Needed {
+		// latched under votesMu: concurrent votes past the threshold do not skip again
+		c.votes.skipped = true
+		p.Skipped = true
+	}
+	c.votesMu.Unlock()
+
+	c.hub.Broadcast(websocket.Event{Type: websocket.EventVoteSkip, Data: p})
+	if p.Skipped {
+		log.Printf("голосование: пропуск трека %s (%d/%d)", qid, p.Votes, p.Needed)
+		if _, err := c.skipIfCurrent(qid); err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+// skipIfCurrent advances only while qid is still the current entry; the check and the
+// switch happen under one lock, so a vote for a track that already ended skips nothing.
+func (c *Controller) skipIfCurrent(qid string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rt.currentQueueID != qid {
+		return false, nil
+	}
+	return true, c.advanceLocked(fadeTracks, false)
+}
+
+// VoteSkipProgress returns the vote state of the current entry.
+func (c *Controller) VoteSkipProgress() VoteProgress {
+	c.mu.RLock()
+	qid := c.rt.currentQueueID
+	c.mu.RUnlock()
+
+	c.votesMu.Lock()
+	defer c.votesMu.Unlock()
+	if c.votes.queueID != qid {
+		c.votes = skipVotes{queueID: qid, voters: map[string]bool{}}
+	}
+	return c.voteProgressLocked()
+}
+
+// voteProgressLocked needs votesMu held.
+func (c *Controller) voteProgressLocked() VoteProgress {
+	return VoteProgress{
+		QueueID: c.votes.queueID,
+		Votes:   len(c.votes.voters),
+		Needed:  c.voteThreshold(),
+	}
+}
+
+func (c *Controller) voteThreshold() int {
+	if c.voteSkip.Percent > 0 {
+		n := c.hub.ClientCount()
+		return max(1, c.voteSkip.Min, (n*c.voteSkip.Percent+99)/100)
+	}
+	return max(1, c.voteSkip.Votes)
+}
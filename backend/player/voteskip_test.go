@@ -0,0 +1,132 @@
+package player
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestVoteSkipThresholdSkipsOnce(t *testing.T) {
+	c := newTestController(t, func(d *ControllerDeps) {
+		d.VoteSkip = VoteSkipConfig{Enabled: true, Votes: 2}
+	})
+	request(t, c, "alice", "a")
+	request(t, c, "alice", "b")
+	request(t, c, "alice", "c")
+	if got := currentTitle(c); got != "a" {
+		t.Fatalf("current = %q, want a", got)
+	}
+
+	p, err := c.VoteSkip(TwitchVoter("bob"))
+	if err != nil || p.Skipped || p.Votes != 1 || p.Needed != 2 {
+		t.Fatalf("first vote: %+v, %v", p, err)
+	}
+	if _, err := c.VoteSkip(TwitchVoter("Bob ")); !errors.Is(err, ErrAlreadyVoted) {
+		t.Fatalf("repeated vote: err = %v, want ErrAlreadyVoted", err)
+	}
+	p, err = c.VoteSkip(TwitchVoter("carol"))
+	if err != nil || !p.Skipped {
+		t.Fatalf("second vote: %+v, %v", p, err)
+	}
+	if got := currentTitle(c); got != "b" {
+		t.Fatalf("current after skip = %q, want b", got)
+	}
+
+	// votes reset for the new entry
+	p, err = c.VoteSkip(TwitchVoter("bob"))
+	if err != nil || p.Votes != 1 || p.Skipped {
+		t.Fatalf("vote on next entry: %+v, %v", p, err)
+	}
+}
+
+func TestVoteSkipLatchedPerEntry(t *testing.T) {
+	c := newTestController(t, func(d *ControllerDeps) {
+		d.VoteSkip = VoteSkipConfig{Enabled: true, Votes: 1}
+	})
+	request(t, c, "alice", "a")
+	request(t, c, "alice", "b")
+
+	c.mu.RLock()
+	qid := c.rt.currentQueueID
+	c.mu.RUnlock()
+
+	// a vote for an entry that is no longer current skips nothing
+	c.votesMu.Lock()
+	c.votes = skipVotes{queueID: qid, voters: map[string]bool{}, skipped: true}
+	c.votesMu.Unlock()
+	p, err := c.VoteSkip(TwitchVoter("late"))
+	if !errors.Is(err, ErrAlreadySkipped) || p.Skipped {
+		t.Fatalf("vote after the skip fired: %+v, %v", p, err)
+	}
+	if got := currentTitle(c); got != "a" {
+		t.Fatalf("current = %q, want a", got)
+	}
+
+	if ok, err := c.skipIfCurrent("not-current"); ok || err != nil {
+		t.Fatalf("skipIfCurrent(stale) = %v, %v", ok, err)
+	}
+}
+
+func TestVoteSkipConcurrentVotesSkipOneTrack(t *testing.T) {
+	c := newTestController(t, func(d *ControllerDeps) {
+		d.VoteSkip = VoteSkipConfig{Enabled: true, Votes: 3}
+	})
+	for _, name := range []string{"a", "b", "c", "d"} {
+		request(t, c, "alice", name)
+	}
+
+	var wg sync.WaitGroup
+	var skipped atomic.Int32
+	// 5 voters, threshold 3: votes that land on the next entry cannot reach it again
+	for i := range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p, err := c.VoteSkip(TwitchVoter(fmt.Sprint("voter", i)))
+			if err == nil && p.Skipped {
+				skipped.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := skipped.Load(); n != 1 {
+		t.Fatalf("%d votes reported a skip, want 1", n)
+	}
+	if got := currentTitle(c); got != "b" {
+		t.Fatalf("current = %q, want b (exactly one track skipped)", got)
+	}
+}
+
+func TestVoteSkipDisabled(t *testing.T) {
+	c := newTestController(t, nil)
+	if _, err := c.VoteSkip(TwitchVoter("bob")); !errors.Is(err, ErrVoteSkipDisabled) {
+		t.Fatalf("err = %v, want ErrVoteSkipDisabled", err)
+	}
+}
+
+func TestVoteSkipPercentHasFloor(t *testing.T) {
+	c := newTestController(t, func(d *ControllerDeps) {
+		d.VoteSkip = VoteSkipConfig{Enabled: true, Percent: 50, Min: 2}
+	})
+	request(t, c, "alice", "a")
+	// no WebSocket listeners connected: a single chat !skip must not be enough
+	if p := c.VoteSkipProgress(); p.Needed != 2 {
+		t.Fatalf("needed = %d, want 2", p.Needed)
+	}
+	p, err := c.VoteSkip(TwitchVoter("bob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Skipped || currentTitle(c) != "a" {
+		t.Fatalf("one chat vote skipped the track: %+v", p)
+	}
+
+	// without a configured floor one vote is still required
+	c.voteSkip.Min = 0
+	if p := c.VoteSkipProgress(); p.Needed != 1 {
+		t.Fatalf("needed = %d, want 1", p.Needed)
+	}
+}
@@ -0,0 +1,70 @@
+// Purpose: SSRF guard for sources that fetch arbitrary hosts (direct HTTP audio, generic yt-dlp).
+// - Only privileged (owner) requests may resolve links through them.
+// - Hosts resolving to loopback, private, link-local (cloud metadata) or other internal
+//   addresses are refused, both when a link is requested and every time it is opened.
+// - The address check is best effort: ffmpeg and yt-dlp resolve the host again and follow
+//   redirects on their own, so DNS rebinding or a redirect to an internal address gets past it.
+//   It narrows mistakes by the owner; it is not what makes these sources safe, the owner-only rule is.
+
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+)
+
+var (
+	ErrRestricted  = errors.New("links to arbitrary sites are allowed for the owner only")
+	ErrPrivateHost = errors.New("host resolves to a private or internal address")
+)
+
+// AnyHost is implemented by sources that may fetch any host; they are guarded when it returns true.
+type AnyHost interface {
+	AnyHost() bool
+}
+
+// lookupIP is replaced in tests.
+var lookupIP = net.DefaultResolver.LookupIPAddr
+
+// CGNAT range, not covered by net.IP.IsPrivate.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+func guarded(s Source) bool {
+	a, ok := s.(AnyHost)
+	return ok && a.AnyHost()
+}
+
+// checkPublicHost refuses u unless every address of its host is public.
+func checkPublicHost(ctx context.Context, u *url.URL) error {
+	host := u.Hostname()
+	if host == "" {
+		return ErrPrivateHost
+	}
+	addrs, err := lookupIP(ctx, host)
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", host, err)
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("resolve %s: no addresses", host)
+	}
+	for _, a := range addrs {
+		if !isPublicIP(a.IP) {
+			return fmt.Errorf("%w: %s (%s)", ErrPrivateHost, host, a.IP)
+		}
+	}
+	return nil
+}
+
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() ||
+		sharedAddressSpace.Contains(ip))
+}
@@ -0,0 +1,69 @@
+// Purpose: Direct HTTP(S) links to audio files, recognized by extension and played as is.
+
+package source
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"strings"
+)
+
+var audioExts = map[string]bool{
+	".mp3": true, ".ogg": true, ".oga": true, ".opus": true, ".m4a": true,
+	".aac": true, ".flac": true, ".wav": true, ".webm": true,
+}
+
+type httpSource struct {
+	ffprobe string
+}
+
+// IsAudioFile reports whether name has a known audio file extension.
+func IsAudioFile(name string) bool {
+	return audioExts[strings.ToLower(path.Ext(name))]
+}
+
+func HTTP(ffprobePath string) Source {
+	return &httpSource{ffprobe: ffprobePath}
+}
+
+func (s *httpSource) Name() string { return "http" }
+
+func (s *httpSource) AnyHost() bool { return true }
+
+func (s *httpSource) Match(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	return IsAudioFile(u.Path)
+}
+
+func (s *httpSource) Resolve(ctx context.Context, raw string) ([]Meta, error) {
+	m, err := Probe(ctx, s.ffprobe, raw)
+	if err != nil {
+		return nil, err
+	}
+	if m.Title == "" {
+		u, _ := url.Parse(raw)
+		m.Title = titleFromName(path.Base(u.Path))
+	}
+	m.WebpageURL = raw
+	return []Meta{m}, nil
+}
+
+func (s *httpSource) Input(_ context.Context, raw string) (string, error) {
+	return raw, nil
+}
+
+// titleFromName turns "01 Some_Song.mp3" into "01 Some Song".
+func titleFromName(name string) string {
+	if un, err := url.PathUnescape(name); err == nil {
+		name = un
+	}
+	name = strings.TrimSuffix(name, path.Ext(name))
+	name = strings.TrimSpace(strings.ReplaceAll(name, "_", " "))
+	if name == "" {
+		return "Unknown title"
+	}
+	return name
+}
@@ -0,0 +1,97 @@
+// Purpose: Local audio files (file:// URLs) restricted to the configured media directories.
+
+package source
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrOutsideMediaDirs = errors.New("file is outside the media directories")
+
+type localSource struct {
+	ffprobe string
+	roots   []string // absolute, cleaned
+}
+
+// Local serves files under roots; relative roots are resolved against the working directory.
+func Local(ffprobePath string, roots []string) Source {
+	s := &localSource{ffprobe: ffprobePath}
+	for _, r := range roots {
+		abs, err := filepath.Abs(r)
+		if err != nil {
+			continue
+		}
+		if real, err := filepath.EvalSymlinks(abs); err == nil {
+			abs = real
+		}
+		s.roots = append(s.roots, abs)
+	}
+	return s
+}
+
+// FileURL builds the file:// URL stored as the track source for path.
+func FileURL(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+func (s *localSource) Name() string { return "local" }
+
+func (s *localSource) Match(u *url.URL) bool {
+	return u.Scheme == "file"
+}
+
+// path maps raw to a regular file inside one of the roots.
+func (s *localSource) path(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "file" {
+		return "", ErrUnsupported
+	}
+	p := filepath.Clean(filepath.FromSlash(u.Path))
+	// symlinks must not lead outside the roots either
+	if real, err := filepath.EvalSymlinks(p); err == nil {
+		p = real
+	}
+	inside := false
+	for _, root := range s.roots {
+		if rel, err := filepath.Rel(root, p); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			inside = true
+			break
+		}
+	}
+	if !inside {
+		return "", ErrOutsideMediaDirs
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() {
+		return "", errors.New("not a regular file")
+	}
+	return p, nil
+}
+
+func (s *localSource) Resolve(ctx context.Context, raw string) ([]Meta, error) {
+	p, err := s.path(raw)
+	if err != nil {
+		return nil, err
+	}
+	m, err := Probe(ctx, s.ffprobe, p)
+	if err != nil {
+		return nil, err
+	}
+	if m.Title == "" {
+		m.Title = titleFromName(filepath.Base(p))
+	}
+	m.WebpageURL = raw
+	return []Meta{m}, nil
+}
+
+func (s *localSource) Input(_ context.Context, raw string) (string, error) {
+	return s.path(raw)
+}
@@ -0,0 +1,60 @@
+// Purpose: ffprobe wrapper for sources without a metadata API (direct HTTP audio, local files).
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Probe reads the duration and title/artist/album tags of input.
+func Probe(ctx context.Context, ffprobePath, input string) (Meta, error) {
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, ffprobePath,
+		"-v", "error",
+		"-show_entries", "format=duration:format_tags",
+		"-of", "json",
+		input,
+	)
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return Meta{}, fmt.Errorf("ffprobe: %s", msg)
+	}
+
+	var res struct {
+		Format struct {
+			Duration string            `json:"duration"`
+			Tags     map[string]string `json:"tags"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &res); err != nil {
+		return Meta{}, fmt.Errorf("ffprobe: %w", err)
+	}
+
+	// tag keys differ in case between containers (TITLE in FLAC, title in MP3)
+	tags := make(map[string]string, len(res.Format.Tags))
+	for k, v := range res.Format.Tags {
+		tags[strings.ToLower(k)] = strings.TrimSpace(v)
+	}
+	dur, _ := strconv.ParseFloat(res.Format.Duration, 64)
+	return Meta{
+		Title:       tags["title"],
+		DurationSec: int(dur),
+		Channel:     tags["artist"],
+		Album:       tags["album"],
+	}, nil
+}
@@ -0,0 +1,180 @@
+// Purpose: Pluggable audio sources.
+// - A Source resolves track metadata for a URL and opens it as an ffmpeg input.
+// - The Registry routes each URL to the first matching source; the source name is stored on the track.
+// - Sources that can save a track to disk implement Downloader and feed the local cache.
+
+package source
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+)
+
+var (
+	ErrUnsupported       = errors.New("unsupported source URL")
+	ErrSearchUnavailable = errors.New("no source supports search")
+)
+
+// IsURL tells links from plain text queries ("artist - song").
+func IsURL(raw string) bool {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Scheme == "" {
+		return false
+	}
+	return u.Host != "" || u.Scheme == "file"
+}
+
+// Meta is the resolved metadata of one track.
+type Meta struct {
+	Title       string
+	DurationSec int
+	WebpageURL  string
+	Channel     string
+	IsLive      bool   // live or upcoming stream
+	Album       string // from file tags (HTTP and local sources)
+	Provider    string // name of the source that resolved it
+}
+
+type Source interface {
+	Name() string
+	Match(u *url.URL) bool
+	// Resolve returns one meta per track (playlists expand to several).
+	Resolve(ctx context.Context, raw string) ([]Meta, error)
+	// Input returns what ffmpeg should open: a direct media URL or a file path.
+	Input(ctx context.Context, raw string) (string, error)
+}
+
+// Searcher is implemented by sources that can look tracks up by a text query.
+type Searcher interface {
+	Search(ctx context.Context, query string, limit int) ([]Meta, error)
+}
+
+// Downloader is implemented by sources whose tracks can be kept in the local cache.
+type Downloader interface {
+	Download(ctx context.Context, raw, dest string) error
+}
+
+type Registry struct {
+	sources []Source // in match order
+}
+
+func NewRegistry(sources ...Source) *Registry {
+	return &Registry{sources: sources}
+}
+
+// Lookup returns the first source that accepts raw.
+func (r *Registry) Lookup(raw string) (Source, error) {
+	s, _, err := r.lookup(raw)
+	return s, err
+}
+
+func (r *Registry) lookup(raw string) (Source, *url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, nil, ErrUnsupported
+	}
+	for _, s := range r.sources {
+		if s.Match(u) {
+			return s, u, nil
+		}
+	}
+	return nil, nil, ErrUnsupported
+}
+
+// open looks up the source of raw and applies the SSRF guard (see guard.go).
+func (r *Registry) open(ctx context.Context, raw string) (Source, error) {
+	s, u, err := r.lookup(raw)
+	if err != nil {
+		return nil, err
+	}
+	if guarded(s) {
+		if err := checkPublicHost(ctx, u); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Provider returns the name of the source for raw ("" = none).
+func (r *Registry) Provider(raw string) string {
+	s, err := r.Lookup(raw)
+	if err != nil {
+		return ""
+	}
+	return s.Name()
+}
+
+// Resolve returns the tracks behind raw; privileged (owner) callers may also use the
+// sources that fetch arbitrary hosts.
+func (r *Registry) Resolve(ctx context.Context, raw string, privileged bool) ([]Meta, error) {
+	s, err := r.Lookup(raw)
+	if err != nil {
+		return nil, err
+	}
+	if guarded(s) && !privileged {
+		return nil, ErrRestricted
+	}
+	if s, err = r.open(ctx, raw); err != nil {
+		return nil, err
+	}
+	metas, err := s.Resolve(ctx, strings.TrimSpace(raw))
+	if err != nil {
+		return nil, err
+	}
+	for i := range metas {
+		metas[i].Provider = s.Name()
+	}
+	return metas, nil
+}
+
+func (r *Registry) Input(ctx context.Context, raw string) (string, error) {
+	s, err := r.open(ctx, raw)
+	if err != nil {
+		return "", err
+	}
+	return s.Input(ctx, strings.TrimSpace(raw))
+}
+
+// Search asks the first source that supports search.
+func (r *Registry) Search(ctx context.Context, query string, limit int) ([]Meta, error) {
+	for _, s := range r.sources {
+		ss, ok := s.(Searcher)
+		if !ok {
+			continue
+		}
+		metas, err := ss.Search(ctx, strings.TrimSpace(query), limit)
+		if err != nil {
+			return nil, err
+		}
+		for i := range metas {
+			metas[i].Provider = s.Name()
+		}
+		return metas, nil
+	}
+	return nil, ErrSearchUnavailable
+}
+
+// Cacheable reports whether the source of raw can download it for the local cache.
+func (r *Registry) Cacheable(raw string) bool {
+	s, err := r.Lookup(raw)
+	if err != nil {
+		return false
+	}
+	_, ok := s.(Downloader)
+	return ok
+}
+
+// DownloadAudio saves raw to dest through its source (used by the local cache).
+func (r *Registry) DownloadAudio(ctx context.Context, raw, dest string) error {
+	s, err := r.open(ctx, raw)
+	if err != nil {
+		return err
+	}
+	d, ok := s.(Downloader)
+	if !ok {
+		return ErrUnsupported
+	}
+	return d.Download(ctx, strings.TrimSpace(raw), dest)
+}
@@ -0,0 +1,134 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"testing"
+
+	"radiokpowka/backend/youtube"
+)
+
+func TestSourceMatching(t *testing.T) {
+	yt := youtube.NewClient(youtube.Config{CookiesFromBrowser: "none"})
+	r := NewRegistry(YouTube(yt), SoundCloud(yt), Bandcamp(yt), HTTP("ffprobe"), Local("ffprobe", []string{t.TempDir()}), YTDLP(yt))
+
+	cases := map[string]string{
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ": "youtube",
+		"https://youtu.be/dQw4w9WgXcQ":                "youtube",
+		"https://music.youtube.com/watch?v=x":         "youtube",
+		"https://soundcloud.com/artist/song":          "soundcloud",
+		"https://artist.bandcamp.com/track/song":      "bandcamp",
+		"https://cdn.example.com/audio/song.MP3":      "http",
+		"https://radio.example.com/live.ogg?x=1":      "http",
+		"file:///music/song.flac":                     "local",
+		"https://vimeo.com/12345":                     "ytdlp",
+		"https://notyoutube.com/watch?v=x":            "ytdlp",
+		"ftp://example.com/song.mp3":                  "",
+		"artist - song":                               "",
+	}
+	for raw, want := range cases {
+		if got := r.Provider(raw); got != want {
+			t.Errorf("Provider(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestIsURL(t *testing.T) {
+	for raw, want := range map[string]bool{
+		"https://youtu.be/x":    true,
+		"file:///music/a.mp3":   true,
+		"artist - song":         false,
+		"foo:bar":               false,
+		"youtube.com/watch?v=x": false,
+		"":                      false,
+	} {
+		if got := IsURL(raw); got != want {
+			t.Errorf("IsURL(%q) = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	for ip, want := range map[string]bool{
+		"8.8.8.8":         true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"::1":             false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"fe80::1":         false,
+		"fd00::1":         false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"224.0.0.1":       false,
+	} {
+		if got := isPublicIP(net.ParseIP(ip)); got != want {
+			t.Errorf("isPublicIP(%s) = %v, want %v", ip, got, want)
+		}
+	}
+}
+
+// stubSource is an arbitrary-host source that records what was opened.
+type stubSource struct{ opened []string }
+
+func (s *stubSource) Name() string          { return "stub" }
+func (s *stubSource) Match(u *url.URL) bool { return u.Scheme == "http" }
+func (s *stubSource) AnyHost() bool         { return true }
+func (s *stubSource) Resolve(_ context.Context, raw string) ([]Meta, error) {
+	return []Meta{{Title: "t", WebpageURL: raw}}, nil
+}
+func (s *stubSource) Input(_ context.Context, raw string) (string, error) {
+	s.opened = append(s.opened, raw)
+	return raw, nil
+}
+
+func TestRegistryGuardsArbitraryHosts(t *testing.T) {
+	hosts := map[string]string{
+		"public.test":   "93.184.216.34",
+		"internal.test": "10.0.0.5",
+		"metadata.test": "169.254.169.254",
+	}
+	orig := lookupIP
+	lookupIP = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		if ip := net.ParseIP(host); ip != nil {
+			return []net.IPAddr{{IP: ip}}, nil
+		}
+		if a, ok := hosts[host]; ok {
+			return []net.IPAddr{{IP: net.ParseIP(a)}}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	t.Cleanup(func() { lookupIP = orig })
+
+	stub := &stubSource{}
+	r := NewRegistry(stub)
+	ctx := context.Background()
+
+	if _, err := r.Resolve(ctx, "http://public.test/a.mp3", false); !errors.Is(err, ErrRestricted) {
+		t.Fatalf("listener request: err = %v, want ErrRestricted", err)
+	}
+	metas, err := r.Resolve(ctx, "http://public.test/a.mp3", true)
+	if err != nil || len(metas) != 1 || metas[0].Provider != "stub" {
+		t.Fatalf("owner request: %+v, %v", metas, err)
+	}
+	for _, raw := range []string{
+		"http://internal.test/a.mp3",
+		"http://metadata.test/latest/meta-data",
+		"http://127.0.0.1:8080/a.mp3",
+		"http://[::1]/a.mp3",
+	} {
+		if _, err := r.Resolve(ctx, raw, true); !errors.Is(err, ErrPrivateHost) {
+			t.Errorf("Resolve(%s): err = %v, want ErrPrivateHost", raw, err)
+		}
+		if _, err := r.Input(ctx, raw); !errors.Is(err, ErrPrivateHost) {
+			t.Errorf("Input(%s): err = %v, want ErrPrivateHost", raw, err)
+		}
+	}
+	if len(stub.opened) != 0 {
+		t.Fatalf("internal hosts were opened: %v", stub.opened)
+	}
+}
@@ -0,0 +1,103 @@
+// Purpose: yt-dlp backed sources: YouTube, SoundCloud, Bandcamp and a catch-all for other sites.
+
+package source
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"radiokpowka/backend/youtube"
+)
+
+type ytdlpSource struct {
+	name string
+	yt   *youtube.Client
+	// hosts lists accepted hosts; a host also matches its subdomains. Empty = any http(s) URL.
+	hosts []string
+}
+
+// youtubeSource adds text search (the source used for "!sr artist - song" requests).
+type youtubeSource struct {
+	ytdlpSource
+}
+
+func YouTube(yt *youtube.Client) Source {
+	return &youtubeSource{ytdlpSource{name: "youtube", yt: yt, hosts: []string{"youtube.com", "youtu.be", "youtube-nocookie.com"}}}
+}
+
+func (s *youtubeSource) Search(ctx context.Context, query string, limit int) ([]Meta, error) {
+	ms, err := s.yt.Search(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	return convertMetas(ms), nil
+}
+
+func SoundCloud(yt *youtube.Client) Source {
+	return &ytdlpSource{name: "soundcloud", yt: yt, hosts: []string{"soundcloud.com", "snd.sc"}}
+}
+
+func Bandcamp(yt *youtube.Client) Source {
+	return &ytdlpSource{name: "bandcamp", yt: yt, hosts: []string{"bandcamp.com"}}
+}
+
+// YTDLP accepts any http(s) URL and leaves it to yt-dlp's generic extractors; register it last.
+func YTDLP(yt *youtube.Client) Source {
+	return &ytdlpSource{name: "ytdlp", yt: yt}
+}
+
+func (s *ytdlpSource) Name() string { return s.name }
+
+// AnyHost: only the catch-all fetches arbitrary hosts.
+func (s *ytdlpSource) AnyHost() bool { return len(s.hosts) == 0 }
+
+func (s *ytdlpSource) Match(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	if len(s.hosts) == 0 {
+		return u.Host != ""
+	}
+	return hostMatches(u.Hostname(), s.hosts)
+}
+
+func (s *ytdlpSource) Resolve(ctx context.Context, raw string) ([]Meta, error) {
+	ms, err := s.yt.ResolveMetas(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+	return convertMetas(ms), nil
+}
+
+func (s *ytdlpSource) Input(ctx context.Context, raw string) (string, error) {
+	return s.yt.DirectAudioURL(ctx, raw)
+}
+
+func (s *ytdlpSource) Download(ctx context.Context, raw, dest string) error {
+	return s.yt.DownloadAudio(ctx, raw, dest)
+}
+
+func convertMetas(ms []youtube.Meta) []Meta {
+	metas := make([]Meta, 0, len(ms))
+	for _, m := range ms {
+		metas = append(metas, Meta{
+			Title:       m.Title,
+			DurationSec: m.DurationSec,
+			WebpageURL:  m.WebpageURL,
+			Channel:     m.Channel,
+			IsLive:      m.IsLive,
+		})
+	}
+	return metas
+}
+
+func hostMatches(host string, hosts []string) bool {
+	host = strings.ToLower(host)
+	for _, h := range hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
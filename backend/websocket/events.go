@@ -10,6 +10,7 @@ const (
 	EventTrackAdded      EventType = "track_added"
 	EventDonationReceived EventType = "donation_received"
 	EventAuthUpdate      EventType = "auth_update"
+	EventVoteSkip        EventType = "vote_skip" // skip vote progress on the current entry
 )
 
 type Event struct {
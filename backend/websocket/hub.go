@@ -4,6 +4,7 @@ package websocket
 
 import (
 	"encoding/json"
+	"sync/atomic"
 )
 
 type Hub struct {
@@ -11,6 +12,7 @@ type Hub struct {
 	unregister chan *Client
 	broadcast  chan []byte
 	clients    map[*Client]bool
+	count      atomic.Int64 // len(clients), readable outside Run
 }
 
 func NewHub() *Hub {
@@ -27,10 +29,12 @@ func (h *Hub) Run() {
 		select {
 		case c := <-h.register:
 			h.clients[c] = true
+			h.count.Store(int64(len(h.clients)))
 		case c := <-h.unregister:
 			if _, ok := h.clients[c]; ok {
 				delete(h.clients, c)
 				close(c.send)
+				h.count.Store(int64(len(h.clients)))
 			}
 		case msg := <-h.broadcast:
 			for c := range h.clients {
@@ -39,12 +43,18 @@ func (h *Hub) Run() {
 				default:
 					delete(h.clients, c)
 					close(c.send)
+					h.count.Store(int64(len(h.clients)))
 				}
 			}
 		}
 	}
 }
 
+// ClientCount returns the number of connected WebSocket clients.
+func (h *Hub) ClientCount() int {
+	return int(h.count.Load())
+}
+
 func (h *Hub) Broadcast(ev Event) {
 	b, err := json.Marshal(ev)
 	if err != nil {
@@ -0,0 +1,82 @@
+// Purpose: Canonical track IDs, so the same video requested through different links
+// (youtu.be, shorts, music.youtube.com, extra query params) is recognized as one.
+
+package youtube
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var videoID = regexp.MustCompile(`^[\w-]{11}$`)
+
+// trackingParams are query parameters share buttons add; they never pick a different track.
+var trackingParams = map[string]bool{"si": true, "fbclid": true, "gclid": true, "ref": true}
+
+// CanonicalID returns "youtube:<video id>" for YouTube links and a normalized URL for anything
+// else: lowercase host without www., no fragment, and the query sorted without tracking
+// parameters. The query is kept because it can select the file (play.mp3?id=1 vs ?id=2).
+func CanonicalID(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return strings.TrimSpace(raw)
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	host = strings.TrimPrefix(host, "m.")
+
+	if id := youtubeVideoID(host, u); id != "" {
+		return "youtube:" + id
+	}
+	id := host + strings.TrimSuffix(u.EscapedPath(), "/")
+	q := u.Query()
+	for k := range q {
+		if trackingParams[strings.ToLower(k)] || strings.HasPrefix(strings.ToLower(k), "utm_") {
+			q.Del(k)
+		}
+	}
+	if len(q) > 0 {
+		id += "?" + q.Encode()
+	}
+	return id
+}
+
+// IsYouTubeURL reports whether raw points at a YouTube host.
+func IsYouTubeURL(raw string) bool {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return false
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	host = strings.TrimPrefix(host, "m.")
+	switch host {
+	case "youtube.com", "music.youtube.com", "youtube-nocookie.com", "youtu.be":
+		return true
+	}
+	return false
+}
+
+func youtubeVideoID(host string, u *url.URL) string {
+	var id string
+	switch host {
+	case "youtu.be":
+		id = strings.Trim(u.Path, "/")
+	case "youtube.com", "music.youtube.com", "youtube-nocookie.com":
+		if v := u.Query().Get("v"); v != "" {
+			id = v
+			break
+		}
+		// /shorts/<id>, /embed/<id>, /live/<id>, /v/<id>
+		parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+		if len(parts) == 2 {
+			switch parts[0] {
+			case "shorts", "embed", "live", "v":
+				id = parts[1]
+			}
+		}
+	}
+	if !videoID.MatchString(id) {
+		return ""
+	}
+	return id
+}
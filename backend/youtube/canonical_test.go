@@ -0,0 +1,24 @@
+package youtube
+
+import "testing"
+
+func TestCanonicalID(t *testing.T) {
+	cases := map[string]string{
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42s":      "youtube:dQw4w9WgXcQ",
+		"https://youtu.be/dQw4w9WgXcQ?si=abc":                    "youtube:dQw4w9WgXcQ",
+		"https://m.youtube.com/shorts/dQw4w9WgXcQ":               "youtube:dQw4w9WgXcQ",
+		"https://music.youtube.com/watch?v=dQw4w9WgXcQ&list=RD1": "youtube:dQw4w9WgXcQ",
+		"https://youtube.com/watch?v=short":                      "youtube.com/watch?v=short",
+		"https://Radio.Example.com/play.mp3?id=1":                "radio.example.com/play.mp3?id=1",
+		"https://radio.example.com/play.mp3?id=2#t=10":           "radio.example.com/play.mp3?id=2",
+		"https://www.soundcloud.com/artist/song/?utm_source=x":   "soundcloud.com/artist/song",
+		"https://soundcloud.com/artist/song?si=1&in=set":         "soundcloud.com/artist/song?in=set",
+		"https://cdn.example.com/a.mp3?b=2&a=1":                  "cdn.example.com/a.mp3?a=1&b=2",
+		"artist - song ":                                         "artist - song",
+	}
+	for raw, want := range cases {
+		if got := CanonicalID(raw); got != want {
+			t.Errorf("CanonicalID(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
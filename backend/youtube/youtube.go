@@ -1,4 +1,4 @@
-// Purpose: yt-dlp + ffmpeg wrappers (metadata + direct audio URL).
+// Purpose: yt-dlp + ffmpeg wrappers (metadata + search + direct audio URL + audio download).
 
 package youtube
 
@@ -41,6 +41,8 @@ type Meta struct {
 	Title       string
 	DurationSec int
 	WebpageURL  string
+	Channel     string
+	IsLive      bool // live or upcoming stream
 }
 
 func (c *Client) ResolveMeta(ctx context.Context, url string) (Meta, error) {
@@ -80,6 +82,19 @@ func (c *Client) ResolveMetas(ctx context.Context, url string) ([]Meta, error) {
 	return metas, nil
 }
 
+// Search returns up to limit YouTube videos for a text query (yt-dlp "ytsearchN:").
+func (c *Client) Search(ctx context.Context, query string, limit int) ([]Meta, error) {
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+
+	// flat: only the result list, no per-video extraction
+	out, err := c.runYTDLP(ctx, "--flat-playlist", "--dump-single-json", "ytsearch"+strconv.Itoa(limit)+":"+query)
+	if err != nil {
+		return nil, err
+	}
+	return parseMetasFromJSON(out, "")
+}
+
 func parseMetasFromJSON(out []byte, fallbackURL string) ([]Meta, error) {
 	var raw map[string]any
 	if err := json.Unmarshal(out, &raw); err != nil {
@@ -126,6 +141,15 @@ func metaFromMap(raw map[string]any, fallbackURL string) Meta {
 	webpage, _ := raw["webpage_url"].(string)
 	id, _ := raw["id"].(string)
 	urlValue, _ := raw["url"].(string)
+	channel, _ := raw["channel"].(string)
+	if channel == "" {
+		channel, _ = raw["uploader"].(string)
+	}
+	isLive, _ := raw["is_live"].(bool)
+	switch raw["live_status"] {
+	case "is_live", "is_upcoming":
+		isLive = true
+	}
 
 	dur := 0
 	switch v := raw["duration"].(type) {
@@ -141,25 +165,42 @@ func metaFromMap(raw map[string]any, fallbackURL string) Meta {
 		title = "Unknown title"
 	}
 	if webpage == "" {
-		webpage = normalizeURL(urlValue, id, fallbackURL)
+		webpage = normalizeURL(raw, urlValue, id, fallbackURL)
 	}
 
-	return Meta{Title: title, DurationSec: dur, WebpageURL: webpage}
+	return Meta{Title: title, DurationSec: dur, WebpageURL: webpage, Channel: channel, IsLive: isLive}
 }
 
-func normalizeURL(urlValue, id, fallbackURL string) string {
+// normalizeURL builds a watch URL from a bare ID only for YouTube entries; other
+// extractors (SoundCloud, Bandcamp, ...) fall back to the requested URL.
+func normalizeURL(raw map[string]any, urlValue, id, fallbackURL string) string {
 	if strings.HasPrefix(urlValue, "http://") || strings.HasPrefix(urlValue, "https://") {
 		return urlValue
 	}
-	if id != "" {
-		return "https://www.youtube.com/watch?v=" + id
-	}
-	if urlValue != "" {
-		return "https://www.youtube.com/watch?v=" + urlValue
+	if isYouTubeEntry(raw, fallbackURL) {
+		if id != "" {
+			return "https://www.youtube.com/watch?v=" + id
+		}
+		if urlValue != "" {
+			return "https://www.youtube.com/watch?v=" + urlValue
+		}
 	}
 	return fallbackURL
 }
 
+// isYouTubeEntry checks the extractor name yt-dlp reports (ie_key in flat playlist
+// entries, extractor_key otherwise) and falls back to the requested URL's host.
+func isYouTubeEntry(raw map[string]any, fallbackURL string) bool {
+	key, _ := raw["ie_key"].(string)
+	if key == "" {
+		key, _ = raw["extractor_key"].(string)
+	}
+	if key != "" {
+		return strings.HasPrefix(strings.ToLower(key), "youtube")
+	}
+	return IsYouTubeURL(fallbackURL)
+}
+
 func (c *Client) DirectAudioURL(ctx context.Context, url string) (string, error) {
 	// yt-dlp -f bestaudio -g URL  => prints direct media URL
 	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
@@ -176,6 +217,15 @@ func (c *Client) DirectAudioURL(ctx context.Context, url string) (string, error)
 	return strings.TrimSpace(lines[0]), nil
 }
 
+// DownloadAudio saves the best audio stream of url to dest (used by the local cache).
+func (c *Client) DownloadAudio(ctx context.Context, url, dest string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+
+	_, err := c.runYTDLP(ctx, "-f", "bestaudio", "--no-playlist", "--no-part", "--no-progress", "-q", "-o", dest, url)
+	return err
+}
+
 func (c *Client) FFMPEGPath() string {
 	return c.cfg.FFMPEGPath
 }
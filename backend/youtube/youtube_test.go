@@ -0,0 +1,37 @@
+package youtube
+
+import "testing"
+
+// flatSearch is trimmed "yt-dlp --flat-playlist --dump-single-json ytsearch3:..." output.
+const flatSearch = `{
+  "_type": "playlist",
+  "id": "artist - song",
+  "entries": [
+    {"_type": "url", "ie_key": "Youtube", "id": "dQw4w9WgXcQ", "url": "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+     "title": "Artist - Song (Official Video)", "duration": 213.0, "channel": "Artist"},
+    {"_type": "url", "ie_key": "Youtube", "id": "abc123def45", "url": "abc123def45",
+     "title": "Artist - Song (live)", "duration": null, "uploader": "Fan", "live_status": "is_live"},
+    {}
+  ]
+}`
+
+func TestParseSearchResults(t *testing.T) {
+	metas, err := parseMetasFromJSON([]byte(flatSearch), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metas) != 2 {
+		t.Fatalf("got %d results, want 2", len(metas))
+	}
+
+	top := metas[0]
+	if top.Title != "Artist - Song (Official Video)" || top.DurationSec != 213 || top.Channel != "Artist" ||
+		top.WebpageURL != "https://www.youtube.com/watch?v=dQw4w9WgXcQ" || top.IsLive {
+		t.Fatalf("top result = %+v", top)
+	}
+	// older yt-dlp puts the bare ID into url
+	live := metas[1]
+	if live.WebpageURL != "https://www.youtube.com/watch?v=abc123def45" || !live.IsLive || live.Channel != "Fan" {
+		t.Fatalf("second result = %+v", live)
+	}
+}
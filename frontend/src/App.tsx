@@ -42,8 +42,8 @@ export default function App() {
 
   async function refreshQueue() {
     try {
-      const q = await api.playlist.list();
-      setQueue(q);
+      const w = await api.playlist.list();
+      setQueue(w.items, w.version);
     } catch {
       // тихо: WS обычно обновит
     }
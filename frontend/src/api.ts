@@ -21,19 +21,89 @@ export type PlayerState = {
   };
   positionSec: number;
   durationSec: number;
+  shuffle?: boolean;
+  shuffleSeed?: number;
+  fairQueue?: boolean; // upcoming entries interleaved by requester
+  repeat?: "off" | "one" | "all";
 };
 
+export type HistoryEntry = {
+  id: string;
+  title: string;
+  url: string;
+  addedByNick?: string;
+  durationSec: number;
+  startedAt: string;
+  endedAt?: string; // empty while playing
+  listenedSec: number;
+  skipped: boolean;
+};
+
+export type HistoryPage = { items: HistoryEntry[]; nextCursor?: string };
+
+export type SearchResult = {
+  title: string;
+  url: string;
+  channel?: string;
+  durationSec: number;
+  isLive?: boolean;
+  provider: string;
+};
+
+export type LibraryTrack = {
+  id: string;
+  title: string;
+  artist?: string;
+  album?: string;
+  durationSec: number;
+};
+
+export type LibraryPage = { items: LibraryTrack[]; total: number };
+
+export type LibraryScanStatus = {
+  enabled: boolean;
+  running: boolean;
+  last?: { added: number; updated: number; removed: number; failed: number; total: number; finishedAt: string };
+};
+
+// Queue window: played entries (oldest first), current, upcoming in play order.
+export type QueueWindow = {
+  version: number;
+  items: QueueEntry[];
+  prevCursor?: string; // GET /api/playlist?before=
+  nextCursor?: string; // GET /api/playlist?after=
+};
+
+// queue_update payload: turns the window of baseVersion into the window of version.
+export type QueueDiff = {
+  version: number;
+  baseVersion: number;
+  inserted?: { index: number; entry: QueueEntry }[];
+  removed?: string[];
+  moved?: { id: string; index: number }[];
+  updated?: QueueEntry[];
+  prevCursor?: string;
+  nextCursor?: string;
+};
+
+export type VoteProgress = { queueId: string; votes: number; needed: number; skipped: boolean };
+
 export type QueueEntry = {
   id: string;
   title: string;
   url: string;
+  provider?: string; // audio source: youtube|soundcloud|bandcamp|http|local|ytdlp
   addedByNick?: string;
   addedAt: string;
   isDonation?: boolean;
+  donationAmount?: number;
+  isFallback?: boolean; // queued by autopilot
+  requestCount?: number; // people who requested it (merged duplicates)
   status: "prev" | "current" | "next";
+  prefetch?: "pending" | "ready" | "cached" | "failed"; // upcoming entries only
 };
 
-type HttpMethod = "GET" | "POST";
+type HttpMethod = "GET" | "POST" | "DELETE";
 
 async function request<T>(path: string, method: HttpMethod, body?: unknown): Promise<T> {
   const token = useAppStore.getState().auth.token;
@@ -74,11 +144,51 @@ export const api = {
     pause: () => request<{ ok: true }>("/api/player/pause", "POST"),
     next: () => request<{ ok: true }>("/api/player/next", "POST"),
     prev: () => request<{ ok: true }>("/api/player/prev", "POST"),
-    volume: (v: number) => request<{ ok: true }>("/api/player/volume", "POST", { volume: v })
+    seek: (positionSec: number) => request<{ ok: true }>("/api/player/seek", "POST", { positionSec }),
+    skip: (offsetSec: number) => request<{ ok: true }>("/api/player/seek", "POST", { offsetSec }),
+    volume: (v: number) => request<{ ok: true }>("/api/player/volume", "POST", { volume: v }),
+    shuffle: (enabled: boolean, seed?: number) =>
+      request<{ ok: true }>("/api/player/shuffle", "POST", { enabled, seed }),
+    fairQueue: (enabled: boolean) => request<{ ok: true }>("/api/player/fair", "POST", { enabled }),
+    repeat: (mode: "off" | "one" | "all") => request<{ ok: true }>("/api/player/repeat", "POST", { mode }),
+    voteSkip: () => request<VoteProgress>("/api/player/voteskip", "POST"),
+    voteSkipProgress: () => request<VoteProgress>("/api/player/voteskip", "GET")
   },
   playlist: {
-    list: () => request<QueueEntry[]>("/api/playlist", "GET"),
-    add: (url: string) => request<{ ok: true }>("/api/playlist/add", "POST", { url })
+    list: () => request<QueueWindow>("/api/playlist", "GET"),
+    before: (cursor: string, limit?: number) =>
+      request<QueueWindow>(`/api/playlist?before=${encodeURIComponent(cursor)}${limit ? `&limit=${limit}` : ""}`, "GET"),
+    after: (cursor: string, limit?: number) =>
+      request<QueueWindow>(`/api/playlist?after=${encodeURIComponent(cursor)}${limit ? `&limit=${limit}` : ""}`, "GET"),
+    add: (url: string) => request<{ ok: true }>("/api/playlist/add", "POST", { url }),
+    addLibrary: (trackId: string) => request<{ ok: true }>("/api/playlist/add", "POST", { track_id: trackId }),
+    remove: (id: string) => request<{ ok: true }>(`/api/playlist/${id}`, "DELETE"),
+    move: (id: string, position: number) => request<{ ok: true }>(`/api/playlist/${id}/move`, "POST", { position }),
+    clear: (scope: "upcoming" | "played") => request<{ removed: number }>("/api/playlist/clear", "POST", { scope })
+  },
+  history: {
+    list: (params: { cursor?: string; limit?: number; from?: string; to?: string; q?: string } = {}) => {
+      const qs = new URLSearchParams();
+      for (const [k, v] of Object.entries(params)) {
+        if (v !== undefined && v !== "") qs.set(k, String(v));
+      }
+      const query = qs.toString();
+      return request<HistoryPage>(`/api/history${query ? `?${query}` : ""}`, "GET");
+    }
+  },
+  search: (q: string, limit?: number) =>
+    request<{ items: SearchResult[] }>(`/api/search?q=${encodeURIComponent(q)}${limit ? `&limit=${limit}` : ""}`, "GET"),
+  library: {
+    list: (params: { q?: string; offset?: number; limit?: number } = {}) => {
+      const qs = new URLSearchParams();
+      for (const [k, v] of Object.entries(params)) {
+        if (v !== undefined && v !== "") qs.set(k, String(v));
+      }
+      const query = qs.toString();
+      return request<LibraryPage>(`/api/library${query ? `?${query}` : ""}`, "GET");
+    },
+    scanStatus: () => request<LibraryScanStatus>("/api/library/scan", "GET"),
+    scan: () => request<{ ok: true }>("/api/library/scan", "POST")
   },
   integrations: {
     donationalertsConnect: (payload: unknown) =>
@@ -50,14 +50,13 @@ export function Player({
     a.volume = clamp(localVolume, 0, 1);
   }, [localVolume]);
 
-  // If server is paused -> pause locally; if server is playing -> try play (autoplay may be blocked)
+  // The stream is continuous: while the server is paused it sends silence, so we never pause
+  // locally (that would stall the connection). When the server plays -> try play (autoplay may be blocked)
   React.useEffect(() => {
     const a = audioRef.current;
     if (!a || !player) return;
 
-    if (player.isPaused || !player.isPlaying) {
-      a.pause();
-    } else {
+    if (player.isPlaying && !player.isPaused && a.paused) {
       const p = a.play();
       if (p) p.catch(() => void 0);
     }
@@ -1,13 +1,46 @@
-import { describe, it, expect } from "vitest";
-import { useAppStore } from "../store/useAppStore";
+import { describe, it, expect, vi, beforeEach, afterEach } from "vitest";
+import { useAppStore } from "../../store/useAppStore";
+import { WsClient } from "../../ws";
+import { api } from "../../api";
+import type { QueueDiff, QueueEntry, QueueWindow } from "../../api";
 
-// This test is a “light” integration: we simulate WS events via store setters.
-// Real WS (WebSocket) E2E is usually done with playwright/cypress, but we keep it minimal.
+// This test is a “light” integration: we simulate WS events via store setters and a fake
+// WebSocket that feeds WsClient. Real WS (WebSocket) E2E is usually done with playwright/cypress, but we keep it minimal.
+
+vi.mock("../../api", () => ({
+  api: { playlist: { list: vi.fn() } }
+}));
+
+const now = new Date().toISOString();
+
+function entry(id: string, patch: Partial<QueueEntry> = {}): QueueEntry {
+  return { id, title: id, url: `https://y/${id}`, addedAt: now, status: "next", ...patch };
+}
+
+const ids = () => useAppStore.getState().queue.map((e) => e.id);
+
+// FakeSocket stands in for the browser WebSocket; emit() delivers a server event.
+class FakeSocket {
+  static last: FakeSocket | null = null;
+  onopen: (() => void) | null = null;
+  onmessage: ((evt: { data: string }) => void) | null = null;
+  onclose: (() => void) | null = null;
+  onerror: (() => void) | null = null;
+  constructor(public url: string) {
+    FakeSocket.last = this;
+  }
+  close() {}
+  emit(type: string, data: unknown) {
+    this.onmessage?.({ data: JSON.stringify({ type, data }) });
+  }
+}
 
 describe("ws integration (store)", () => {
-  it("applies player_state and queue_update into store", () => {
-    const now = new Date().toISOString();
+  beforeEach(() => {
+    useAppStore.getState().setQueue([], 0);
+  });
 
+  it("applies player_state and queue_update into store", () => {
     useAppStore.getState().setPlayerState({
       isPlaying: true,
       isPaused: false,
@@ -17,20 +50,118 @@ describe("ws integration (store)", () => {
       current: { id: "t1", title: "Song", url: "https://y", addedByNick: "nick" }
     });
 
-    useAppStore.getState().setQueue([
-      {
-        id: "q1",
-        title: "Song",
-        url: "https://y",
-        addedByNick: "nick",
-        addedAt: now,
-        status: "current"
-      }
-    ]);
+    useAppStore.getState().setQueue(
+      [
+        {
+          id: "q1",
+          title: "Song",
+          url: "https://y",
+          addedByNick: "nick",
+          addedAt: now,
+          status: "current"
+        }
+      ],
+      1
+    );
+    const ok = useAppStore.getState().applyQueueDiff({
+      version: 2,
+      baseVersion: 1,
+      inserted: [{ index: 1, entry: entry("q2") }]
+    });
 
     const s = useAppStore.getState();
+    expect(ok).toBe(true);
     expect(s.player?.isPlaying).toBe(true);
-    expect(s.queue.length).toBe(1);
-    expect(s.queue[0]?.title).toBe("Song");
+    expect(s.queue.map((e) => e.title)).toEqual(["Song", "q2"]);
+    expect(s.queueVersion).toBe(2);
+  });
+
+  it("round-trips insert, remove, move and update diffs", () => {
+    const store = useAppStore.getState();
+    store.setQueue([entry("a"), entry("b"), entry("c"), entry("d")], 5);
+
+    // same case as TestDiffQueue on the backend: a b c d -> b x d c, b becomes current
+    expect(
+      store.applyQueueDiff({
+        version: 6,
+        baseVersion: 5,
+        removed: ["a"],
+        inserted: [{ index: 1, entry: entry("x") }],
+        moved: [{ id: "d", index: 2 }],
+        updated: [entry("b", { status: "current" })]
+      })
+    ).toBe(true);
+    expect(ids()).toEqual(["b", "x", "d", "c"]);
+    expect(useAppStore.getState().queue[0]?.status).toBe("current");
+
+    // move to the front and append at the end in one diff
+    expect(
+      useAppStore.getState().applyQueueDiff({
+        version: 7,
+        baseVersion: 6,
+        moved: [{ id: "c", index: 0 }],
+        inserted: [{ index: 4, entry: entry("y") }]
+      })
+    ).toBe(true);
+    expect(ids()).toEqual(["c", "b", "x", "d", "y"]);
+
+    // everything removed
+    expect(
+      useAppStore.getState().applyQueueDiff({ version: 8, baseVersion: 7, removed: ["c", "b", "x", "d", "y"] })
+    ).toBe(true);
+    expect(ids()).toEqual([]);
+    expect(useAppStore.getState().queueVersion).toBe(8);
+  });
+
+  it("rejects a diff with a version gap and keeps the queue", () => {
+    const store = useAppStore.getState();
+    store.setQueue([entry("a")], 3);
+    const skipped: QueueDiff = { version: 5, baseVersion: 4, inserted: [{ index: 1, entry: entry("b") }] };
+    expect(store.applyQueueDiff(skipped)).toBe(false);
+    expect(ids()).toEqual(["a"]);
+    expect(useAppStore.getState().queueVersion).toBe(3);
+
+    // nothing loaded yet: the first diff cannot be applied either
+    store.setQueue([], 0);
+    expect(store.applyQueueDiff({ version: 1, baseVersion: 0 })).toBe(false);
+  });
+
+  describe("WsClient", () => {
+    let client: WsClient;
+
+    beforeEach(() => {
+      vi.stubGlobal("WebSocket", FakeSocket);
+      client = new WsClient();
+      client.start();
+    });
+
+    afterEach(() => {
+      client.stop();
+      vi.unstubAllGlobals();
+      vi.mocked(api.playlist.list).mockReset();
+    });
+
+    it("applies queue_update diffs in order", () => {
+      useAppStore.getState().setQueue([entry("a")], 1);
+      FakeSocket.last?.emit("queue_update", { version: 2, baseVersion: 1, inserted: [{ index: 1, entry: entry("b") }] });
+      FakeSocket.last?.emit("queue_update", { version: 3, baseVersion: 2, removed: ["a"] });
+      expect(ids()).toEqual(["b"]);
+      expect(api.playlist.list).not.toHaveBeenCalled();
+    });
+
+    it("resyncs the full window after a version gap", async () => {
+      useAppStore.getState().setQueue([entry("a")], 1);
+      const win: QueueWindow = { version: 9, items: [entry("a"), entry("c")] };
+      vi.mocked(api.playlist.list).mockResolvedValue(win);
+
+      FakeSocket.last?.emit("queue_update", { version: 9, baseVersion: 8, inserted: [{ index: 1, entry: entry("c") }] });
+      expect(api.playlist.list).toHaveBeenCalledTimes(1);
+      await vi.waitFor(() => expect(useAppStore.getState().queueVersion).toBe(9));
+      expect(ids()).toEqual(["a", "c"]);
+
+      // later diffs continue from the refetched version
+      FakeSocket.last?.emit("queue_update", { version: 10, baseVersion: 9, moved: [{ id: "c", index: 0 }] });
+      expect(ids()).toEqual(["c", "a"]);
+    });
   });
 });
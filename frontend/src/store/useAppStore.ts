@@ -4,7 +4,7 @@
  */
 
 import { create } from "zustand";
-import type { PlayerState, QueueEntry } from "../api";
+import type { PlayerState, QueueDiff, QueueEntry } from "../api";
 
 type Role = "owner" | "listener" | "unknown";
 type WsStatus = "connected" | "disconnected";
@@ -27,6 +27,7 @@ type AppState = {
 
   player: PlayerState | null;
   queue: QueueEntry[];
+  queueVersion: number; // version of the queue window, 0 = unknown
 
   ui: {
     loginOpen: boolean;
@@ -43,7 +44,8 @@ type AppState = {
   setWsStatus: (s: WsStatus) => void;
 
   setPlayerState: (s: PlayerState) => void;
-  setQueue: (q: QueueEntry[]) => void;
+  setQueue: (q: QueueEntry[], version?: number) => void;
+  applyQueueDiff: (d: QueueDiff) => boolean; // false = version mismatch, refetch the window
   pushQueue: (e: QueueEntry) => void;
 
   openLogin: () => void;
@@ -81,6 +83,29 @@ function getInitialTheme(): "dark" | "light" {
   return "light";
 }
 
+// applyDiff: drop removed, replace updated, put inserted/moved entries at their index and
+// fill the remaining slots with the other entries in their previous order.
+function applyDiff(queue: QueueEntry[], d: QueueDiff): QueueEntry[] {
+  const removed = new Set(d.removed ?? []);
+  const updated = new Map((d.updated ?? []).map((e) => [e.id, e]));
+  const moved = new Map((d.moved ?? []).map((m) => [m.id, m.index]));
+
+  const size = queue.length - removed.size + (d.inserted?.length ?? 0);
+  const out: (QueueEntry | undefined)[] = new Array(size).fill(undefined);
+  const rest: QueueEntry[] = [];
+  for (const old of queue) {
+    if (removed.has(old.id)) continue;
+    const e = updated.get(old.id) ?? old;
+    const idx = moved.get(e.id);
+    if (idx !== undefined) out[idx] = e;
+    else rest.push(e);
+  }
+  for (const ins of d.inserted ?? []) out[ins.index] = ins.entry;
+
+  let k = 0;
+  return out.map((e) => e ?? rest[k++]).filter((e): e is QueueEntry => e !== undefined);
+}
+
 export const useAppStore = create<AppState>((set, get) => ({
   auth: { token: null },
   role: "unknown",
@@ -88,6 +113,7 @@ export const useAppStore = create<AppState>((set, get) => ({
 
   player: null,
   queue: [],
+  queueVersion: 0,
 
   ui: {
     loginOpen: true,
@@ -103,7 +129,15 @@ export const useAppStore = create<AppState>((set, get) => ({
   setWsStatus: (s) => set({ wsStatus: s }),
 
   setPlayerState: (s) => set({ player: s }),
-  setQueue: (q) => set({ queue: q }),
+  setQueue: (q, version = 0) => set({ queue: q, queueVersion: version }),
+  applyQueueDiff: (d) => {
+    const { queue, queueVersion } = get();
+    if (queueVersion === 0 || d.baseVersion !== queueVersion) {
+      return false;
+    }
+    set({ queue: applyDiff(queue, d), queueVersion: d.version });
+    return true;
+  },
   pushQueue: (e) => set({ queue: [...get().queue, e] }),
 
   openLogin: () => set({ ui: { ...get().ui, loginOpen: true } }),
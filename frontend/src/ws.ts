@@ -1,15 +1,16 @@
 /**
  * Purpose: WebSocket client with auto-reconnect and event dispatching into Zustand store.
- * Realtime events: player_state, queue_update, track_added, donation_received, auth_update
+ * Realtime events: player_state, queue_update, track_added, donation_received, auth_update, vote_skip
  */
 
 import { config } from "./config";
 import { useAppStore } from "./store/useAppStore";
-import type { PlayerState, QueueEntry } from "./api";
+import { api } from "./api";
+import type { PlayerState, QueueDiff, VoteProgress } from "./api";
 
 export type WsEvent =
   | { type: "player_state"; data: PlayerState }
-  | { type: "queue_update"; data: QueueEntry[] }
+  | { type: "queue_update"; data: QueueDiff }
   | {
       type: "track_added";
       data: {
@@ -21,7 +22,8 @@ export type WsEvent =
       };
     }
   | { type: "donation_received"; data: { donorNick: string; trackUrl?: string; message: string } }
-  | { type: "auth_update"; data: { role: "owner" | "listener" } };
+  | { type: "auth_update"; data: { role: "owner" | "listener" } }
+  | { type: "vote_skip"; data: VoteProgress };
 
 type WsClientOptions = {
   reconnectMinMs?: number;
@@ -102,18 +104,16 @@ export class WsClient {
         store.setPlayerState(evt.data);
         break;
       case "queue_update":
-        store.setQueue(evt.data);
+        if (!store.applyQueueDiff(evt.data)) {
+          // missed an update (or first load): take the full window
+          void api.playlist
+            .list()
+            .then((w) => useAppStore.getState().setQueue(w.items, w.version))
+            .catch(() => undefined);
+        }
         break;
       case "track_added":
-        store.pushQueue({
-          id: evt.data.id,
-          title: evt.data.title,
-          url: evt.data.url,
-          addedByNick: evt.data.addedByNick,
-          addedAt: new Date().toISOString(),
-          isDonation: evt.data.isDonation,
-          status: "next"
-        });
+        // the entry itself arrives with the queue_update diff
         break;
       case "donation_received":
         store.showDonationPreview(evt.data);